@@ -0,0 +1,23 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mmap provides memory-mapped access to whole files.
+//
+// On Linux, files are mapped privately (copy-on-write): modifications to
+// the mapped data are allowed, but never written back to the file. On
+// other platforms, the whole file is read into memory instead.
+package mmap
+
+// Mapping is a file mapped into memory.
+type Mapping struct {
+	data   []byte
+	mapped bool
+}
+
+// Bytes returns the content of the file.
+//
+// The returned slice must not be used after calling Close.
+func (m *Mapping) Bytes() []byte {
+	return m.data
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package mmap
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"syscall"
+)
+
+// Open maps the named file into memory.
+func Open(filename string) (*Mapping, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size > math.MaxInt {
+		return nil, fmt.Errorf("mmap: file too large: %d", size)
+	}
+	if size == 0 {
+		return &Mapping{data: []byte{}}, nil
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size),
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, &os.PathError{Op: "mmap", Path: filename, Err: err}
+	}
+	return &Mapping{data: data, mapped: true}, nil
+}
+
+// Close unmaps the file. It is safe to call Close more than once.
+func (m *Mapping) Close() error {
+	if !m.mapped {
+		m.data = nil
+		return nil
+	}
+	data := m.data
+	m.data = nil
+	m.mapped = false
+	return syscall.Munmap(data)
+}
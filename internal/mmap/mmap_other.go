@@ -0,0 +1,24 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package mmap
+
+import "os"
+
+// Open reads the whole named file into memory.
+func Open(filename string) (*Mapping, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &Mapping{data: data}, nil
+}
+
+// Close releases the file data. It is safe to call Close more than once.
+func (m *Mapping) Close() error {
+	m.data = nil
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	for _, content := range []string{"", "foo bar"} {
+		filename := path.Join(t.TempDir(), "test")
+		if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		m, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(m.Bytes()); got != content {
+			t.Errorf("expected %q, got %q", content, got)
+		}
+
+		// Mapped data is private: the file must not be modified.
+		if len(content) > 0 {
+			m.Bytes()[0] = 'X'
+		}
+		if err = m.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Close(); err != nil {
+			t.Fatalf("second Close: %v", err)
+		}
+		actual, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != content {
+			t.Errorf("file must not be modified, got %q", actual)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+)
+
+// The benchmarks below load synthetic pickles, built the same way CPython
+// would, from four kinds of sources: an in-memory byte slice, a
+// bufio.Reader, an unbuffered io.ReadSeeker, and an unbuffered io.Reader.
+//
+// Run them with:
+//
+//	go test -run NONE -bench . -benchmem ./pickle
+
+func BenchmarkLoadLargeList(b *testing.B) {
+	benchmarkLoad(b, largeListPickle(100000), nil)
+}
+
+func BenchmarkLoadLargeDict(b *testing.B) {
+	benchmarkLoad(b, largeDictPickle(50000), nil)
+}
+
+func BenchmarkLoadTensorHeavy(b *testing.B) {
+	benchmarkLoad(b, tensorHeavyPickle(2000), func(u *Unpickler) {
+		u.FindClass = func(module, name string) (interface{}, error) {
+			return benchCallable{}, nil
+		}
+		u.PersistentLoad = func(interface{}) (interface{}, error) {
+			return nil, nil
+		}
+	})
+}
+
+// benchCallable stands for the classes and functions of PyTorch pickles.
+type benchCallable struct{}
+
+func (benchCallable) Call(args ...interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+// BenchmarkLoadSmall compares creating a new Unpickler for each small
+// pickle with reusing Unpicklers through a Decoder.
+func BenchmarkLoadSmall(b *testing.B) {
+	data := []byte("\x80\x04\x95\x19\x00\x00\x00\x00\x00\x00\x00}\x94(\x8c\x02id\x94K*\x8c\x04name\x94\x8c\x03foo\x94u.")
+	b.Run("NewUnpickler", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			u := NewUnpicklerBytes(data)
+			if _, err := u.Load(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Decoder", func(b *testing.B) {
+		var d Decoder
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.DecodeBytes(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func benchmarkLoad(b *testing.B, data []byte, setup func(*Unpickler)) {
+	load := func(b *testing.B, u Unpickler) {
+		if setup != nil {
+			setup(&u)
+		}
+		if _, err := u.Load(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("Bytes", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			load(b, NewUnpicklerBytes(data))
+		}
+	})
+	b.Run("Bufio", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			load(b, NewUnpickler(bufio.NewReader(bytes.NewReader(data))))
+		}
+	})
+	b.Run("Seeker", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			load(b, NewUnpickler(onlySeeker{bytes.NewReader(data)}))
+		}
+	})
+	b.Run("Unbuffered", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			load(b, NewUnpickler(onlyReader{bytes.NewReader(data)}))
+		}
+	})
+}
+
+// onlySeeker hides any method of the wrapped io.ReadSeeker other than Read
+// and Seek, like an *os.File.
+type onlySeeker struct{ io.ReadSeeker }
+
+// onlyReader hides any method of the wrapped io.Reader other than Read.
+type onlyReader struct{ io.Reader }
+
+// pickleBuilder builds pickle data, splitting it into frames of about
+// 64 KiB for protocol 4 and higher, like CPython's pickle module.
+type pickleBuilder struct {
+	out   bytes.Buffer
+	frame bytes.Buffer
+	proto byte
+}
+
+const benchFrameSize = 64 * 1024
+
+func newPickleBuilder(proto byte) *pickleBuilder {
+	p := &pickleBuilder{proto: proto}
+	p.out.Write([]byte{'\x80', proto})
+	return p
+}
+
+// op appends a whole opcode, with its arguments.
+func (p *pickleBuilder) op(b ...byte) {
+	if p.proto < 4 {
+		p.out.Write(b)
+		return
+	}
+	p.frame.Write(b)
+	if p.frame.Len() >= benchFrameSize {
+		p.flush()
+	}
+}
+
+func (p *pickleBuilder) flush() {
+	if p.frame.Len() == 0 {
+		return
+	}
+	var hdr [9]byte
+	hdr[0] = '\x95'
+	binary.LittleEndian.PutUint64(hdr[1:], uint64(p.frame.Len()))
+	p.out.Write(hdr[:])
+	p.out.Write(p.frame.Bytes())
+	p.frame.Reset()
+}
+
+func (p *pickleBuilder) int(v int) {
+	switch {
+	case v >= 0 && v <= math.MaxUint8:
+		p.op('K', byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		p.op('M', byte(v), byte(v>>8))
+	default:
+		var b [5]byte
+		b[0] = 'J'
+		binary.LittleEndian.PutUint32(b[1:], uint32(int32(v)))
+		p.op(b[:]...)
+	}
+}
+
+func (p *pickleBuilder) float(v float64) {
+	var b [9]byte
+	b[0] = 'G'
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(v))
+	p.op(b[:]...)
+}
+
+func (p *pickleBuilder) str(s string) {
+	if len(s) < 256 {
+		p.op(append([]byte{'\x8c', byte(len(s))}, s...)...)
+		return
+	}
+	b := make([]byte, 5, 5+len(s))
+	b[0] = 'X'
+	binary.LittleEndian.PutUint32(b[1:], uint32(len(s)))
+	p.op(append(b, s...)...)
+}
+
+func (p *pickleBuilder) bytes(v []byte) {
+	if len(v) < 256 {
+		p.op(append([]byte{'C', byte(len(v))}, v...)...)
+		return
+	}
+	b := make([]byte, 5, 5+len(v))
+	b[0] = 'B'
+	binary.LittleEndian.PutUint32(b[1:], uint32(len(v)))
+	p.op(append(b, v...)...)
+}
+
+// memoize stores the topmost stack item in the memo, returning its index.
+// memo is the current size of the memo.
+func (p *pickleBuilder) memoize(memo *int) int {
+	i := *memo
+	*memo++
+	if p.proto >= 4 {
+		p.op('\x94')
+	} else if i < 256 {
+		p.op('q', byte(i))
+	} else {
+		var b [5]byte
+		b[0] = 'r'
+		binary.LittleEndian.PutUint32(b[1:], uint32(i))
+		p.op(b[:]...)
+	}
+	return i
+}
+
+func (p *pickleBuilder) get(i int) {
+	if i < 256 {
+		p.op('h', byte(i))
+		return
+	}
+	var b [5]byte
+	b[0] = 'j'
+	binary.LittleEndian.PutUint32(b[1:], uint32(i))
+	p.op(b[:]...)
+}
+
+func (p *pickleBuilder) bytesOut() []byte {
+	p.flush()
+	p.out.WriteByte('.')
+	return p.out.Bytes()
+}
+
+// largeListPickle returns a protocol 4 pickle of a list of n mixed
+// integers, floats, strings and bytes, appended in batches of 1000 items.
+func largeListPickle(n int) []byte {
+	p := newPickleBuilder(4)
+	memo := 0
+	p.op(']')
+	p.memoize(&memo)
+	for i := 0; i < n; i++ {
+		if i%1000 == 0 {
+			if i > 0 {
+				p.op('e')
+			}
+			p.op('(')
+		}
+		switch i % 4 {
+		case 0:
+			p.int(i)
+		case 1:
+			p.float(float64(i) / 3)
+		case 2:
+			p.str(fmt.Sprintf("item-%d", i))
+			p.memoize(&memo)
+		case 3:
+			p.bytes([]byte{byte(i), byte(i >> 8), byte(i >> 16)})
+			p.memoize(&memo)
+		}
+	}
+	p.op('e')
+	return p.bytesOut()
+}
+
+// largeDictPickle returns a protocol 4 pickle of a dict of n string keys
+// to small tuples, set in batches of 1000 items.
+func largeDictPickle(n int) []byte {
+	p := newPickleBuilder(4)
+	memo := 0
+	p.op('}')
+	p.memoize(&memo)
+	for i := 0; i < n; i++ {
+		if i%1000 == 0 {
+			if i > 0 {
+				p.op('u')
+			}
+			p.op('(')
+		}
+		p.str(fmt.Sprintf("key.%d", i))
+		p.memoize(&memo)
+		p.int(i)
+		p.float(float64(i) * 0.5)
+		p.op('\x86')
+		p.memoize(&memo)
+	}
+	p.op('u')
+	return p.bytesOut()
+}
+
+// tensorHeavyPickle returns a protocol 2 pickle resembling a PyTorch state
+// dict of n tensors, as written by torch.save.
+func tensorHeavyPickle(n int) []byte {
+	p := newPickleBuilder(2)
+	memo := 0
+	p.op([]byte("ccollections\nOrderedDict\n")...)
+	orderedDict := p.memoize(&memo)
+	p.op(')', 'R')
+	p.memoize(&memo)
+
+	rebuild, storage, location, storageType := -1, -1, -1, -1
+	p.op('(')
+	for i := 0; i < n; i++ {
+		p.str(fmt.Sprintf("layers.%d.weight", i))
+		p.memoize(&memo)
+
+		if rebuild < 0 {
+			p.op([]byte("ctorch._utils\n_rebuild_tensor_v2\n")...)
+			rebuild = p.memoize(&memo)
+		} else {
+			p.get(rebuild)
+		}
+		p.op('(')
+
+		// persistent ID: ('storage', torch.FloatStorage, key, 'cpu', numel)
+		p.op('(')
+		if storage < 0 {
+			p.str("storage")
+			storage = p.memoize(&memo)
+			p.op([]byte("ctorch\nFloatStorage\n")...)
+			storageType = p.memoize(&memo)
+		} else {
+			p.get(storage)
+			p.get(storageType)
+		}
+		p.str(fmt.Sprintf("%d", 94000000+i))
+		p.memoize(&memo)
+		if location < 0 {
+			p.str("cpu")
+			location = p.memoize(&memo)
+		} else {
+			p.get(location)
+		}
+		p.int(512 * 512)
+		p.op('t')
+		p.memoize(&memo)
+		p.op('Q')
+
+		p.int(0) // storage offset
+		p.int(512)
+		p.int(512)
+		p.op('\x86') // size
+		p.memoize(&memo)
+		p.int(512)
+		p.int(1)
+		p.op('\x86') // stride
+		p.memoize(&memo)
+		p.op('\x89') // requires_grad
+		p.get(orderedDict)
+		p.op(')', 'R') // backward hooks
+		p.memoize(&memo)
+		p.op('t')
+		p.memoize(&memo)
+		p.op('R')
+		p.memoize(&memo)
+	}
+	p.op('u')
+	return p.bytesOut()
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import "io"
+
+// reader is the interface of the data sources of an Unpickler.
+//
+// Peek returns the next n bytes without advancing the reader; the returned
+// slice is only valid until the next call to a reading method. If fewer
+// than n bytes are available, it returns them along with an error.
+// Discard skips the next n bytes.
+//
+// *bufio.Reader, *sliceReader and *bufReader implement it.
+type reader interface {
+	io.Reader
+	io.ByteReader
+	Peek(n int) ([]byte, error)
+	Discard(n int) (int, error)
+}
+
+// bufReader implements reader on top of any io.Reader.
+//
+// Unlike bufio.Reader, it never leaves the underlying reader positioned
+// past the bytes which have been consumed, once the pickle is loaded, so
+// that any data following its STOP opcode can be read from it:
+//
+//   - if the underlying reader is an io.Seeker, such as an *os.File, data is
+//     read ahead in blocks of readAheadSize bytes, and unread seeks back to
+//     the logical position;
+//   - otherwise, it reads exactly the bytes being peeked, and the bytes which
+//     are known to follow the current opcode (see opcodeArgSizes).
+type bufReader struct {
+	r      io.Reader
+	br     io.ByteReader // r, if it implements io.ByteReader
+	seeker io.Seeker     // r, if it implements io.Seeker and can seek
+	// buf[off:] is the data which has been read, but not yet consumed.
+	buf []byte
+	off int
+}
+
+var _ reader = &bufReader{}
+
+// readAheadSize is the minimum amount of bytes read at once from the
+// underlying io.Seeker of a bufReader.
+const readAheadSize = 4096
+
+func newBufReader(r io.Reader) *bufReader {
+	b := &bufReader{}
+	b.reset(r)
+	return b
+}
+
+// reset makes the bufReader read from r, keeping its buffer.
+func (r *bufReader) reset(rd io.Reader) {
+	r.r = rd
+	r.br, _ = rd.(io.ByteReader)
+	r.seeker = nil
+	if s, ok := rd.(io.Seeker); ok && r.br == nil {
+		// readers such as pipes implement io.Seeker, but fail to seek
+		if _, err := s.Seek(0, io.SeekCurrent); err == nil {
+			r.seeker = s
+		}
+	}
+	r.buf = r.buf[:0]
+	r.off = 0
+}
+
+// unread discards the data read ahead from an io.Seeker, seeking back to
+// the first byte which has not been consumed.
+func (r *bufReader) unread() error {
+	n := r.buffered()
+	r.buf, r.off = r.buf[:0], 0
+	if r.seeker == nil || n == 0 {
+		return nil
+	}
+	_, err := r.seeker.Seek(-int64(n), io.SeekCurrent)
+	return err
+}
+
+// expect informs the bufReader that at least n more bytes are going to be
+// read, so that they can be read at once from a reader which is neither an
+// io.ByteReader nor an io.Seeker. Errors are reported by the following
+// reads.
+func (r *bufReader) expect(n int) {
+	if r.br == nil && r.seeker == nil && r.buffered() < n {
+		_, _ = r.Peek(n)
+	}
+}
+
+func (r *bufReader) buffered() int {
+	return len(r.buf) - r.off
+}
+
+func (r *bufReader) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, io.ErrShortBuffer
+	}
+	avail := r.buffered()
+	if avail >= n {
+		return r.buf[r.off : r.off+n], nil
+	}
+	size := n
+	if r.seeker != nil && size < readAheadSize {
+		size = readAheadSize
+	}
+	if cap(r.buf) < size {
+		buf := make([]byte, avail, size)
+		copy(buf, r.buf[r.off:])
+		r.buf = buf
+	} else {
+		r.buf = r.buf[:copy(r.buf, r.buf[r.off:])]
+	}
+	r.off = 0
+	m, err := io.ReadAtLeast(r.r, r.buf[avail:size], n-avail)
+	r.buf = r.buf[:avail+m]
+	if len(r.buf) >= n {
+		return r.buf[:n], nil
+	}
+	if err == io.EOF && avail > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return r.buf, err
+}
+
+func (r *bufReader) Discard(n int) (int, error) {
+	avail := r.buffered()
+	if n <= avail {
+		r.off += n
+		return n, nil
+	}
+	r.buf, r.off = r.buf[:0], 0
+	m, err := io.CopyN(io.Discard, r.r, int64(n-avail))
+	return avail + int(m), err
+}
+
+func (r *bufReader) Read(p []byte) (int, error) {
+	if r.buffered() > 0 {
+		n := copy(p, r.buf[r.off:])
+		r.off += n
+		return n, nil
+	}
+	return r.r.Read(p)
+}
+
+func (r *bufReader) ReadByte() (byte, error) {
+	if r.buffered() > 0 {
+		b := r.buf[r.off]
+		r.off++
+		return b, nil
+	}
+	if r.br != nil {
+		return r.br.ReadByte()
+	}
+	buf, err := r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	r.off++
+	return buf[0], nil
+}
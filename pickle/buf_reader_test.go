@@ -0,0 +1,194 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+func TestNewUnpicklerDoesNotReadAhead(t *testing.T) {
+	// protocol 0 and protocol 4 (framed) pickles, followed by other data
+	data := "I42\n." +
+		"\x80\x04\x95\x0b\x00\x00\x00\x00\x00\x00\x00\x8c\x05hello\x94N\x86." +
+		"trailing data"
+	r := onlyReader{strings.NewReader(data)}
+
+	u := NewUnpickler(r)
+	v, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 42 {
+		t.Errorf("expected 42, got %#v", v)
+	}
+
+	u = NewUnpickler(r)
+	v, err = u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tu, ok := v.(*types.Tuple); !ok || tu.Len() != 2 || tu.Get(0) != "hello" {
+		t.Errorf("expected ('hello', None), got %#v", v)
+	}
+
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "trailing data" {
+		t.Errorf("expected trailing data, got %q", rest)
+	}
+}
+
+func TestUnpicklerReadLongerThanBufioBuffer(t *testing.T) {
+	value := bytes.Repeat([]byte("0123456789"), 10)
+	data := "B\x64\x00\x00\x00" + string(value) + "."
+
+	u := NewUnpickler(bufio.NewReaderSize(strings.NewReader(data), 16))
+	v, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := v.([]byte); !ok || !bytes.Equal(b, value) {
+		t.Errorf("expected %q, got %#v", value, v)
+	}
+}
+
+func TestUnpicklerReadTruncatedUnbuffered(t *testing.T) {
+	for _, data := range []string{"J\x01\x02", "I42", "\x80\x04\x95\x05\x00\x00"} {
+		u := NewUnpickler(onlyReader{strings.NewReader(data)})
+		_, err := u.Load()
+		if !errors.Is(err, ErrTruncated) {
+			t.Errorf("%q: expected ErrTruncated, got %v", data, err)
+		}
+	}
+}
+
+func TestBufReader(t *testing.T) {
+	r := newBufReader(onlyReader{strings.NewReader("abcdefgh")})
+
+	p, err := r.Peek(3)
+	if err != nil || string(p) != "abc" {
+		t.Fatalf("Peek(3) = %q, %v", p, err)
+	}
+	if n, err := r.Discard(1); n != 1 || err != nil {
+		t.Fatalf("Discard(1) = %d, %v", n, err)
+	}
+	b, err := r.ReadByte()
+	if err != nil || b != 'b' {
+		t.Fatalf("ReadByte() = %q, %v", b, err)
+	}
+	p, err = r.Peek(4)
+	if err != nil || string(p) != "cdef" {
+		t.Fatalf("Peek(4) = %q, %v", p, err)
+	}
+	if n, err := r.Discard(5); n != 5 || err != nil {
+		t.Fatalf("Discard(5) = %d, %v", n, err)
+	}
+	p, err = r.Peek(2)
+	if err != io.ErrUnexpectedEOF || string(p) != "h" {
+		t.Fatalf("Peek(2) = %q, %v", p, err)
+	}
+	if n, err := r.Discard(1); n != 1 || err != nil {
+		t.Fatalf("Discard(1) = %d, %v", n, err)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Fatalf("ReadByte() error = %v", err)
+	}
+}
+
+// countingReader counts the calls to Read of the wrapped reader.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+// countingSeeker is a countingReader implementing io.Seeker, but not
+// io.ByteReader, like an *os.File.
+type countingSeeker struct {
+	countingReader
+	s io.Seeker
+}
+
+func (c *countingSeeker) Seek(offset int64, whence int) (int64, error) {
+	return c.s.Seek(offset, whence)
+}
+
+// brokenSeeker implements io.Seeker, failing to seek, like a pipe.
+type brokenSeeker struct{ io.Reader }
+
+func (brokenSeeker) Seek(int64, int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+func TestNewUnpicklerSeekerReadAhead(t *testing.T) {
+	data := "\x80\x02]q\x00(K\x01K\x02K\x03e." + "\x80\x02X\x05\x00\x00\x00helloq\x00." + "trailing data"
+	sr := strings.NewReader(data)
+	r := &countingSeeker{countingReader{r: sr}, sr}
+
+	u := NewUnpickler(r)
+	v, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := v.(*types.List); !ok || l.Len() != 3 {
+		t.Errorf("expected [1, 2, 3], got %#v", v)
+	}
+	// the whole data is read at once, then the reader seeks back
+	if r.reads != 1 {
+		t.Errorf("expected 1 read, actual %d", r.reads)
+	}
+
+	u.Reset(r)
+	if v, err = u.Load(); err != nil || v != "hello" {
+		t.Errorf("expected 'hello', got %#v, %v", v, err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || string(rest) != "trailing data" {
+		t.Errorf("expected trailing data, got %q, %v", rest, err)
+	}
+}
+
+func TestNewUnpicklerUnbufferedReads(t *testing.T) {
+	// the arguments of each opcode are read with the next opcode, in one
+	// call, or two for the length-prefixed ones
+	data := "\x80\x02\x8c\x05helloK\x01\x86." + "trailing data"
+	for _, r := range []io.Reader{
+		&countingReader{r: onlyReader{strings.NewReader(data)}},
+		brokenSeeker{&countingReader{r: onlyReader{strings.NewReader(data)}}},
+	} {
+		u := NewUnpickler(r)
+		v, err := u.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tu, ok := v.(*types.Tuple); !ok || tu.Len() != 2 || tu.Get(0) != "hello" {
+			t.Errorf("expected ('hello', 1), got %#v", v)
+		}
+		cr, ok := r.(*countingReader)
+		if !ok {
+			cr = r.(brokenSeeker).Reader.(*countingReader)
+		}
+		if cr.reads != 6 {
+			t.Errorf("expected 6 reads, actual %d", cr.reads)
+		}
+		rest, err := io.ReadAll(r)
+		if err != nil || string(rest) != "trailing data" {
+			t.Errorf("expected trailing data, got %q, %v", rest, err)
+		}
+	}
+}
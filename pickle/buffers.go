@@ -0,0 +1,38 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"errors"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// ErrNotEnoughBuffers is returned by the NextBuffer callback made by
+// WithBuffers when the pickle refers to more out-of-band buffers than the
+// ones supplied.
+var ErrNotEnoughBuffers = errors.New("not enough out-of-band buffers")
+
+// WithBuffers returns a function to be used as Unpickler.NextBuffer, which
+// supplies the given out-of-band buffers, in order, to the NEXT_BUFFER
+// opcodes of a protocol 5 pickle, like the "buffers" argument of Python
+// "pickle.loads".
+//
+// Each buffer is loaded as a writable *types.PickleBuffer wrapping the
+// slice, without copying it; the READONLY_BUFFER opcode then makes it
+// read-only, unless Unpickler.MakeReadOnly is set.
+//
+// The buffers are typically the ones collected by Pickler.BufferCallback.
+func WithBuffers(buffers [][]byte) func() (interface{}, error) {
+	next := 0
+	return func() (interface{}, error) {
+		if next >= len(buffers) {
+			return nil, ErrNotEnoughBuffers
+		}
+		b := buffers[next]
+		next++
+		return types.NewPickleBuffer(b, false), nil
+	}
+}
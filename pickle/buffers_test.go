@@ -0,0 +1,95 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+func TestPickleBuffersMatchPython(t *testing.T) {
+	ro := types.NewPickleBuffer([]byte("ro"), true)
+	rw := types.NewPickleBuffer([]byte("rw"), false)
+	value := &types.List{ro, rw, ro}
+
+	var buffers [][]byte
+	var buf bytes.Buffer
+	p := NewPickler(&buf, 5)
+	p.BufferCallback = func(b *types.PickleBuffer) (bool, error) {
+		buffers = append(buffers, b.Data)
+		return false, nil
+	}
+	if err := p.Dump(value); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\x80\x05\x95\n\x00\x00\x00\x00\x00\x00\x00]\x94(\x97\x98\x97\x97\x98e."
+	if buf.String() != expected {
+		t.Errorf("out-of-band:\nexpected %q\nactual   %q", expected, buf.String())
+	}
+	if len(buffers) != 3 || &buffers[0][0] != &ro.Data[0] || &buffers[1][0] != &rw.Data[0] {
+		t.Errorf("unexpected buffers %q", buffers)
+	}
+
+	expected = "\x80\x05\x95\x18\x00\x00\x00\x00\x00\x00\x00]\x94(C\x02ro\x94\x96\x02\x00\x00\x00\x00\x00\x00\x00rw\x94h\x01e."
+	if actual := dumpNoErr(t, value, 5, nil); string(actual) != expected {
+		t.Errorf("in-band:\nexpected %q\nactual   %q", expected, actual)
+	}
+}
+
+func TestWithBuffers(t *testing.T) {
+	data := "\x80\x05\x95\n\x00\x00\x00\x00\x00\x00\x00]\x94(\x97\x98\x97\x97\x98e."
+	buffers := [][]byte{[]byte("ro"), []byte("rw"), []byte("ro")}
+	u := NewUnpicklerBytes([]byte(data))
+	u.NextBuffer = WithBuffers(buffers)
+	result, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := result.(*types.List)
+	if list.Len() != 3 {
+		t.Fatalf("unexpected %v", list)
+	}
+	for i, readOnly := range []bool{true, false, true} {
+		b, ok := list.Get(i).(*types.PickleBuffer)
+		if !ok {
+			t.Fatalf("item %d: unexpected %#v", i, list.Get(i))
+		}
+		if b.ReadOnly != readOnly || &b.Data[0] != &buffers[i][0] {
+			t.Errorf("item %d: unexpected %#v", i, b)
+		}
+	}
+
+	u = NewUnpicklerBytes([]byte(data))
+	u.NextBuffer = WithBuffers(buffers[:2])
+	if _, err := u.Load(); !errors.Is(err, ErrNotEnoughBuffers) {
+		t.Errorf("expected ErrNotEnoughBuffers, actual %v", err)
+	}
+}
+
+func TestPickleBufferRoundTripInBand(t *testing.T) {
+	value := &types.List{
+		types.NewPickleBuffer([]byte("ro"), true),
+		types.NewPickleBuffer([]byte("rw"), false),
+	}
+	result, err := Loads(string(dumpNoErr(t, value, 5, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[b'ro', bytearray(b'rw')]"
+	if actual := types.Repr(result); actual != expected {
+		t.Errorf("expected %s, actual %s", expected, actual)
+	}
+}
+
+func TestPickleBufferProtocol(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPickler(&buf, 4)
+	if err := p.Dump(types.NewPickleBuffer(nil, true)); err == nil {
+		t.Error("expected error")
+	}
+}
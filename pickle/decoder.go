@@ -0,0 +1,65 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"io"
+	"sync"
+)
+
+// maxPooledMemo is the maximum size of the memo of an Unpickler which is
+// put back into a Decoder's pool. Go maps never shrink, so Unpicklers used
+// for unusually large pickles are dropped instead.
+const maxPooledMemo = 1 << 14
+
+// Decoder loads pickles using a pool of reusable Unpicklers, reducing the
+// allocations needed for decoding many small pickles.
+//
+// A Decoder is safe for concurrent use by multiple goroutines. The zero
+// value is ready to use. A Decoder must not be copied after first use.
+type Decoder struct {
+	// Configure, if not nil, is called on each new Unpickler created by
+	// the Decoder, to set its options and callbacks. Since Unpicklers are
+	// reused, it must not depend on the pickle being decoded.
+	Configure func(u *Unpickler)
+
+	pool sync.Pool
+}
+
+// Decode loads a pickle from r, like NewUnpickler(r).Load().
+func (d *Decoder) Decode(r io.Reader) (interface{}, error) {
+	u := d.get()
+	u.Reset(r)
+	defer d.put(u)
+	return u.Load()
+}
+
+// DecodeBytes loads a pickle from b, like NewUnpicklerBytes(b).Load().
+func (d *Decoder) DecodeBytes(b []byte) (interface{}, error) {
+	u := d.get()
+	u.ResetBytes(b)
+	defer d.put(u)
+	return u.Load()
+}
+
+func (d *Decoder) get() *Unpickler {
+	if u, ok := d.pool.Get().(*Unpickler); ok {
+		return u
+	}
+	u := &Unpickler{}
+	if d.Configure != nil {
+		d.Configure(u)
+	}
+	return u
+}
+
+func (d *Decoder) put(u *Unpickler) {
+	if len(u.memo) > maxPooledMemo {
+		return
+	}
+	// don't retain the input and the loaded values while pooled
+	u.clear()
+	d.pool.Put(u)
+}
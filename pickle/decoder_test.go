@@ -0,0 +1,126 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+func TestUnpicklerReset(t *testing.T) {
+	u := NewUnpickler(strings.NewReader("cfoo\nbar\nq\x00."))
+	findClassCalls := 0
+	u.FindClass = func(module, name string) (interface{}, error) {
+		findClassCalls++
+		return module + "." + name, nil
+	}
+	v, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "foo.bar" {
+		t.Errorf("expected foo.bar, got %#v", v)
+	}
+
+	// the memo must be cleared
+	u.Reset(strings.NewReader("h\x00."))
+	_, err = u.Load()
+	if !errors.Is(err, ErrMemoMissing) {
+		t.Errorf("expected ErrMemoMissing, got %v", err)
+	}
+
+	// the callbacks must be preserved
+	u.Reset(onlyReader{strings.NewReader("cbaz\nqux\n.")})
+	v, err = u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "baz.qux" || findClassCalls != 2 {
+		t.Errorf("expected baz.qux from FindClass, got %#v", v)
+	}
+
+	u.ResetBytes([]byte("\x80\x04K\x01K\x02\x86."))
+	v, err = u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tu, ok := v.(*types.Tuple); !ok || tu.Len() != 2 {
+		t.Errorf("expected a 2-tuple, got %#v", v)
+	}
+}
+
+func TestUnpicklerResetZeroValue(t *testing.T) {
+	var u Unpickler
+	u.Reset(strings.NewReader("K\x2a."))
+	v, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 42 {
+		t.Errorf("expected 42, got %#v", v)
+	}
+}
+
+func TestDecoderConcurrent(t *testing.T) {
+	d := &Decoder{
+		Configure: func(u *Unpickler) {
+			u.FindClass = func(module, name string) (interface{}, error) {
+				return module + "." + name, nil
+			}
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				n := g*1000 + i
+				pkl := fmt.Sprintf("(cmod\nname\nq\x00I%d\nh\x00t.", n)
+
+				var v interface{}
+				var err error
+				if i%2 == 0 {
+					v, err = d.Decode(strings.NewReader(pkl))
+				} else {
+					v, err = d.DecodeBytes([]byte(pkl))
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				tu, ok := v.(*types.Tuple)
+				if !ok || tu.Len() != 3 || tu.Get(0) != "mod.name" ||
+					tu.Get(1) != n || tu.Get(2) != "mod.name" {
+					errs <- fmt.Errorf("unexpected value %#v for %d", v, n)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDecoderError(t *testing.T) {
+	var d Decoder
+	if _, err := d.DecodeBytes([]byte("K\x01")); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	v, err := d.DecodeBytes([]byte("K\x01."))
+	if err != nil || v != 1 {
+		t.Errorf("expected 1, got %#v, %v", v, err)
+	}
+}
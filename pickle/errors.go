@@ -0,0 +1,212 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentinel errors for the most common classes of unpickling failures.
+//
+// They are never returned directly by Unpickler.Load, which always wraps
+// them in an *UnpicklingError; use errors.Is to test for them.
+var (
+	// ErrStackUnderflow is reported when an opcode needs more items than
+	// the ones available on the stack, or when no MARK is available.
+	ErrStackUnderflow = errors.New("stack underflow")
+	// ErrUnknownOpcode is reported when an invalid opcode is encountered.
+	ErrUnknownOpcode = errors.New("unknown opcode")
+	// ErrTruncated is reported when the data ends before the STOP opcode,
+	// or in the middle of an opcode argument or frame.
+	//
+	// An *UnpicklingError caused by io.EOF or io.ErrUnexpectedEOF also
+	// matches ErrTruncated.
+	ErrTruncated = errors.New("pickle data was truncated")
+	// ErrMemoMissing is reported when a GET opcode refers to a memo index
+	// which was never PUT.
+	ErrMemoMissing = errors.New("memo value not found")
+)
+
+// maxStackItems is the maximum number of topmost stack items rendered
+// in an UnpicklingError.
+const maxStackItems = 3
+
+// maxStackItemLen is the maximum length, in runes, of the rendering of
+// a single stack item in an UnpicklingError.
+const maxStackItemLen = 48
+
+// UnpicklingError is the error returned by Unpickler.Load. It describes
+// the state of the unpickling machine at the time of the failure.
+type UnpicklingError struct {
+	// Offset is the position, in bytes from the beginning of the pickle
+	// data, of the opcode which failed.
+	Offset int64
+	// Opcode is the opcode which failed. It is meaningful only if OpName
+	// is not empty.
+	Opcode byte
+	// OpName is the pickletools name of Opcode (e.g. "BININT1"). It is
+	// empty if the opcode itself could not be read, or if it is unknown.
+	OpName string
+	// MemoSize is the amount of values stored in the memo.
+	MemoSize int
+	// StackDepth is the amount of items on the stack, not counting the
+	// ones before the last MARK.
+	StackDepth int
+	// Stack is a short rendering of the topmost stack items, from the
+	// deepest to the topmost.
+	Stack []string
+	// Err is the underlying error.
+	Err error
+}
+
+var _ error = &UnpicklingError{}
+
+func (e *UnpicklingError) Error() string {
+	o := new(strings.Builder)
+	o.WriteString("pickle: ")
+	o.WriteString(e.Err.Error())
+	fmt.Fprintf(o, " (offset %d", e.Offset)
+	if e.OpName != "" {
+		fmt.Fprintf(o, ", opcode 0x%02x %s", e.Opcode, e.OpName)
+	}
+	fmt.Fprintf(o, ", memo size %d, stack depth %d", e.MemoSize, e.StackDepth)
+	if len(e.Stack) > 0 {
+		fmt.Fprintf(o, ", stack top [%s]", strings.Join(e.Stack, ", "))
+	}
+	o.WriteString(")")
+	return o.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *UnpicklingError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches ErrTruncated, for errors caused by
+// an unexpected end of the data.
+func (e *UnpicklingError) Is(target error) bool {
+	return target == ErrTruncated &&
+		(errors.Is(e.Err, io.EOF) || errors.Is(e.Err, io.ErrUnexpectedEOF))
+}
+
+// newUnpicklingError wraps err in an *UnpicklingError describing the
+// current state of the machine. If hasOpcode is false, the opcode could
+// not be read at all.
+func (u *Unpickler) newUnpicklingError(err error, offset int64, opcode byte, hasOpcode bool) *UnpicklingError {
+	e := &UnpicklingError{
+		Offset:     offset,
+		Opcode:     opcode,
+		MemoSize:   len(u.memo),
+		StackDepth: len(u.stack),
+		Err:        err,
+	}
+	if hasOpcode {
+		e.OpName = opcodeNames[opcode]
+	}
+	first := len(u.stack) - maxStackItems
+	if first < 0 {
+		first = 0
+	}
+	for _, item := range u.stack[first:] {
+		e.Stack = append(e.Stack, renderStackItem(item))
+	}
+	return e
+}
+
+// renderStackItem renders scalar values in full, and any other value only
+// by type and length (if any), since containers might be self-referencing.
+func renderStackItem(item interface{}) string {
+	var s string
+	switch v := item.(type) {
+	case nil, bool, int, float64, string, []byte, *big.Int:
+		s = fmt.Sprintf("%T(%v)", v, v)
+	case interface{ Len() int }:
+		s = fmt.Sprintf("%T(len=%d)", v, v.Len())
+	default:
+		s = fmt.Sprintf("%T", v)
+	}
+	if utf8.RuneCountInString(s) <= maxStackItemLen {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:maxStackItemLen-3]) + "..."
+}
+
+// opcodeNames maps each opcode to its pickletools name.
+var opcodeNames = [256]string{
+	'(':    "MARK",
+	'.':    "STOP",
+	'0':    "POP",
+	'1':    "POP_MARK",
+	'2':    "DUP",
+	'F':    "FLOAT",
+	'I':    "INT",
+	'J':    "BININT",
+	'K':    "BININT1",
+	'L':    "LONG",
+	'M':    "BININT2",
+	'N':    "NONE",
+	'P':    "PERSID",
+	'Q':    "BINPERSID",
+	'R':    "REDUCE",
+	'S':    "STRING",
+	'T':    "BINSTRING",
+	'U':    "SHORT_BINSTRING",
+	'V':    "UNICODE",
+	'X':    "BINUNICODE",
+	'a':    "APPEND",
+	'b':    "BUILD",
+	'c':    "GLOBAL",
+	'd':    "DICT",
+	'}':    "EMPTY_DICT",
+	'e':    "APPENDS",
+	'g':    "GET",
+	'h':    "BINGET",
+	'i':    "INST",
+	'j':    "LONG_BINGET",
+	'l':    "LIST",
+	']':    "EMPTY_LIST",
+	'o':    "OBJ",
+	'p':    "PUT",
+	'q':    "BINPUT",
+	'r':    "LONG_BINPUT",
+	's':    "SETITEM",
+	't':    "TUPLE",
+	')':    "EMPTY_TUPLE",
+	'u':    "SETITEMS",
+	'G':    "BINFLOAT",
+	'\x80': "PROTO",
+	'\x81': "NEWOBJ",
+	'\x82': "EXT1",
+	'\x83': "EXT2",
+	'\x84': "EXT4",
+	'\x85': "TUPLE1",
+	'\x86': "TUPLE2",
+	'\x87': "TUPLE3",
+	'\x88': "NEWTRUE",
+	'\x89': "NEWFALSE",
+	'\x8a': "LONG1",
+	'\x8b': "LONG4",
+	'B':    "BINBYTES",
+	'C':    "SHORT_BINBYTES",
+	'\x8c': "SHORT_BINUNICODE",
+	'\x8d': "BINUNICODE8",
+	'\x8e': "BINBYTES8",
+	'\x8f': "EMPTY_SET",
+	'\x90': "ADDITEMS",
+	'\x91': "FROZENSET",
+	'\x92': "NEWOBJ_EX",
+	'\x93': "STACK_GLOBAL",
+	'\x94': "MEMOIZE",
+	'\x95': "FRAME",
+	'\x96': "BYTEARRAY8",
+	'\x97': "NEXT_BUFFER",
+	'\x98': "READONLY_BUFFER",
+}
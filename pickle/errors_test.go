@@ -0,0 +1,156 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestUnpicklingError(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		pkl        string
+		sentinel   error
+		offset     int64
+		opName     string
+		memoSize   int
+		stackDepth int
+	}{
+		{
+			name:     "stack underflow",
+			pkl:      "\x80\x02K\x01\x86.",
+			sentinel: ErrStackUnderflow,
+			offset:   4,
+			opName:   "TUPLE2",
+		},
+		{
+			name:     "read-only buffer on an empty stack",
+			pkl:      "\x80\x05\x98.",
+			sentinel: ErrStackUnderflow,
+			offset:   2,
+			opName:   "READONLY_BUFFER",
+		},
+		{
+			name:     "pop mark without mark",
+			pkl:      "1.",
+			sentinel: ErrStackUnderflow,
+			offset:   0,
+			opName:   "POP_MARK",
+		},
+		{
+			name:       "unknown opcode",
+			pkl:        "K\x01K\x02\xf0.",
+			sentinel:   ErrUnknownOpcode,
+			offset:     4,
+			stackDepth: 2,
+		},
+		{
+			name:       "truncated argument",
+			pkl:        "K\x01J\x01\x02",
+			sentinel:   ErrTruncated,
+			offset:     2,
+			opName:     "BININT",
+			stackDepth: 1,
+		},
+		{
+			name:       "missing stop",
+			pkl:        "K\x01",
+			sentinel:   ErrTruncated,
+			offset:     2,
+			stackDepth: 1,
+		},
+		{
+			name:     "truncated frame",
+			pkl:      "\x80\x04\x95\x05\x00\x00\x00\x00\x00\x00\x00K\x01",
+			sentinel: ErrTruncated,
+			offset:   2,
+			opName:   "FRAME",
+		},
+		{
+			name:       "memo missing",
+			pkl:        "K\x01q\x00h\x01.",
+			sentinel:   ErrMemoMissing,
+			offset:     4,
+			opName:     "BINGET",
+			memoSize:   1,
+			stackDepth: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Loads(tc.pkl)
+			if !errors.Is(err, tc.sentinel) {
+				t.Fatalf("expected %v, got %v", tc.sentinel, err)
+			}
+			var ue *UnpicklingError
+			if !errors.As(err, &ue) {
+				t.Fatalf("expected *UnpicklingError, got %#v", err)
+			}
+			if ue.Offset != tc.offset {
+				t.Errorf("expected offset %d, got %d", tc.offset, ue.Offset)
+			}
+			if ue.OpName != tc.opName {
+				t.Errorf("expected opcode name %q, got %q", tc.opName, ue.OpName)
+			}
+			if ue.MemoSize != tc.memoSize {
+				t.Errorf("expected memo size %d, got %d", tc.memoSize, ue.MemoSize)
+			}
+			if ue.StackDepth != tc.stackDepth {
+				t.Errorf("expected stack depth %d, got %d", tc.stackDepth, ue.StackDepth)
+			}
+			if len(ue.Stack) != tc.stackDepth {
+				t.Errorf("expected %d rendered stack items, got %v", tc.stackDepth, ue.Stack)
+			}
+		})
+	}
+}
+
+func TestUnpicklingErrorTruncatedIsEOF(t *testing.T) {
+	_, err := Loads("K\x01J\x01\x02")
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestUnpicklingErrorMessage(t *testing.T) {
+	_, err := Loads("\x80\x02K\x01K\x02N\x82.")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	got := err.Error()
+	for _, want := range []string{
+		"unsupported extension code encountered",
+		"offset 7",
+		"opcode 0x82 EXT1",
+		"stack depth 3",
+		"stack top [int(1), int(2), <nil>(<nil>)]",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q to contain %q", got, want)
+		}
+	}
+}
+
+func TestUnpicklingErrorLongStackItem(t *testing.T) {
+	_, err := Loads("X\x64\x00\x00\x00" + strings.Repeat("a", 100) + "0")
+	var ue *UnpicklingError
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected *UnpicklingError, got %#v", err)
+	}
+	if len(ue.Stack) != 0 {
+		t.Fatalf("expected empty stack, got %v", ue.Stack)
+	}
+
+	_, err = Loads("X\x64\x00\x00\x00" + strings.Repeat("a", 100))
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected *UnpicklingError, got %#v", err)
+	}
+	if len(ue.Stack) != 1 || len(ue.Stack[0]) != maxStackItemLen ||
+		!strings.HasSuffix(ue.Stack[0], "...") {
+		t.Errorf("expected a truncated stack item, got %v", ue.Stack)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxExtensionCode is the largest extension code allowed by Python
+// "copyreg.add_extension".
+const maxExtensionCode = 0x7fffffff
+
+// ExtensionRegistry maps extension codes to globals, identified by module
+// and name, like the Python registry managed with "copyreg.add_extension".
+//
+// An Unpickler with a registry resolves the EXT1, EXT2 and EXT4 opcodes
+// to the registered globals, which are then looked up like the ones of the
+// GLOBAL opcode. A Pickler with a registry writes the registered globals
+// with the EXT opcodes instead of their module and name.
+//
+// An ExtensionRegistry is safe for concurrent use by multiple goroutines.
+// The zero value is an empty registry, ready to use.
+type ExtensionRegistry struct {
+	mu     sync.RWMutex
+	byCode map[int]extensionKey
+	byKey  map[extensionKey]int
+}
+
+type extensionKey struct {
+	module, name string
+}
+
+// NewExtensionRegistry returns a new empty ExtensionRegistry.
+func NewExtensionRegistry() *ExtensionRegistry {
+	return &ExtensionRegistry{}
+}
+
+// Add registers code for the global module.name. Like Python
+// "copyreg.add_extension", it fails if the code is not in the range
+// [1, 0x7fffffff], or if either the code or the global are already
+// registered differently. Registering the same pair again has no effect.
+func (r *ExtensionRegistry) Add(module, name string, code int) error {
+	if code < 1 || code > maxExtensionCode {
+		return fmt.Errorf("extension code %d out of range", code)
+	}
+	key := extensionKey{module: module, name: name}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	oldKey, codeOk := r.byCode[code]
+	oldCode, keyOk := r.byKey[key]
+	if codeOk && oldKey == key && keyOk && oldCode == code {
+		return nil
+	}
+	if keyOk {
+		return fmt.Errorf("key %s.%s is already registered with code %d", module, name, oldCode)
+	}
+	if codeOk {
+		return fmt.Errorf("code %d is already in use for key %s.%s", code, oldKey.module, oldKey.name)
+	}
+	if r.byCode == nil {
+		r.byCode = make(map[int]extensionKey)
+		r.byKey = make(map[extensionKey]int)
+	}
+	r.byCode[code] = key
+	r.byKey[key] = code
+	return nil
+}
+
+// Remove unregisters code for the global module.name, like Python
+// "copyreg.remove_extension". It fails if that pair is not registered.
+func (r *ExtensionRegistry) Remove(module, name string, code int) error {
+	key := extensionKey{module: module, name: name}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if oldKey, ok := r.byCode[code]; !ok || oldKey != key || r.byKey[key] != code {
+		return fmt.Errorf("key %s.%s is not registered with code %d", module, name, code)
+	}
+	delete(r.byCode, code)
+	delete(r.byKey, key)
+	return nil
+}
+
+// Lookup returns the module and name of the global registered with code.
+func (r *ExtensionRegistry) Lookup(code int) (module, name string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.byCode[code]
+	return key.module, key.name, ok
+}
+
+// Code returns the code registered for the global module.name.
+func (r *ExtensionRegistry) Code(module, name string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	code, ok := r.byKey[extensionKey{module: module, name: name}]
+	return code, ok
+}
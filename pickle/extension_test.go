@@ -0,0 +1,127 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+func TestExtensionRegistry(t *testing.T) {
+	var r ExtensionRegistry // the zero value is usable
+	if _, _, ok := r.Lookup(1); ok {
+		t.Error("empty registry: unexpected code 1")
+	}
+	if err := r.Add("collections", "OrderedDict", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("collections", "OrderedDict", 1); err != nil {
+		t.Errorf("registering the same pair again: %v", err)
+	}
+	for _, tc := range []struct {
+		module, name string
+		code         int
+	}{
+		{"collections", "OrderedDict", 2}, // key already registered
+		{"collections", "Counter", 1},     // code already in use
+		{"collections", "Counter", 0},
+		{"collections", "Counter", 0x80000000},
+	} {
+		if err := r.Add(tc.module, tc.name, tc.code); err == nil {
+			t.Errorf("Add(%q, %q, %d): expected error", tc.module, tc.name, tc.code)
+		}
+	}
+
+	module, name, ok := r.Lookup(1)
+	if !ok || module != "collections" || name != "OrderedDict" {
+		t.Errorf("Lookup(1): %q %q %v", module, name, ok)
+	}
+	code, ok := r.Code("collections", "OrderedDict")
+	if !ok || code != 1 {
+		t.Errorf("Code: %d %v", code, ok)
+	}
+
+	if err := r.Remove("collections", "OrderedDict", 2); err == nil {
+		t.Error("Remove with the wrong code: expected error")
+	}
+	if err := r.Remove("collections", "OrderedDict", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Code("collections", "OrderedDict"); ok {
+		t.Error("removed key is still registered")
+	}
+	if err := r.Add("collections", "Counter", 1); err != nil {
+		t.Errorf("reusing a removed code: %v", err)
+	}
+}
+
+func TestExtensionOpcodes(t *testing.T) {
+	r := NewExtensionRegistry()
+	for module, code := range map[string]int{"m1": 1, "m2": 300, "m4": 70000} {
+		if err := r.Add(module, "C", code); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testCases := []struct {
+		opcode string
+		data   string
+		module string
+	}{
+		{"EXT1", "\x80\x02\x82\x01.", "m1"},
+		{"EXT2", "\x80\x02\x83,\x01.", "m2"},
+		{"EXT4", "\x80\x02\x84p\x11\x01\x00.", "m4"},
+	}
+	for _, tc := range testCases {
+		u := NewUnpicklerBytes([]byte(tc.data))
+		if _, err := u.Load(); err == nil {
+			t.Errorf("%s without registry: expected error", tc.opcode)
+		}
+
+		u = NewUnpicklerBytes([]byte(tc.data))
+		u.Extensions = r
+		actual, err := u.Load()
+		if err != nil {
+			t.Errorf("%s: %v", tc.opcode, err)
+			continue
+		}
+		class, ok := actual.(*types.GenericClass)
+		if !ok || class.Module != tc.module || class.Name != "C" {
+			t.Errorf("%s: unexpected %#v", tc.opcode, actual)
+		}
+
+		u = NewUnpicklerBytes([]byte(tc.data))
+		u.Extensions = r
+		u.FindClass = func(module, name string) (interface{}, error) {
+			return module + "." + name, nil
+		}
+		actual, err = u.Load()
+		if err != nil || actual != tc.module+".C" {
+			t.Errorf("%s with FindClass: %v %v", tc.opcode, actual, err)
+		}
+	}
+}
+
+func TestExtensionRoundTrip(t *testing.T) {
+	r := NewExtensionRegistry()
+	if err := r.Add("collections", "OrderedDict", 1); err != nil {
+		t.Fatal(err)
+	}
+	od := types.NewOrderedDict()
+	od.Set("a", 1)
+
+	for _, proto := range []int{2, 4} {
+		data := dumpNoErr(t, od, proto, r)
+		u := NewUnpicklerBytes(data)
+		u.Extensions = r
+		actual, err := u.Load()
+		if err != nil {
+			t.Fatalf("protocol %d: %v", proto, err)
+		}
+		if types.Repr(actual) != types.Repr(od) {
+			t.Errorf("protocol %d: unexpected %v", proto, actual)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// FuzzLoads checks that any input is either loaded successfully, or makes
+// Load return an *UnpicklingError, without panicking, both when reading
+// from a byte slice and from an unbuffered io.Reader.
+//
+// The seed corpus in testdata/fuzz/FuzzLoads is generated with
+// testdata/generate_fuzz_corpus.py.
+func FuzzLoads(f *testing.F) {
+	for _, seed := range []string{
+		"\xff.",            // opcode 0xFF
+		"\x8a\x00.",        // LONG1 with no data bytes
+		"(K\x01u.",         // SETITEMS with an odd number of items
+		"\x8f(C\x01a\x90.", // ADDITEMS with an unhashable item
+		"(C\x01a\x91.",     // FROZENSET with an unhashable item
+		"(]]d.",            // DICT with an unhashable key
+		"(K\x01d.",         // DICT with an odd number of items
+		"I",                // INT without newline
+		"\x95\x00\x00\x00\x00\x00\x00\x00\x00\x95\x00\x00\x00\x00\x00\x00\x00\x00N.",                // empty frames
+		"\x8e\xff\xff\xff\xff\xff\xff\xff\x7f",                                                      // huge BINBYTES8
+		"\x95\xff\xff\xff\xff\xff\xff\xff\x7f",                                                      // huge FRAME
+		"]q\x00h\x00a(\x90",                                                                         // self-referencing list in an error
+		"\x80\x02carray\n_array_reconstructor\n(carray\narray\nX\x01\x00\x00\x00dK\x00C\x01\x00tR.", // array with mismatched mformat
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := Loads(string(data))
+
+		u := NewUnpicklerBytes(data)
+		u.ZeroCopy = true
+		_, bytesErr := u.Load()
+		if (err == nil) != (bytesErr == nil) {
+			t.Fatalf("Loads error %v, NewUnpicklerBytes error %v", err, bytesErr)
+		}
+
+		u = NewUnpickler(onlyReader{bytes.NewReader(data)})
+		_, readerErr := u.Load()
+		if (err == nil) != (readerErr == nil) {
+			t.Fatalf("Loads error %v, NewUnpickler error %v", err, readerErr)
+		}
+
+		if err == nil {
+			return
+		}
+		var ue *UnpicklingError
+		if !errors.As(err, &ue) {
+			t.Fatalf("expected *UnpicklingError, got %#v", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// IntMode selects the Go type of the integers loaded by an Unpickler.
+type IntMode int
+
+const (
+	// NativeInt represents integers as int, or as *big.Int if they don't
+	// fit in an int (which is 32 bits wide on 32-bit platforms).
+	NativeInt IntMode = iota
+	// AlwaysInt64 represents integers as int64, or as *big.Int if they
+	// don't fit in an int64, on every platform.
+	AlwaysInt64
+	// AlwaysBigInt represents all integers as *big.Int.
+	AlwaysBigInt
+)
+
+// intValue returns the integer i represented according to u.IntMode.
+func (u *Unpickler) intValue(i int64) interface{} {
+	switch u.IntMode {
+	case AlwaysInt64:
+		return i
+	case AlwaysBigInt:
+		return big.NewInt(i)
+	default:
+		if n := int(i); int64(n) == i {
+			return n
+		}
+		return big.NewInt(i)
+	}
+}
+
+// bigIntValue returns the integer b represented according to u.IntMode.
+func (u *Unpickler) bigIntValue(b *big.Int) interface{} {
+	if u.IntMode != AlwaysBigInt && b.IsInt64() {
+		return u.intValue(b.Int64())
+	}
+	return b
+}
+
+// parseInt parses a decimal integer, of any size.
+func (u *Unpickler) parseInt(s string) (interface{}, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return u.intValue(i), nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		b, ok := new(big.Int).SetString(s, 10)
+		if ok {
+			return u.bigIntValue(b), nil
+		}
+	}
+	return nil, err
+}
+
+// decodeLong decodes a little-endian two's complement integer, as found in
+// LONG1 and LONG4 opcodes.
+func (u *Unpickler) decodeLong(b []byte) interface{} {
+	if len(b) <= 8 {
+		var ux uint64
+		for i := len(b) - 1; i >= 0; i-- {
+			ux = ux<<8 | uint64(b[i])
+		}
+		// sign extension
+		if len(b) > 0 && len(b) < 8 && b[len(b)-1]&0x80 != 0 {
+			ux |= ^uint64(0) << (8 * len(b))
+		}
+		return u.intValue(int64(ux))
+	}
+
+	// big.Int.SetBytes expects big-endian unsigned bytes
+	be := make([]byte, len(b))
+	for i, c := range b {
+		be[len(b)-1-i] = c
+	}
+	bi := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		bi.Sub(bi, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return u.bigIntValue(bi)
+}
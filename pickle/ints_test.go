@@ -0,0 +1,88 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+func TestIntMode(t *testing.T) {
+	testCases := []struct {
+		opcode   string
+		data     string
+		expected string
+	}{
+		{"INT", "I5\n.", "5"},
+		{"INT", "I-2147483648\n.", "-2147483648"},
+		{"INT", "I9223372036854775808\n.", "9223372036854775808"},
+		{"BININT", "\x80\x02J\xff\xff\xff\xff.", "-1"},
+		{"BININT", "\x80\x02J\x00\x00\x00\x80.", "-2147483648"},
+		{"BININT1", "\x80\x02K\x05.", "5"},
+		{"BININT2", "\x80\x02M,\x01.", "300"},
+		{"LONG", "L2147483648L\n.", "2147483648"},
+		{"LONG", "L-9223372036854775808L\n.", "-9223372036854775808"},
+		{"LONG", "L9223372036854775808L\n.", "9223372036854775808"},
+		{"LONG1", "\x80\x02\x8a\x00.", "0"},
+		{"LONG1", "\x80\x02\x8a\x05\x00\x00\x00\x80\x00.", "2147483648"},
+		{"LONG1", "\x80\x02\x8a\x08\xff\xff\xff\xff\xff\xff\xff\x7f.", "9223372036854775807"},
+		{"LONG1", "\x80\x02\x8a\x08\x00\x00\x00\x00\x00\x00\x00\x80.", "-9223372036854775808"},
+		{"LONG1", "\x80\x02\x8a\t\x00\x00\x00\x00\x00\x00\x00\x80\x00.", "9223372036854775808"},
+		{"LONG1", "\x80\x02\x8a\t\xff\xff\xff\xff\xff\xff\xff\x7f\xff.", "-9223372036854775809"},
+		{"LONG1", "\x80\x02\x8a\t\x00\x00\x00\x00\x00\x00\x00\x00\x00.", "0"},
+		{"LONG4", "\x80\x02\x8b\x02\x00\x00\x00\xff\x7f.", "32767"},
+		{"LONG4", "\x80\x02\x8b\x0d\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x10.",
+			"1267650600228229401496703205376"},
+	}
+	for _, tc := range testCases {
+		expected, _ := new(big.Int).SetString(tc.expected, 10)
+		for _, mode := range []IntMode{NativeInt, AlwaysInt64, AlwaysBigInt} {
+			u := NewUnpicklerBytes([]byte(tc.data))
+			u.IntMode = mode
+			actual, err := u.Load()
+			if err != nil {
+				t.Errorf("%s %q mode %d: %v", tc.opcode, tc.data, mode, err)
+				continue
+			}
+			var ok bool
+			switch v := actual.(type) {
+			case int:
+				ok = mode == NativeInt && int64(v) == expected.Int64() && expected.IsInt64()
+			case int64:
+				ok = mode == AlwaysInt64 && v == expected.Int64() && expected.IsInt64()
+			case *big.Int:
+				fitsInt := expected.IsInt64() && expected.Int64() == int64(int(expected.Int64()))
+				switch mode {
+				case NativeInt:
+					ok = !fitsInt
+				case AlwaysInt64:
+					ok = !expected.IsInt64()
+				default:
+					ok = true
+				}
+				ok = ok && v.Cmp(expected) == 0
+			}
+			if !ok {
+				t.Errorf("%s %q mode %d: expected %s, actual %T(%v) (int size %d)",
+					tc.opcode, tc.data, mode, tc.expected, actual, actual, strconv.IntSize)
+			}
+		}
+	}
+}
+
+func TestIntModeKeepsBooleans(t *testing.T) {
+	for _, mode := range []IntMode{NativeInt, AlwaysInt64, AlwaysBigInt} {
+		u := NewUnpicklerBytes([]byte("(I01\nI00\nt."))
+		u.IntMode = mode
+		actual, err := u.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := actual.(interface{ String() string }).String(); s != "(true, false)" {
+			t.Errorf("mode %d: expected (true, false), actual %s", mode, s)
+		}
+	}
+}
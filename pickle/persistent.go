@@ -0,0 +1,133 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// ErrUnknownPersistentID is returned by PersistentRegistry when no resolver
+// is registered for the typename of a persistent ID.
+var ErrUnknownPersistentID = errors.New("unknown persistent ID")
+
+// PersistentID is a persistent ID loaded by the PERSID or BINPERSID
+// opcodes, as returned by Python "Pickler.persistent_id" when the pickle
+// was written.
+//
+// Persistent IDs are commonly tuples whose first item is a string naming
+// the kind of the referenced object, such as ('storage', ...) and
+// ('module', ...) in PyTorch files: Typename and Args split them.
+type PersistentID struct {
+	// Value is the persistent ID: a string for the PERSID opcode, any value
+	// for BINPERSID.
+	Value interface{}
+}
+
+// Typename returns the first item of the persistent ID, if it is a
+// non-empty tuple starting with a string.
+func (id PersistentID) Typename() (string, bool) {
+	t, ok := id.Value.(*types.Tuple)
+	if !ok || t.Len() == 0 {
+		return "", false
+	}
+	typename, ok := t.Get(0).(string)
+	return typename, ok
+}
+
+// Args returns the items of a tuple persistent ID following the first one.
+// It returns an empty tuple if the persistent ID is not a tuple.
+func (id PersistentID) Args() *types.Tuple {
+	t, ok := id.Value.(*types.Tuple)
+	if !ok || t.Len() == 0 {
+		return types.NewTupleFromSlice(nil)
+	}
+	return types.NewTupleFromSlice((*t)[1:])
+}
+
+// String returns the persistent ID as Python repr().
+func (id PersistentID) String() string {
+	return types.Repr(id.Value)
+}
+
+// PersistentResolver resolves persistent IDs to the referenced objects,
+// like Python "Unpickler.persistent_load". See Unpickler.PersistentResolver.
+type PersistentResolver interface {
+	ResolvePersistentID(id PersistentID) (interface{}, error)
+}
+
+// PersistentResolverFunc is a function implementing PersistentResolver.
+type PersistentResolverFunc func(id PersistentID) (interface{}, error)
+
+// ResolvePersistentID calls f(id).
+func (f PersistentResolverFunc) ResolvePersistentID(id PersistentID) (interface{}, error) {
+	return f(id)
+}
+
+// PersistentRegistry is a PersistentResolver dispatching each persistent
+// ID to the resolver registered for its typename (see
+// PersistentID.Typename), such as "storage" for PyTorch tensor data.
+//
+// A PersistentRegistry is safe for concurrent use by multiple goroutines.
+// The zero value is an empty registry, ready to use.
+type PersistentRegistry struct {
+	mu        sync.RWMutex
+	resolvers map[string]PersistentResolver
+	// Default, if not nil, resolves the persistent IDs without a registered
+	// typename. Otherwise, they are reported as ErrUnknownPersistentID.
+	Default PersistentResolver
+}
+
+var _ PersistentResolver = &PersistentRegistry{}
+
+// NewPersistentRegistry returns a new empty PersistentRegistry.
+func NewPersistentRegistry() *PersistentRegistry {
+	return &PersistentRegistry{}
+}
+
+// Register sets the resolver of the persistent IDs with the given
+// typename, replacing any previous one.
+func (r *PersistentRegistry) Register(typename string, resolver PersistentResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resolvers == nil {
+		r.resolvers = make(map[string]PersistentResolver)
+	}
+	r.resolvers[typename] = resolver
+}
+
+// RegisterFunc is like Register, for a resolver function.
+func (r *PersistentRegistry) RegisterFunc(typename string, f func(id PersistentID) (interface{}, error)) {
+	r.Register(typename, PersistentResolverFunc(f))
+}
+
+// ResolvePersistentID calls the resolver registered for the typename of
+// id, or the Default one.
+func (r *PersistentRegistry) ResolvePersistentID(id PersistentID) (interface{}, error) {
+	typename, ok := id.Typename()
+	var resolver PersistentResolver
+	if ok {
+		r.mu.RLock()
+		resolver = r.resolvers[typename]
+		r.mu.RUnlock()
+	}
+	if resolver == nil {
+		resolver = r.Default
+	}
+	if resolver == nil {
+		if ok {
+			return nil, fmt.Errorf("%w: typename %q", ErrUnknownPersistentID, typename)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPersistentID, id)
+	}
+	result, err := resolver.ResolvePersistentID(id)
+	if err != nil && ok {
+		return nil, fmt.Errorf("persistent ID %q: %w", typename, err)
+	}
+	return result, err
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+func TestPersistentID(t *testing.T) {
+	id := PersistentID{Value: types.NewTupleFromSlice([]interface{}{"storage", 1, "k"})}
+	if typename, ok := id.Typename(); !ok || typename != "storage" {
+		t.Errorf("Typename() = %q, %v", typename, ok)
+	}
+	if args := id.Args(); args.Len() != 2 || args.Get(0) != 1 || args.Get(1) != "k" {
+		t.Errorf("Args() = %v", args)
+	}
+	if s := id.String(); s != "('storage', 1, 'k')" {
+		t.Errorf("String() = %s", s)
+	}
+
+	for _, value := range []interface{}{"storage", types.NewTupleFromSlice(nil),
+		types.NewTupleFromSlice([]interface{}{1, "storage"})} {
+		id := PersistentID{Value: value}
+		if _, ok := id.Typename(); ok {
+			t.Errorf("%s: unexpected typename", id)
+		}
+	}
+	if args := (PersistentID{Value: "storage"}).Args(); args.Len() != 0 {
+		t.Errorf("string ID: unexpected args %v", args)
+	}
+}
+
+func TestPersistentRegistry(t *testing.T) {
+	var r PersistentRegistry // the zero value is usable
+	r.RegisterFunc("db", func(id PersistentID) (interface{}, error) {
+		key, err := id.Args().GetString(0)
+		if err != nil {
+			return nil, err
+		}
+		return "record " + key, nil
+	})
+
+	// ('db', '1') and ('other', 2) with BINPERSID, then 'x' with PERSID
+	data := "\x80\x04\x8c\x02db\x8c\x011\x86Q\x8c\x05otherK\x02\x86QPx\n\x87."
+	u := NewUnpicklerBytes([]byte(data))
+	u.PersistentResolver = &r
+	_, err := u.Load()
+	if !errors.Is(err, ErrUnknownPersistentID) || !strings.Contains(err.Error(), `"other"`) {
+		t.Errorf("expected ErrUnknownPersistentID for 'other', actual %v", err)
+	}
+
+	var defaultIDs []PersistentID
+	r.Default = PersistentResolverFunc(func(id PersistentID) (interface{}, error) {
+		defaultIDs = append(defaultIDs, id)
+		return id.Value, nil
+	})
+	u = NewUnpicklerBytes([]byte(data))
+	u.PersistentResolver = &r
+	result, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "('record 1', ('other', 2), 'x')"
+	if actual := types.Repr(result); actual != expected {
+		t.Errorf("expected %s, actual %s", expected, actual)
+	}
+	if len(defaultIDs) != 2 {
+		t.Errorf("unexpected IDs resolved by Default: %v", defaultIDs)
+	}
+
+	u = NewUnpicklerBytes([]byte("\x80\x02\x8c\x02db\x85Q."))
+	u.PersistentResolver = &r
+	if _, err := u.Load(); err == nil || !strings.Contains(err.Error(), `persistent ID "db"`) {
+		t.Errorf("expected error naming the typename, actual %v", err)
+	}
+}
+
+func TestPicklerPersistentID(t *testing.T) {
+	persistentID := func(v interface{}) (interface{}, error) {
+		if s, ok := v.(string); ok && strings.HasPrefix(s, "db:") {
+			return types.NewTupleFromSlice([]interface{}{"db", s[3:]}), nil
+		}
+		return nil, nil
+	}
+	value := &types.List{"x", "db:1", types.NewTupleFromSlice([]interface{}{"db:2"})}
+
+	var buf strings.Builder
+	p := NewPickler(&buf, 4)
+	p.PersistentID = persistentID
+	if err := p.Dump(value); err != nil {
+		t.Fatal(err)
+	}
+	// written by Python 3.11
+	expected := "\x80\x04\x95 \x00\x00\x00\x00\x00\x00\x00]\x94(\x8c\x01x\x94\x8c\x02db\x94\x8c\x011\x94\x86\x94Qh\x02\x8c\x012\x94\x86\x94Q\x85\x94e."
+	if buf.String() != expected {
+		t.Errorf("expected %q\nactual   %q", expected, buf.String())
+	}
+
+	r := NewPersistentRegistry()
+	r.RegisterFunc("db", func(id PersistentID) (interface{}, error) {
+		key, err := id.Args().GetString(0)
+		return "db:" + key, err
+	})
+	u := NewUnpicklerBytes([]byte(buf.String()))
+	u.PersistentResolver = r
+	result, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.Repr(result) != types.Repr(value) {
+		t.Errorf("expected %s, actual %s", types.Repr(value), types.Repr(result))
+	}
+}
@@ -11,11 +11,11 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"math/big"
 	"os"
+	"reflect"
 	"strconv"
-	"strings"
 
+	"github.com/nlpodyssey/gopickle/internal/mmap"
 	"github.com/nlpodyssey/gopickle/types"
 )
 
@@ -32,50 +32,82 @@ func Load(filename string) (interface{}, error) {
 }
 
 func Loads(s string) (interface{}, error) {
-	sr := strings.NewReader(s)
-	u := NewUnpickler(sr)
+	u := NewUnpicklerBytes([]byte(s))
 	return u.Load()
 }
 
-type reader interface {
-	io.Reader
-	io.ByteReader
-}
-
-type bytereader struct {
-	io.Reader
-	bytebuf [1]byte
-}
-
-func (r *bytereader) ReadByte() (byte, error) {
-	n, err := r.Read(r.bytebuf[:])
-	if n == 1 {
-		return r.bytebuf[0], nil
+// LoadMmap loads a pickle file, memory-mapping it on Linux so that large
+// files are loaded with little copying.
+//
+// Loaded bytes and bytearray values alias the mapped file data (see
+// Unpickler.ZeroCopy): they must not be used after the returned Closer is
+// closed. Modifications to such values are never written back to the file.
+func LoadMmap(filename string) (interface{}, io.Closer, error) {
+	m, err := mmap.Open(filename)
+	if err != nil {
+		return nil, nil, err
 	}
-	if err == nil {
-		err = io.EOF
+	u := NewUnpicklerBytes(m.Bytes())
+	u.ZeroCopy = true
+	result, err := u.Load()
+	if err != nil {
+		_ = m.Close()
+		return nil, nil, err
 	}
-	return 0, err
+	return result, m, nil
 }
 
 type Unpickler struct {
-	r              reader
-	proto          byte
-	currentFrame   *bytes.Reader
-	stack          []interface{}
-	metaStack      [][]interface{}
-	memo           map[int]interface{}
+	r            reader
+	proto        byte
+	currentFrame *sliceReader
+	frame        sliceReader
+	frameBuf     []byte
+	scratch      []byte
+	pos          int64
+	stack        []interface{}
+	metaStack    [][]interface{}
+	memo         map[int]interface{}
+	// ZeroCopy makes bytes and bytearray values alias the input data,
+	// instead of being copied. It is effective only on Unpicklers created
+	// with NewUnpicklerBytes: the input slice must not be modified for as
+	// long as the loaded values are in use.
+	ZeroCopy bool
+	// IntMode selects the Go type of the loaded integers. The default is
+	// NativeInt.
+	IntMode        IntMode
 	FindClass      func(module, name string) (interface{}, error)
 	PersistentLoad func(interface{}) (interface{}, error)
-	GetExtension   func(code int) (interface{}, error)
-	NextBuffer     func() (interface{}, error)
-	MakeReadOnly   func(interface{}) (interface{}, error)
-}
-
+	// PersistentResolver, if not nil, resolves the persistent IDs of the
+	// PERSID and BINPERSID opcodes, when PersistentLoad is nil.
+	PersistentResolver PersistentResolver
+	GetExtension       func(code int) (interface{}, error)
+	// Extensions, if not nil, resolves the extension codes of the EXT1,
+	// EXT2 and EXT4 opcodes, when GetExtension is nil.
+	Extensions *ExtensionRegistry
+	// NextBuffer supplies the out-of-band buffers of the NEXT_BUFFER
+	// opcode; see WithBuffers.
+	NextBuffer func() (interface{}, error)
+	// MakeReadOnly, if not nil, handles the READONLY_BUFFER opcode. By
+	// default, a *types.PickleBuffer is replaced with a read-only one,
+	// and any other value is left unchanged.
+	MakeReadOnly func(interface{}) (interface{}, error)
+}
+
+// NewUnpickler returns an Unpickler reading from ior.
+//
+// Once Load returns, ior is positioned right after the end of the pickle,
+// so that any following data can be read from it. If ior implements
+// io.Seeker, such as an *os.File, it is read ahead in blocks, and seeked
+// back when Load returns. Otherwise, the Unpickler never reads past the end
+// of the pickle: each opcode is read along with the arguments of the
+// previous one, so that reading an unbuffered ior costs about one Read call
+// per opcode. Wrapping such an ior in a bufio.Reader is faster, if reading
+// further data is not needed, or is done from the bufio.Reader too.
 func NewUnpickler(ior io.Reader) Unpickler {
 	r, ok := ior.(reader)
 	if !ok {
-		r = &bytereader{Reader: ior}
+		r = newBufReader(ior)
 	}
 	return Unpickler{
 		r:    r,
@@ -83,20 +115,117 @@ func NewUnpickler(ior io.Reader) Unpickler {
 	}
 }
 
+// NewUnpicklerBytes returns an Unpickler reading from the given in-memory
+// pickle data. Reading opcodes and frames does not involve any copy of the
+// input data; see also Unpickler.ZeroCopy.
+func NewUnpicklerBytes(b []byte) Unpickler {
+	return Unpickler{
+		r:    &sliceReader{data: b},
+		memo: make(map[int]interface{}, 256+128),
+	}
+}
+
+// Reset discards the state of the Unpickler, making it read a new pickle
+// from r, as if it were returned by NewUnpickler(r).
+//
+// The memo and the stacks are cleared, but their allocated memory is
+// retained. The configuration (ZeroCopy and the callback functions) is
+// preserved.
+func (u *Unpickler) Reset(r io.Reader) {
+	u.clear()
+	if rr, ok := r.(reader); ok {
+		u.r = rr
+	} else if br, ok := u.r.(*bufReader); ok {
+		br.reset(r)
+	} else {
+		u.r = newBufReader(r)
+	}
+}
+
+// ResetBytes is like Reset, but makes the Unpickler read from the given
+// in-memory pickle data, as if it were returned by NewUnpicklerBytes(b).
+func (u *Unpickler) ResetBytes(b []byte) {
+	u.clear()
+	if sr, ok := u.r.(*sliceReader); ok {
+		*sr = sliceReader{data: b}
+	} else {
+		u.r = &sliceReader{data: b}
+	}
+}
+
+// clear drops any reference to the input and to the loaded values, keeping
+// the allocated memory.
+func (u *Unpickler) clear() {
+	switch r := u.r.(type) {
+	case *bufReader:
+		_ = r.unread()
+		r.reset(nil)
+	case *sliceReader:
+		*r = sliceReader{}
+	default:
+		u.r = nil
+	}
+	u.proto = 0
+	u.currentFrame = nil
+	u.frame = sliceReader{}
+	u.pos = 0
+	u.resetStacks()
+	if u.memo == nil {
+		u.memo = make(map[int]interface{}, 256+128)
+	}
+	for k := range u.memo {
+		delete(u.memo, k)
+	}
+}
+
+// resetStacks empties the stack and the meta-stack, keeping their capacity.
+func (u *Unpickler) resetStacks() {
+	for i := range u.stack {
+		u.stack[i] = nil
+	}
+	for i := range u.metaStack {
+		u.metaStack[i] = nil
+	}
+	if u.stack == nil {
+		u.stack = make([]interface{}, 0, 16)
+	}
+	if u.metaStack == nil {
+		u.metaStack = make([][]interface{}, 0, 16)
+	}
+	u.stack = u.stack[:0]
+	u.metaStack = u.metaStack[:0]
+}
+
 func (u *Unpickler) Load() (interface{}, error) {
-	u.metaStack = make([][]interface{}, 0, 16)
-	u.stack = make([]interface{}, 0, 16)
+	result, err := u.load()
+	if br, ok := u.r.(*bufReader); ok {
+		if unreadErr := br.unread(); unreadErr != nil && err == nil {
+			return nil, unreadErr
+		}
+	}
+	return result, err
+}
+
+func (u *Unpickler) load() (interface{}, error) {
+	u.resetStacks()
 	u.proto = 0
 
 	for {
+		offset := u.pos
 		opcode, err := u.readOne()
 		if err != nil {
-			return nil, err
+			return nil, u.newUnpicklingError(err, offset, 0, false)
 		}
 
 		opFunc := dispatch[opcode]
 		if opFunc == nil {
-			return nil, fmt.Errorf("unknown opcode: 0x%x '%c'", opcode, opcode)
+			err = fmt.Errorf("%w: 0x%x '%c'", ErrUnknownOpcode, opcode, opcode)
+			return nil, u.newUnpicklingError(err, offset, opcode, false)
+		}
+		if br, ok := u.r.(*bufReader); ok && opcode != '.' && !u.inFrame() {
+			// any opcode but STOP is followed by its arguments and by
+			// another opcode
+			br.expect(int(opcodeArgSizes[opcode]) + 1)
 		}
 
 		err = opFunc(u)
@@ -104,7 +233,7 @@ func (u *Unpickler) Load() (interface{}, error) {
 			if p, ok := err.(pickleStop); ok {
 				return p.value, nil
 			}
-			return nil, err
+			return nil, u.newUnpicklingError(err, offset, opcode, true)
 		}
 	}
 }
@@ -128,11 +257,26 @@ func (u *Unpickler) findClass(module, name string) (interface{}, error) {
 			return &types.List{}, nil
 		case "dict":
 			return &types.Dict{}, nil
+		case "set":
+			return &types.Set{}, nil
+		case "frozenset":
+			return &types.FrozenSet{}, nil
 		}
 	case "__builtin__":
 		switch name {
 		case "object":
 			return &types.ObjectClass{}, nil
+		case "set":
+			return &types.Set{}, nil
+		case "frozenset":
+			return &types.FrozenSet{}, nil
+		case "bytes":
+			return bytesClass{}, nil
+		}
+	case "_codecs":
+		switch name {
+		case "encode":
+			return codecsEncode{}, nil
 		}
 	case "array":
 		switch name {
@@ -151,99 +295,216 @@ func (u *Unpickler) findClass(module, name string) (interface{}, error) {
 	return types.NewGenericClass(module, name), nil
 }
 
-func (u *Unpickler) read(n int) ([]byte, error) {
-	buf := make([]byte, n)
+// bytesClass is the Python "bytes" class, which is called without
+// arguments by the pickles of empty bytes written with protocol 2.
+type bytesClass struct{}
 
-	if u.currentFrame != nil {
-		m, err := io.ReadFull(u.currentFrame, buf)
-		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			return nil, err
+func (bytesClass) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("bytes: invalid arguments: %#v", args)
+	}
+	return []byte{}, nil
+}
+
+// codecsEncode is the Python function "_codecs.encode", which is called by
+// the pickles of bytes written with protocol 2, with the bytes decoded as a
+// Latin-1 string.
+type codecsEncode struct{}
+
+func (codecsEncode) Call(args ...interface{}) (interface{}, error) {
+	if len(args) == 2 {
+		s, sOk := args[0].(string)
+		encoding, encodingOk := args[1].(string)
+		if sOk && encodingOk && (encoding == "latin1" || encoding == "latin-1") {
+			b := make([]byte, 0, len(s))
+			for _, r := range s {
+				if r > 0xff {
+					return nil, fmt.Errorf("_codecs.encode: cannot encode %U to Latin-1", r)
+				}
+				b = append(b, byte(r))
+			}
+			return b, nil
 		}
-		if m == 0 && n != 0 {
-			u.currentFrame = nil
-			m, err := io.ReadFull(u.r, buf)
-			return buf[0:m], err
+	}
+	return nil, fmt.Errorf("_codecs.encode: unsupported arguments: %#v", args)
+}
+
+// maxPrealloc is the maximum amount of bytes allocated upfront for reading
+// opcode arguments and frames. Longer data is accumulated while it is
+// actually read, so that a corrupted length can't cause a huge allocation.
+const maxPrealloc = 1 << 20
+
+// maxPeek is the maximum size of the opcode arguments which are read
+// through reader.Peek, into the reader's own buffer. Longer arguments are
+// read into newly allocated memory.
+const maxPeek = 4096
+
+// inFrame reports whether the data is being read from a frame, discarding
+// the current frame if it has been fully read.
+func (u *Unpickler) inFrame() bool {
+	if u.currentFrame != nil && u.currentFrame.Len() == 0 {
+		u.currentFrame = nil
+	}
+	return u.currentFrame != nil
+}
+
+// read reads the next n bytes. The returned slice must not be retained: it
+// may be overwritten by the next read.
+func (u *Unpickler) read(n int) ([]byte, error) {
+	if u.inFrame() {
+		if n > u.currentFrame.Len() {
+			return nil, fmt.Errorf("%w: pickle exhausted before end of frame", ErrTruncated)
 		}
-		if m < n {
-			return nil, fmt.Errorf("pickle exhausted before end of frame")
+		buf, _ := u.currentFrame.next(n)
+		u.pos += int64(n)
+		return buf, nil
+	}
+
+	var buf []byte
+	var err error
+	if sr, ok := u.r.(*sliceReader); ok {
+		buf, err = sr.next(n)
+	} else if n <= maxPeek {
+		if br, ok := u.r.(*bufReader); ok {
+			// the arguments of an opcode are followed by another opcode
+			br.expect(n + 1)
 		}
-		return buf[0:m], nil
+		buf, err = u.r.Peek(n)
+		if err == bufio.ErrBufferFull {
+			buf, err = readFull(u.r, n)
+		} else {
+			if err == io.EOF && len(buf) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			_, _ = u.r.Discard(len(buf))
+		}
+	} else {
+		buf, err = readFull(u.r, n)
+	}
+	u.pos += int64(len(buf))
+	return buf, err
+}
+
+// readBytes is like read, but the returned slice is always safe to be
+// retained by the caller: it aliases the input data only if ZeroCopy is
+// enabled.
+func (u *Unpickler) readBytes(n int) ([]byte, error) {
+	_, isSlice := u.r.(*sliceReader)
+	if isSlice && u.ZeroCopy {
+		return u.read(n)
 	}
+	if !isSlice && n > maxPeek && !u.inFrame() {
+		// read allocates a new slice anyway
+		return u.read(n)
+	}
+	buf, err := u.read(n)
+	if err != nil {
+		return buf, err
+	}
+	return append(make([]byte, 0, len(buf)), buf...), nil
+}
+
+// readFull reads exactly n bytes from r, with the same semantics of
+// io.ReadFull.
+func readFull(r io.Reader, n int) ([]byte, error) {
+	return readFullInto(r, nil, n)
+}
 
-	m, err := io.ReadFull(u.r, buf)
-	return buf[0:m], err
+// readFullInto is like readFull, but reuses buf if it is large enough.
+func readFullInto(r io.Reader, buf []byte, n int) ([]byte, error) {
+	if n <= cap(buf) || n <= maxPrealloc {
+		if n > cap(buf) {
+			buf = make([]byte, n)
+		}
+		m, err := io.ReadFull(r, buf[:n])
+		return buf[:m], err
+	}
+	b := bytes.NewBuffer(make([]byte, 0, maxPrealloc))
+	m, err := io.CopyN(b, r, int64(n))
+	if err == io.EOF && m > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return b.Bytes(), err
 }
 
 func (u *Unpickler) readOne() (byte, error) {
-	var err error
 	var b byte
-	if u.currentFrame != nil {
+	var err error
+	if u.inFrame() {
 		b, err = u.currentFrame.ReadByte()
 	} else {
 		b, err = u.r.ReadByte()
 	}
-	if err == nil {
-		return b, nil
-	}
-
-	buf, err := u.read(1)
 	if err != nil {
 		return 0, err
 	}
-	return buf[0], nil
+	u.pos++
+	return b, nil
 }
 
+// readLine reads a newline-terminated opcode argument, returning it without
+// the final newline. The returned slice must not be retained.
 func (u *Unpickler) readLine() ([]byte, error) {
-	if u.currentFrame != nil {
-		line, err := readLine(u.currentFrame)
+	if u.inFrame() {
+		line, err := u.currentFrame.nextLine()
 		if err != nil {
-			if err == io.EOF && len(line) == 0 {
-				u.currentFrame = nil
-				return readLine(u.r)
-			}
-			return nil, err
-		}
-		if len(line) == 0 {
-			return nil, fmt.Errorf("readLine no data")
+			u.pos += int64(len(line))
+			return nil, fmt.Errorf("%w: pickle exhausted before end of frame", ErrTruncated)
 		}
-		if line[len(line)-1] != '\n' {
-			return nil, fmt.Errorf("pickle exhausted before end of frame")
+		u.pos += int64(len(line)) + 1
+		return line, nil
+	}
+	if sr, ok := u.r.(*sliceReader); ok {
+		line, err := sr.nextLine()
+		if err != nil {
+			u.pos += int64(len(line))
+			return nil, err
 		}
+		u.pos += int64(len(line)) + 1
 		return line, nil
 	}
-	return readLine(u.r)
-}
-
-func readLine(r reader) (line []byte, err error) {
-	line = make([]byte, 0, 32)
 
-	var b byte
+	line := u.scratch[:0]
 	for {
-		b, err = r.ReadByte()
+		b, err := u.r.ReadByte()
 		if err != nil {
-			return
+			u.scratch = line
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
 		}
-		line = append(line, b)
+		u.pos++
 		if b == '\n' {
-			return
+			u.scratch = line
+			return line, nil
 		}
+		line = append(line, b)
 	}
 }
 
 func (u *Unpickler) loadFrame(frameSize int) error {
-	buf := make([]byte, frameSize)
-	if u.currentFrame != nil {
-		n, err := (*u.currentFrame).Read(buf)
-		if n > 0 || err == nil {
-			return fmt.Errorf(
-				"beginning of a new frame before end of current frame")
+	if u.currentFrame != nil && u.currentFrame.Len() > 0 {
+		return fmt.Errorf(
+			"beginning of a new frame before end of current frame")
+	}
+	var buf []byte
+	var err error
+	if sr, ok := u.r.(*sliceReader); ok {
+		buf, err = sr.next(frameSize)
+	} else {
+		// Values loaded from frames never alias the frame data, unless
+		// reading from a sliceReader, so the buffer can be reused.
+		buf, err = readFullInto(u.r, u.frameBuf, frameSize)
+		if cap(buf) > cap(u.frameBuf) {
+			u.frameBuf = buf
 		}
 	}
-	_, err := io.ReadFull(u.r, buf)
 	if err != nil {
 		return err
 	}
-	u.currentFrame = bytes.NewReader(buf)
+	u.frame = sliceReader{data: buf}
+	u.currentFrame = &u.frame
 	return nil
 }
 
@@ -253,7 +514,7 @@ func (u *Unpickler) append(element interface{}) {
 
 func (u *Unpickler) stackLast() (interface{}, error) {
 	if len(u.stack) == 0 {
-		return nil, fmt.Errorf("the stack is empty")
+		return nil, fmt.Errorf("%w: the stack is empty", ErrStackUnderflow)
 	}
 	return u.stack[len(u.stack)-1], nil
 }
@@ -269,7 +530,7 @@ func (u *Unpickler) stackPop() (interface{}, error) {
 
 func (u *Unpickler) metaStackLast() ([]interface{}, error) {
 	if len(u.metaStack) == 0 {
-		return nil, fmt.Errorf("the meta stack is empty")
+		return nil, fmt.Errorf("%w: no MARK on the stack", ErrStackUnderflow)
 	}
 	return u.metaStack[len(u.metaStack)-1], nil
 }
@@ -294,7 +555,18 @@ func (u *Unpickler) popMark() ([]interface{}, error) {
 	return items, nil
 }
 
-var dispatch [math.MaxUint8]func(*Unpickler) error
+var dispatch [math.MaxUint8 + 1]func(*Unpickler) error
+
+// opcodeArgSizes holds the minimum size of the arguments of the opcodes:
+// the size of their fixed-size arguments, the length prefix of the
+// variable-size ones, and a newline for each line-terminated one.
+var opcodeArgSizes = [math.MaxUint8 + 1]uint8{
+	'F': 1, 'I': 1, 'J': 4, 'K': 1, 'L': 1, 'M': 2, 'P': 1, 'S': 1,
+	'T': 4, 'U': 1, 'V': 1, 'X': 4, 'c': 2, 'g': 1, 'h': 1, 'i': 2,
+	'j': 4, 'p': 1, 'q': 1, 'r': 4, 'G': 8, 'B': 4, 'C': 1,
+	'\x80': 1, '\x82': 1, '\x83': 2, '\x84': 4, '\x8a': 1, '\x8b': 4,
+	'\x8c': 1, '\x8d': 8, '\x8e': 8, '\x95': 8, '\x96': 8,
+}
 
 func init() {
 	// Initialize `dispatch` assigning functions to opcodes
@@ -403,40 +675,44 @@ func loadFrame(u *Unpickler) error {
 		return err
 	}
 	frameSize := binary.LittleEndian.Uint64(buf)
-	if frameSize > math.MaxInt64 {
-		return fmt.Errorf("frame size > max int64: %d", frameSize)
+	if frameSize > math.MaxInt {
+		return fmt.Errorf("frame size exceeds system's maximum size: %d", frameSize)
 	}
 	return u.loadFrame(int(frameSize))
 }
 
 // push persistent object; id is taken from string arg
 func loadPersId(u *Unpickler) error {
-	if u.PersistentLoad == nil {
+	if u.PersistentLoad == nil && u.PersistentResolver == nil {
 		return fmt.Errorf("unsupported persistent ID encountered")
 	}
 	line, err := u.readLine()
 	if err != nil {
 		return err
 	}
-	pid := string(line[:len(line)-1])
-	result, err := u.PersistentLoad(pid)
-	if err != nil {
-		return err
-	}
-	u.append(result)
-	return nil
+	return u.persistentLoad(string(line))
 }
 
 // push persistent object; id is taken from stack
 func loadBinPersId(u *Unpickler) error {
-	if u.PersistentLoad == nil {
+	if u.PersistentLoad == nil && u.PersistentResolver == nil {
 		return fmt.Errorf("unsupported persistent ID encountered")
 	}
 	pid, err := u.stackPop()
 	if err != nil {
 		return err
 	}
-	result, err := u.PersistentLoad(pid)
+	return u.persistentLoad(pid)
+}
+
+func (u *Unpickler) persistentLoad(pid interface{}) error {
+	var result interface{}
+	var err error
+	if u.PersistentLoad != nil {
+		result, err = u.PersistentLoad(pid)
+	} else {
+		result, err = u.PersistentResolver.ResolvePersistentID(PersistentID{Value: pid})
+	}
 	if err != nil {
 		return err
 	}
@@ -468,7 +744,7 @@ func loadInt(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	data := string(line[:len(line)-1])
+	data := string(line)
 	if len(data) == 2 && data[0] == '0' && data[1] == '0' {
 		u.append(false)
 		return nil
@@ -477,7 +753,7 @@ func loadInt(u *Unpickler) error {
 		u.append(true)
 		return nil
 	}
-	i, err := strconv.Atoi(data)
+	i, err := u.parseInt(data)
 	if err != nil {
 		return err
 	}
@@ -491,7 +767,7 @@ func loadBinInt(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	u.append(decodeInt32(buf))
+	u.append(u.intValue(int64(decodeInt32(buf))))
 	return nil
 }
 
@@ -501,7 +777,7 @@ func loadBinInt1(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	u.append(int(i))
+	u.append(u.intValue(int64(i)))
 	return nil
 }
 
@@ -511,7 +787,7 @@ func loadBinInt2(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	u.append(int(binary.LittleEndian.Uint16(buf)))
+	u.append(u.intValue(int64(binary.LittleEndian.Uint16(buf))))
 	return nil
 }
 
@@ -521,28 +797,17 @@ func loadLong(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	sub := line[:len(line)-1]
-	if len(sub) == 0 {
+	if len(line) == 0 {
 		return fmt.Errorf("invalid long data")
 	}
-	if sub[len(sub)-1] == 'L' {
-		sub = sub[0 : len(sub)-1]
+	if line[len(line)-1] == 'L' {
+		line = line[0 : len(line)-1]
 	}
-	str := string(sub)
-	i, err := strconv.ParseInt(str, 10, 64)
-
+	i, err := u.parseInt(string(line))
 	if err != nil {
-		if ne, isNe := err.(*strconv.NumError); isNe && ne.Err == strconv.ErrRange {
-			bi, ok := new(big.Int).SetString(str, 10)
-			if !ok {
-				return fmt.Errorf("invalid long data")
-			}
-			u.append(bi)
-			return nil
-		}
 		return err
 	}
-	u.append(int(i))
+	u.append(i)
 	return nil
 }
 
@@ -557,7 +822,7 @@ func loadLong1(u *Unpickler) error {
 		return err
 	}
 
-	u.append(decodeLong(data))
+	u.append(u.decodeLong(data))
 	return nil
 }
 
@@ -576,50 +841,17 @@ func loadLong4(u *Unpickler) error {
 		return err
 	}
 
-	u.append(decodeLong(data))
+	u.append(u.decodeLong(data))
 	return nil
 }
 
-func decodeLong(bytes []byte) interface{} {
-	msBitSet := bytes[len(bytes)-1]&0x80 != 0
-
-	if len(bytes) > 8 {
-		bi := new(big.Int)
-		_ = bytes[len(bytes)-1]
-		for i := len(bytes) - 1; i >= 0; i-- {
-			bi = bi.Lsh(bi, 8)
-			if msBitSet {
-				bi = bi.Or(bi, big.NewInt(int64(^bytes[i])))
-			} else {
-				bi = bi.Or(bi, big.NewInt(int64(bytes[i])))
-			}
-		}
-		if msBitSet {
-			bi = bi.Add(bi, big.NewInt(1))
-			bi = bi.Neg(bi)
-		}
-		return bi
-	}
-
-	var ux, bitMask uint64
-	_ = bytes[len(bytes)-1]
-	for i := len(bytes) - 1; i >= 0; i-- {
-		ux = (ux << 8) | uint64(bytes[i])
-		bitMask = (bitMask << 8) | 0xFF
-	}
-	if msBitSet {
-		return -(int(^ux&bitMask) + 1)
-	}
-	return int(ux)
-}
-
 // push float object; decimal string argument
 func loadFloat(u *Unpickler) error {
 	line, err := u.readLine()
 	if err != nil {
 		return err
 	}
-	f, err := strconv.ParseFloat(string(line[:len(line)-1]), 64)
+	f, err := strconv.ParseFloat(string(line), 64)
 	if err != nil {
 		return err
 	}
@@ -643,12 +875,11 @@ func loadString(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	data := line[:len(line)-1]
 	// Strip outermost quotes
-	if !isQuotedString(data) {
+	if !isQuotedString(line) {
 		return fmt.Errorf("the STRING opcode argument must be quoted")
 	}
-	data = data[1 : len(data)-1]
+	data := line[1 : len(line)-1]
 	// TODO: decode to string with the desired decoder
 	u.append(string(data))
 	return nil
@@ -684,8 +915,11 @@ func loadBinBytes(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	length := int(binary.LittleEndian.Uint32(buf))
-	buf, err = u.read(length)
+	length := binary.LittleEndian.Uint32(buf)
+	if uint64(length) > math.MaxInt {
+		return fmt.Errorf("BINBYTES exceeds system's maximum size")
+	}
+	buf, err = u.readBytes(int(length))
 	if err != nil {
 		return err
 	}
@@ -699,7 +933,7 @@ func loadUnicode(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	u.append(string(line[:len(line)-1]))
+	u.append(string(line))
 	return nil
 }
 
@@ -709,8 +943,11 @@ func loadBinUnicode(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	length := int(binary.LittleEndian.Uint32(buf))
-	buf, err = u.read(length)
+	length := binary.LittleEndian.Uint32(buf)
+	if uint64(length) > math.MaxInt {
+		return fmt.Errorf("BINUNICODE exceeds system's maximum size")
+	}
+	buf, err = u.read(int(length))
 	if err != nil {
 		return err
 	}
@@ -725,7 +962,7 @@ func loadBinUnicode8(u *Unpickler) error {
 		return err
 	}
 	length := binary.LittleEndian.Uint64(buf)
-	if length > math.MaxInt64 {
+	if length > math.MaxInt {
 		return fmt.Errorf("BINUNICODE8 exceeds system's maximum size")
 	}
 	buf, err = u.read(int(length))
@@ -743,10 +980,10 @@ func loadBinBytes8(u *Unpickler) error {
 		return err
 	}
 	length := binary.LittleEndian.Uint64(buf)
-	if length > math.MaxInt64 {
+	if length > math.MaxInt {
 		return fmt.Errorf("BINBYTES8 exceeds system's maximum size")
 	}
-	buf, err = u.read(int(length))
+	buf, err = u.readBytes(int(length))
 	if err != nil {
 		return err
 	}
@@ -761,10 +998,10 @@ func loadByteArray8(u *Unpickler) error {
 		return err
 	}
 	length := binary.LittleEndian.Uint64(buf)
-	if length > math.MaxInt64 {
+	if length > math.MaxInt {
 		return fmt.Errorf("BYTEARRAY8 exceeds system's maximum size")
 	}
-	buf, err = u.read(int(length))
+	buf, err = u.readBytes(int(length))
 	if err != nil {
 		return err
 	}
@@ -787,13 +1024,18 @@ func loadNextBuffer(u *Unpickler) error {
 
 // make top of stack readonly
 func loadReadOnlyBuffer(u *Unpickler) error {
-	if u.MakeReadOnly == nil {
-		return nil
-	}
-	buf, err := u.stackPop()
+	buf, err := u.stackLast()
 	if err != nil {
 		return err
 	}
+	if u.MakeReadOnly == nil {
+		if b, ok := buf.(*types.PickleBuffer); ok && !b.ReadOnly {
+			// a read-only view, leaving the original buffer unchanged
+			u.stack[len(u.stack)-1] = types.NewPickleBuffer(b.Data, true)
+		}
+		return nil
+	}
+	u.stack = u.stack[:len(u.stack)-1]
 	buf, err = u.MakeReadOnly(buf)
 	if err != nil {
 		return err
@@ -823,7 +1065,7 @@ func loadShortBinBytes(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	buf, err := u.read(int(length))
+	buf, err := u.readBytes(int(length))
 	if err != nil {
 		return err
 	}
@@ -927,6 +1169,11 @@ func loadFrozenSet(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
+	for _, item := range items {
+		if _, err := types.Hash(item); err != nil {
+			return fmt.Errorf("FROZENSET: %w", err)
+		}
+	}
 	u.append(types.NewFrozenSetFromSlice(items))
 	return nil
 }
@@ -947,9 +1194,15 @@ func loadDict(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	d := types.NewDict()
 	n := len(items)
-	for i := 0; i < n-1; i += 2 {
+	if n%2 != 0 {
+		return fmt.Errorf("DICT requires an even number of items")
+	}
+	d := types.NewDict()
+	for i := 0; i < n; i += 2 {
+		if err := checkHashable(d, items[i]); err != nil {
+			return fmt.Errorf("DICT: %w", err)
+		}
 		d.Set(items[i], items[i+1])
 	}
 	u.append(d)
@@ -962,13 +1215,13 @@ func loadInst(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	module := string(line[0 : len(line)-1])
+	module := string(line)
 
 	line, err = u.readLine()
 	if err != nil {
 		return err
 	}
-	name := string(line[0 : len(line)-1])
+	name := string(line)
 
 	class, err := u.findClass(module, name)
 	if err != nil {
@@ -1087,13 +1340,13 @@ func loadGlobal(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	module := string(line[0 : len(line)-1])
+	module := string(line)
 
 	line, err = u.readLine() // TODO: deode UTF-8?
 	if err != nil {
 		return err
 	}
-	name := string(line[0 : len(line)-1])
+	name := string(line)
 
 	class, err := u.findClass(module, name)
 	if err != nil {
@@ -1133,50 +1386,51 @@ func loadStackGlobal(u *Unpickler) error {
 
 // push object from extension registry; 1-byte index
 func opExt1(u *Unpickler) error {
-	if u.GetExtension == nil {
-		return fmt.Errorf("unsupported extension code encountered")
-	}
 	i, err := u.readOne()
 	if err != nil {
 		return err
 	}
-	obj, err := u.GetExtension(int(i))
-	if err != nil {
-		return err
-	}
-	u.append(obj)
-	return nil
+	return u.loadExtension(int(i))
 }
 
 // ditto, but 2-byte index
 func opExt2(u *Unpickler) error {
-	if u.GetExtension == nil {
-		return fmt.Errorf("unsupported extension code encountered")
-	}
 	buf, err := u.read(2)
 	if err != nil {
 		return err
 	}
-	code := int(binary.LittleEndian.Uint16(buf))
-	obj, err := u.GetExtension(code)
-	if err != nil {
-		return err
-	}
-	u.append(obj)
-	return nil
+	return u.loadExtension(int(binary.LittleEndian.Uint16(buf)))
 }
 
 // ditto, but 4-byte index
 func opExt4(u *Unpickler) error {
-	if u.GetExtension == nil {
-		return fmt.Errorf("unsupported extension code encountered")
-	}
 	buf, err := u.read(4)
 	if err != nil {
 		return err
 	}
-	code := int(binary.LittleEndian.Uint32(buf))
-	obj, err := u.GetExtension(code)
+	return u.loadExtension(int(int32(binary.LittleEndian.Uint32(buf))))
+}
+
+// loadExtension pushes the object registered with the given extension code,
+// resolved by GetExtension, if set, or else through Extensions.
+func (u *Unpickler) loadExtension(code int) error {
+	if code <= 0 {
+		return fmt.Errorf("EXT specifies code <= 0")
+	}
+	var obj interface{}
+	var err error
+	switch {
+	case u.GetExtension != nil:
+		obj, err = u.GetExtension(code)
+	case u.Extensions != nil:
+		module, name, ok := u.Extensions.Lookup(code)
+		if !ok {
+			return fmt.Errorf("unregistered extension code %d", code)
+		}
+		obj, err = u.findClass(module, name)
+	default:
+		return fmt.Errorf("unsupported extension code encountered")
+	}
 	if err != nil {
 		return err
 	}
@@ -1244,12 +1498,11 @@ func loadGet(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	i, err := strconv.Atoi(string(line[:len(line)-1]))
+	i, err := strconv.Atoi(string(line))
 	if err != nil {
 		return err
 	}
-	u.append(u.memo[i])
-	return nil
+	return u.appendMemo(i)
 }
 
 // push item from memo on stack; index is 1-byte arg
@@ -1258,8 +1511,7 @@ func loadBinGet(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	u.append(u.memo[int(i)])
-	return nil
+	return u.appendMemo(int(i))
 }
 
 // push item from memo on stack; index is 4-byte arg
@@ -1269,7 +1521,16 @@ func loadLongBinGet(u *Unpickler) error {
 		return err
 	}
 	i := int(binary.LittleEndian.Uint32(buf))
-	u.append(u.memo[i])
+	return u.appendMemo(i)
+}
+
+// push the memo value at index i on the stack
+func (u *Unpickler) appendMemo(i int) error {
+	value, ok := u.memo[i]
+	if !ok {
+		return fmt.Errorf("%w: index %d", ErrMemoMissing, i)
+	}
+	u.append(value)
 	return nil
 }
 
@@ -1279,7 +1540,7 @@ func loadPut(u *Unpickler) error {
 	if err != nil {
 		return err
 	}
-	i, err := strconv.Atoi(string(line[:len(line)-1]))
+	i, err := strconv.Atoi(string(line))
 	if err != nil {
 		return err
 	}
@@ -1379,6 +1640,9 @@ func loadSetItem(u *Unpickler) error {
 	if !dictOk {
 		return fmt.Errorf("SETITEM requires DictSetter")
 	}
+	if err := checkHashable(dict, key); err != nil {
+		return fmt.Errorf("SETITEM: %w", err)
+	}
 	dict.Set(key, value)
 	return nil
 }
@@ -1398,7 +1662,13 @@ func loadSetItems(u *Unpickler) error {
 		return fmt.Errorf("SETITEMS requires DictSetter")
 	}
 	itemsLen := len(items)
+	if itemsLen%2 != 0 {
+		return fmt.Errorf("SETITEMS requires an even number of items")
+	}
 	for i := 0; i < itemsLen; i += 2 {
+		if err := checkHashable(dict, items[i]); err != nil {
+			return fmt.Errorf("SETITEMS: %w", err)
+		}
 		dict.Set(items[i], items[i+1])
 	}
 	u.append(dict)
@@ -1420,6 +1690,9 @@ func loadAddItems(u *Unpickler) error {
 		return fmt.Errorf("ADDITEMS requires SetAdder")
 	}
 	for _, item := range items {
+		if err := checkHashable(set, item); err != nil {
+			return fmt.Errorf("ADDITEMS: %w", err)
+		}
 		set.Add(item)
 	}
 	u.append(set)
@@ -1451,7 +1724,7 @@ func loadBuild(u *Unpickler) error {
 		if !instPdsOk {
 			return fmt.Errorf("BUILD requires a PyDictSettable instance: %#v", inst)
 		}
-		for _, entry := range *stateDict {
+		for _, entry := range stateDict.Entries() {
 			err := instPds.PyDictSet(entry.Key, entry.Value)
 			if err != nil {
 				return err
@@ -1465,7 +1738,7 @@ func loadBuild(u *Unpickler) error {
 			return fmt.Errorf(
 				"BUILD requires a PyAttrSettable instance: %#v", inst)
 		}
-		for _, entry := range *slotStateDict {
+		for _, entry := range slotStateDict.Entries() {
 			sk, keyOk := entry.Key.(string)
 			if !keyOk {
 				return fmt.Errorf("BUILD requires string slot state keys")
@@ -1496,11 +1769,53 @@ func loadStop(u *Unpickler) error {
 	return pickleStop{value: value}
 }
 
-func decodeInt32(b []byte) int {
-	ux := binary.LittleEndian.Uint32(b)
-	x := int(ux)
-	if b[3]&0x80 != 0 {
-		x = -(int(^ux) + 1)
+// checkHashable returns an error if v can't be used as a key of the given
+// built-in dict or set type, which would make it panic.
+func checkHashable(container, v interface{}) error {
+	switch container.(type) {
+	case *types.OrderedDict:
+		if !isHashable(v) {
+			return fmt.Errorf("%w: %T", types.ErrUnhashable, v)
+		}
+	case *types.Dict, *types.Set:
+		if _, err := types.Hash(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isHashable reports whether v can be used as a key of a Go map without
+// causing a run-time panic.
+func isHashable(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	return isHashableValue(reflect.ValueOf(v))
+}
+
+func isHashableValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	case reflect.Interface:
+		return v.IsNil() || isHashableValue(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isHashableValue(v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isHashableValue(v.Field(i)) {
+				return false
+			}
+		}
 	}
-	return x
+	return true
+}
+
+func decodeInt32(b []byte) int {
+	return int(int32(binary.LittleEndian.Uint32(b)))
 }
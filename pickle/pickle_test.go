@@ -5,8 +5,8 @@
 package pickle
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"math/big"
 	"reflect"
 	"strings"
@@ -573,6 +573,27 @@ func TestP5GenericObject(t *testing.T) {
 	}
 }
 
+func TestP4GenericObjectWithState(t *testing.T) {
+	// class Foo(): pass
+	// foo = Foo(); foo.x = 1
+	// pickle.dumps(foo, protocol=4)
+	actual := loadsNoErr(t, "\x80\x04\x95!\x00\x00\x00\x00\x00\x00\x00"+
+		"\x8c\x08__main__\x94\x8c\x03Foo\x94\x93\x94)\x81\x94}\x94"+
+		"\x8c\x01x\x94K\x01sb.")
+	switch v := actual.(type) {
+	case *types.GenericObject:
+		state, ok := v.State.(*types.Dict)
+		if !ok {
+			t.Fatalf("expected Dict state, actual: %#v", v.State)
+		}
+		if x, ok := state.Get("x"); state.Len() != 1 || !ok || x != 1 {
+			t.Error("expected {'x': 1} state, actual:", v.State)
+		}
+	default:
+		t.Error("expected GenericObject, actual:", actual)
+	}
+}
+
 func TestP4EmptyOrderedDict(t *testing.T) {
 	// pickle.dumps(collections.OrderedDict(), protocol=4)
 	actual := loadsNoErr(t, "\x80\x04\x95\"\x00\x00\x00\x00\x00\x00\x00"+
@@ -757,25 +778,31 @@ func TestP4Carray(t *testing.T) {
 
 func TestIssue16(t *testing.T) {
 	var (
+		// DICT with an odd number of items
 		pkl  = "\x28\x88\x88\x88\x88\x88\x88\x88\x64"
-		want = io.EOF
+		want = "DICT requires an even number of items"
 	)
 	_, err := Loads(pkl)
 	if err == nil {
 		t.Fatalf("expected an error")
 	}
-	if got, want := err.Error(), want.Error(); got != want {
+	var ue *UnpicklingError
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected *UnpicklingError, got %T: %v", err, err)
+	}
+	if got := ue.Err.Error(); got != want {
 		t.Fatalf("invalid error:\ngot= %q\nwant=%q", got, want)
 	}
+	if ue.OpName != "DICT" || ue.Offset != 8 {
+		t.Errorf("expected DICT at offset 8, got %q at offset %d", ue.OpName, ue.Offset)
+	}
 }
 
-// TODO: test BinPersId
 // TODO: test Get
 // TODO: test BinGet
 // TODO: test LongBinPut
 // TODO: test LongBinGet
 // TODO: test Build
-// TODO: test PersId
 // TODO: test Pop
 // TODO: test PopMark
 // TODO: test Dup
@@ -784,11 +811,6 @@ func TestIssue16(t *testing.T) {
 // TODO: test Long4
 // TODO: test BinUnicode8
 // TODO: test BinBytes8
-// TODO: test Ext1
-// TODO: test Ext2
-// TODO: test Ext4
-// TODO: test NextBuffer
-// TODO: test ReadOnlyBuffer
 // TODO: test NewObjEx
 
 func loadsNoErrEqual(t *testing.T, s string, expected interface{}) {
@@ -805,3 +827,30 @@ func loadsNoErr(t *testing.T, s string) interface{} {
 	}
 	return result
 }
+
+func TestUnhashableKeys(t *testing.T) {
+	for _, pkl := range []string{
+		"}]K\x01s.",           // dict with a list key
+		"}(]K\x01u.",          // dict with a list key, SETITEMS
+		"(]]d.",               // dict with a list key, DICT
+		"\x80\x04\x8f(}\x90.", // set with a dict item
+		"(K\x01]\x86\x91.",    // frozenset with an unhashable tuple
+	} {
+		_, err := Loads(pkl)
+		if !errors.Is(err, types.ErrUnhashable) {
+			t.Errorf("%q: expected ErrUnhashable, got %v", pkl, err)
+		}
+	}
+}
+
+func TestDictKeysPythonEquality(t *testing.T) {
+	// {(1, 2): 'a'} with the key looked up as (1.0, True + 1)
+	actual := loadsNoErr(t, "}(K\x01K\x02\x86X\x01\x00\x00\x00au.")
+	d, ok := actual.(*types.Dict)
+	if !ok {
+		t.Fatalf("expected Dict, actual: %#v", actual)
+	}
+	if v, ok := d.Get(&types.Tuple{1.0, big.NewInt(2)}); !ok || v != "a" {
+		t.Errorf("expected 'a', actual: %#v", v)
+	}
+}
@@ -0,0 +1,817 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// DefaultProtocol is the protocol used by Dumps, the same as the default
+// protocol of Python 3.8 and later.
+const DefaultProtocol = 4
+
+// lowestPicklerProtocol is the lowest protocol supported by Pickler.
+const lowestPicklerProtocol = 2
+
+// Opcodes written by Pickler.
+const (
+	opcodeMark            byte = '('
+	opcodeStop            byte = '.'
+	opcodePop             byte = '0'
+	opcodePopMark         byte = '1'
+	opcodeBinint          byte = 'J'
+	opcodeBinint1         byte = 'K'
+	opcodeBinint2         byte = 'M'
+	opcodeNone            byte = 'N'
+	opcodeBinpersid       byte = 'Q'
+	opcodeReduce          byte = 'R'
+	opcodeBinunicode      byte = 'X'
+	opcodeAppend          byte = 'a'
+	opcodeBuild           byte = 'b'
+	opcodeGlobal          byte = 'c'
+	opcodeEmptyDict       byte = '}'
+	opcodeAppends         byte = 'e'
+	opcodeBinget          byte = 'h'
+	opcodeLongBinget      byte = 'j'
+	opcodeEmptyList       byte = ']'
+	opcodeBinput          byte = 'q'
+	opcodeLongBinput      byte = 'r'
+	opcodeSetitem         byte = 's'
+	opcodeTuple           byte = 't'
+	opcodeEmptyTuple      byte = ')'
+	opcodeSetitems        byte = 'u'
+	opcodeBinfloat        byte = 'G'
+	opcodeProto           byte = '\x80'
+	opcodeNewobj          byte = '\x81'
+	opcodeExt1            byte = '\x82'
+	opcodeExt2            byte = '\x83'
+	opcodeExt4            byte = '\x84'
+	opcodeTuple1          byte = '\x85'
+	opcodeTuple2          byte = '\x86'
+	opcodeTuple3          byte = '\x87'
+	opcodeNewtrue         byte = '\x88'
+	opcodeNewfalse        byte = '\x89'
+	opcodeLong1           byte = '\x8a'
+	opcodeLong4           byte = '\x8b'
+	opcodeBinbytes        byte = 'B'
+	opcodeShortBinbytes   byte = 'C'
+	opcodeShortBinunicode byte = '\x8c'
+	opcodeBinunicode8     byte = '\x8d'
+	opcodeBinbytes8       byte = '\x8e'
+	opcodeEmptySet        byte = '\x8f'
+	opcodeAdditems        byte = '\x90'
+	opcodeFrozenset       byte = '\x91'
+	opcodeStackGlobal     byte = '\x93'
+	opcodeMemoize         byte = '\x94'
+	opcodeFrame           byte = '\x95'
+	opcodeBytearray8      byte = '\x96'
+	opcodeNextBuffer      byte = '\x97'
+	opcodeReadonlyBuffer  byte = '\x98'
+)
+
+// frameSizeTarget and frameSizeMin are the target size of the frames
+// written for protocol 4 and later, and the size below which data is not
+// framed at all, as in Python.
+const (
+	frameSizeTarget = 64 * 1024
+	frameSizeMin    = 4
+)
+
+// batchSize is the maximum amount of items appended to a list, or set into
+// a dictionary, by a single APPENDS or SETITEMS opcode.
+const batchSize = 1000
+
+// Dumps returns the pickle representation of v, with DefaultProtocol.
+func Dumps(v interface{}) ([]byte, error) {
+	var b bytesWriter
+	p := NewPickler(&b, DefaultProtocol)
+	if err := p.Dump(v); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type bytesWriter []byte
+
+func (b *bytesWriter) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+// Pickler writes the pickle representation of values, like Python
+// "pickle.Pickler". It supports protocols 2 to HighestProtocol.
+//
+// The values which can be pickled are the ones loaded by an Unpickler:
+// nil, booleans, integers (including *big.Int), floats, strings, []byte
+// (as bytes), and the containers and classes of package types: Tuple,
+// List, Dict, OrderedDict, Set, FrozenSet, ByteArray and PickleBuffer
+// (protocol 5 only), GenericClass and GenericObject.
+// As in Python, with the protocols lacking the opcodes for them, bytes are
+// reduced to a call of "_codecs.encode" (protocol 2), and sets and
+// frozensets to a call of their class with a list of the items (protocols
+// 2 and 3).
+// Containers are memoized by identity, so that shared and self-referencing
+// values are preserved.
+type Pickler struct {
+	w     io.Writer
+	proto int
+	buf   []byte
+	// frameStart is the position in buf of the header of the current
+	// frame, or -1 if no frame is open.
+	frameStart int
+	memo       map[interface{}]int
+	// Extensions, if not nil, makes the Pickler write the registered
+	// globals (such as classes) with the EXT1, EXT2 and EXT4 opcodes.
+	Extensions *ExtensionRegistry
+	// BufferCallback, like the "buffer_callback" argument of Python
+	// "pickle.Pickler", is called with each *types.PickleBuffer to be
+	// pickled: if it returns false, the buffer is written out-of-band,
+	// and must be supplied to the Unpickler (see WithBuffers); if it
+	// returns true, or if BufferCallback is nil, the buffer data is written
+	// into the pickle, as bytes or bytearray.
+	BufferCallback func(buf *types.PickleBuffer) (inBand bool, err error)
+	// PersistentID, like Python "Pickler.persistent_id", is called with
+	// each value to be pickled: if it returns a non-nil ID, the value is
+	// written as a reference to that persistent ID, to be resolved by
+	// Unpickler.PersistentLoad or Unpickler.PersistentResolver.
+	PersistentID func(v interface{}) (interface{}, error)
+}
+
+// NewPickler returns a Pickler writing to w with the given protocol.
+func NewPickler(w io.Writer, proto int) Pickler {
+	return Pickler{
+		w:          w,
+		proto:      proto,
+		frameStart: -1,
+		memo:       make(map[interface{}]int),
+	}
+}
+
+// Dump writes the pickle representation of v.
+//
+// The memo is not cleared between calls: values already written by a
+// previous call are written as references to the memo, as in Python.
+func (p *Pickler) Dump(v interface{}) error {
+	if p.proto < lowestPicklerProtocol || p.proto > int(HighestProtocol) {
+		return fmt.Errorf("unsupported pickle protocol %d", p.proto)
+	}
+	if p.memo == nil {
+		p.memo = make(map[interface{}]int)
+	}
+	p.buf = append(p.buf[:0], opcodeProto, byte(p.proto))
+	p.frameStart = -1
+	if p.proto >= 4 {
+		p.startFrame()
+	}
+	if err := p.save(v); err != nil {
+		p.buf = p.buf[:0]
+		return err
+	}
+	p.buf = append(p.buf, opcodeStop)
+	return p.flush()
+}
+
+func (p *Pickler) startFrame() {
+	p.frameStart = len(p.buf)
+	p.buf = append(p.buf, opcodeFrame, 0, 0, 0, 0, 0, 0, 0, 0)
+}
+
+// endOpcode is called after each opcode has been written, committing the
+// current frame, or flushing the buffer, when it's large enough.
+func (p *Pickler) endOpcode() error {
+	if p.frameStart < 0 {
+		if len(p.buf) >= frameSizeTarget {
+			return p.flush()
+		}
+		return nil
+	}
+	if len(p.buf)-p.frameStart-9 >= frameSizeTarget {
+		if err := p.flush(); err != nil {
+			return err
+		}
+		p.startFrame()
+	}
+	return nil
+}
+
+// flush commits the current frame, if any, and writes the buffer.
+func (p *Pickler) flush() error {
+	if p.frameStart >= 0 {
+		n := len(p.buf) - p.frameStart - 9
+		if n < frameSizeMin {
+			// don't frame small amounts of data
+			p.buf = append(p.buf[:p.frameStart], p.buf[p.frameStart+9:]...)
+		} else {
+			binary.LittleEndian.PutUint64(p.buf[p.frameStart+1:], uint64(n))
+		}
+		p.frameStart = -1
+	}
+	_, err := p.w.Write(p.buf)
+	p.buf = p.buf[:0]
+	return err
+}
+
+func (p *Pickler) write(b ...byte) {
+	p.buf = append(p.buf, b...)
+}
+
+func (p *Pickler) writeUint32(op byte, n int) {
+	p.buf = append(p.buf, op, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(p.buf[len(p.buf)-4:], uint32(n))
+}
+
+func (p *Pickler) writeUint64(op byte, n int) {
+	p.buf = append(p.buf, op, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(p.buf[len(p.buf)-8:], uint64(n))
+}
+
+func (p *Pickler) save(v interface{}) error {
+	if err := p.saveValue(v); err != nil {
+		return err
+	}
+	return p.endOpcode()
+}
+
+func (p *Pickler) saveValue(v interface{}) error {
+	if p.PersistentID != nil {
+		pid, err := p.PersistentID(v)
+		if err != nil {
+			return err
+		}
+		if pid != nil {
+			// as in Python, the ID itself is not checked for persistence,
+			// but its items are
+			if err := p.saveObject(pid); err != nil {
+				return err
+			}
+			p.write(opcodeBinpersid)
+			return nil
+		}
+	}
+	return p.saveObject(v)
+}
+
+func (p *Pickler) saveObject(v interface{}) error {
+	if isMemoizable(v) {
+		if i, ok := p.memo[v]; ok {
+			p.writeGet(i)
+			return nil
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		p.write(opcodeNone)
+	case bool:
+		if v {
+			p.write(opcodeNewtrue)
+		} else {
+			p.write(opcodeNewfalse)
+		}
+	case int:
+		p.saveInt(int64(v))
+	case int64:
+		p.saveInt(v)
+	case *big.Int:
+		p.saveBigInt(v)
+	case float64:
+		p.saveFloat(v)
+	case float32:
+		p.saveFloat(float64(v))
+	case string:
+		if err := p.saveString(v); err != nil {
+			return err
+		}
+		p.memoize(v)
+	case []byte:
+		return p.saveBytes(v)
+	case *types.ByteArray:
+		return p.saveByteArray(v)
+	case *types.PickleBuffer:
+		return p.savePickleBuffer(v)
+	case *types.Tuple:
+		return p.saveTuple(v)
+	case *types.List:
+		return p.saveList(v)
+	case *types.Dict:
+		return p.saveDict(v)
+	case *types.OrderedDict:
+		return p.saveOrderedDict(v)
+	case *types.Set:
+		return p.saveSet(v)
+	case *types.FrozenSet:
+		return p.saveFrozenSet(v)
+	case *types.GenericClass:
+		return p.saveGlobal(v, v.Module, v.Name)
+	case *types.GenericObject:
+		return p.saveGenericObject(v)
+	default:
+		return p.saveOther(v)
+	}
+	return nil
+}
+
+// saveOther saves values of other integer and float types.
+func (p *Pickler) saveOther(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.saveInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			p.saveBigInt(new(big.Int).SetUint64(u))
+		} else {
+			p.saveInt(int64(u))
+		}
+	default:
+		return fmt.Errorf("cannot pickle value of type %T", v)
+	}
+	return nil
+}
+
+// isMemoizable reports whether v is looked up in the memo. Strings are
+// memoized by value, and the other values by identity.
+func isMemoizable(v interface{}) bool {
+	switch v.(type) {
+	case string, *types.Tuple, *types.List, *types.Dict, *types.OrderedDict,
+		*types.Set, *types.FrozenSet, *types.ByteArray, *types.PickleBuffer,
+		*types.GenericClass, *types.GenericObject:
+		return true
+	}
+	return false
+}
+
+func (p *Pickler) memoize(v interface{}) {
+	i := len(p.memo)
+	p.memo[v] = i
+	switch {
+	case p.proto >= 4:
+		p.write(opcodeMemoize)
+	case i < 256:
+		p.write(opcodeBinput, byte(i))
+	default:
+		p.writeUint32(opcodeLongBinput, i)
+	}
+}
+
+func (p *Pickler) writeGet(i int) {
+	if i < 256 {
+		p.write(opcodeBinget, byte(i))
+	} else {
+		p.writeUint32(opcodeLongBinget, i)
+	}
+}
+
+func (p *Pickler) saveInt(i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxUint8:
+		p.write(opcodeBinint1, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		p.write(opcodeBinint2, byte(i), byte(i>>8))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		p.writeUint32(opcodeBinint, int(int32(i)))
+	default:
+		p.saveBigInt(big.NewInt(i))
+	}
+}
+
+func (p *Pickler) saveBigInt(b *big.Int) {
+	if b.IsInt64() {
+		if i := b.Int64(); i >= math.MinInt32 && i <= math.MaxInt32 {
+			p.saveInt(i)
+			return
+		}
+	}
+	data := encodeLong(b)
+	if len(data) < 256 {
+		p.write(opcodeLong1, byte(len(data)))
+	} else {
+		p.writeUint32(opcodeLong4, len(data))
+	}
+	p.write(data...)
+}
+
+// encodeLong encodes b as a little-endian two's complement integer, with
+// the minimum number of bytes, as Python "pickle.encode_long".
+func encodeLong(b *big.Int) []byte {
+	if b.Sign() == 0 {
+		return nil
+	}
+	n := b.BitLen()/8 + 1
+	x := b
+	if b.Sign() < 0 {
+		// two's complement: 2**(8n) + b
+		x = new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+		x.Add(x, b)
+	}
+	be := x.FillBytes(make([]byte, n))
+	le := make([]byte, n)
+	for i, c := range be {
+		le[n-1-i] = c
+	}
+	// drop a redundant sign byte, as Python does
+	if b.Sign() < 0 && n > 1 && le[n-1] == 0xff && le[n-2]&0x80 != 0 {
+		le = le[:n-1]
+	}
+	return le
+}
+
+func (p *Pickler) saveFloat(f float64) {
+	p.buf = append(p.buf, opcodeBinfloat, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(p.buf[len(p.buf)-8:], math.Float64bits(f))
+}
+
+func (p *Pickler) saveString(s string) error {
+	if err := p.checkSize32("string", len(s)); err != nil {
+		return err
+	}
+	switch {
+	case len(s) < 256 && p.proto >= 4:
+		p.write(opcodeShortBinunicode, byte(len(s)))
+	case uint64(len(s)) > math.MaxUint32 && p.proto >= 4:
+		p.writeUint64(opcodeBinunicode8, len(s))
+	default:
+		p.writeUint32(opcodeBinunicode, len(s))
+	}
+	p.buf = append(p.buf, s...)
+	return nil
+}
+
+// checkSize32 returns an error if an object of n bytes cannot be written
+// with the protocol, which before 4 has only opcodes with 32-bit sizes.
+func (p *Pickler) checkSize32(what string, n int) error {
+	if p.proto < 4 && uint64(n) > math.MaxUint32 {
+		return fmt.Errorf("cannot pickle a %s larger than 4 GiB with protocol %d", what, p.proto)
+	}
+	return nil
+}
+
+func (p *Pickler) saveBytes(b []byte) error {
+	if p.proto < 3 {
+		// as in Python, protocol 2 has no opcode for bytes: they are
+		// reduced to a call of bytes() or of codecs.encode(), with the
+		// bytes decoded as Latin-1
+		if len(b) == 0 {
+			return p.saveReduce(nil, "__builtin__", "bytes")
+		}
+		s := make([]rune, len(b))
+		for i, c := range b {
+			s[i] = rune(c)
+		}
+		return p.saveReduce(nil, "_codecs", "encode", string(s), "latin1")
+	}
+	if err := p.checkSize32("bytes object", len(b)); err != nil {
+		return err
+	}
+	p.writeBytes(b)
+	p.memoize(memoPlaceholder{len(p.memo)})
+	return nil
+}
+
+func (p *Pickler) writeBytes(b []byte) {
+	switch {
+	case len(b) < 256:
+		p.write(opcodeShortBinbytes, byte(len(b)))
+	case uint64(len(b)) > math.MaxUint32 && p.proto >= 4:
+		p.writeUint64(opcodeBinbytes8, len(b))
+	default:
+		p.writeUint32(opcodeBinbytes, len(b))
+	}
+	p.write(b...)
+}
+
+func (p *Pickler) saveByteArray(b *types.ByteArray) error {
+	if p.proto < 5 {
+		return fmt.Errorf("cannot pickle bytearray with protocol %d", p.proto)
+	}
+	p.writeByteArray(*b)
+	p.memoize(b)
+	return nil
+}
+
+func (p *Pickler) writeByteArray(b []byte) {
+	p.writeUint64(opcodeBytearray8, len(b))
+	p.write(b...)
+}
+
+func (p *Pickler) savePickleBuffer(b *types.PickleBuffer) error {
+	if p.proto < 5 {
+		return fmt.Errorf("cannot pickle PickleBuffer with protocol %d", p.proto)
+	}
+	inBand := true
+	if p.BufferCallback != nil {
+		var err error
+		if inBand, err = p.BufferCallback(b); err != nil {
+			return err
+		}
+	}
+	if !inBand {
+		p.write(opcodeNextBuffer)
+		if b.ReadOnly {
+			p.write(opcodeReadonlyBuffer)
+		}
+		return nil
+	}
+	if b.ReadOnly {
+		p.writeBytes(b.Data)
+	} else {
+		p.writeByteArray(b.Data)
+	}
+	p.memoize(b)
+	return nil
+}
+
+func (p *Pickler) saveTuple(t *types.Tuple) error {
+	n := t.Len()
+	if n == 0 {
+		p.write(opcodeEmptyTuple)
+		return nil
+	}
+	if n > 3 {
+		p.write(opcodeMark)
+	}
+	for _, item := range *t {
+		if err := p.save(item); err != nil {
+			return err
+		}
+	}
+	if i, ok := p.memo[t]; ok {
+		// the tuple contains itself, through a mutable container, and it
+		// has already been saved: discard the items and get it from memo
+		if n > 3 {
+			p.write(opcodePopMark)
+		} else {
+			for j := 0; j < n; j++ {
+				p.write(opcodePop)
+			}
+		}
+		p.writeGet(i)
+		return nil
+	}
+	switch n {
+	case 1:
+		p.write(opcodeTuple1)
+	case 2:
+		p.write(opcodeTuple2)
+	case 3:
+		p.write(opcodeTuple3)
+	default:
+		p.write(opcodeTuple)
+	}
+	p.memoize(t)
+	return nil
+}
+
+func (p *Pickler) saveList(l *types.List) error {
+	p.write(opcodeEmptyList)
+	p.memoize(l)
+	return p.batchAppends(*l, opcodeAppend, opcodeAppends)
+}
+
+// batchAppends saves items in batches, with the given single-item and
+// multiple-item opcodes.
+func (p *Pickler) batchAppends(items []interface{}, single, multiple byte) error {
+	for len(items) > 0 {
+		n := len(items)
+		if n > batchSize {
+			n = batchSize
+		}
+		if n > 1 {
+			p.write(opcodeMark)
+		}
+		for _, item := range items[:n] {
+			if err := p.save(item); err != nil {
+				return err
+			}
+		}
+		if n > 1 {
+			p.write(multiple)
+		} else {
+			p.write(single)
+		}
+		items = items[n:]
+	}
+	return nil
+}
+
+func (p *Pickler) saveDict(d *types.Dict) error {
+	p.write(opcodeEmptyDict)
+	p.memoize(d)
+	return p.batchSetItems(d.Entries())
+}
+
+func (p *Pickler) batchSetItems(entries []types.DictEntry) error {
+	for len(entries) > 0 {
+		n := len(entries)
+		if n > batchSize {
+			n = batchSize
+		}
+		if n > 1 {
+			p.write(opcodeMark)
+		}
+		for _, e := range entries[:n] {
+			if err := p.save(e.Key); err != nil {
+				return err
+			}
+			if err := p.save(e.Value); err != nil {
+				return err
+			}
+		}
+		if n > 1 {
+			p.write(opcodeSetitems)
+		} else {
+			p.write(opcodeSetitem)
+		}
+		entries = entries[n:]
+	}
+	return nil
+}
+
+// saveOrderedDict saves an OrderedDict as Python does, by calling the
+// class without arguments, then setting the items, and finally the
+// attributes, if any.
+func (p *Pickler) saveOrderedDict(od *types.OrderedDict) error {
+	if err := p.saveGlobal(nil, "collections", "OrderedDict"); err != nil {
+		return err
+	}
+	p.write(opcodeEmptyTuple, opcodeReduce)
+	p.memoize(od)
+	entries := make([]types.DictEntry, 0, od.Len())
+	for e := od.List.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*types.OrderedDictEntry)
+		entries = append(entries, types.DictEntry{Key: entry.Key, Value: entry.Value})
+	}
+	if err := p.batchSetItems(entries); err != nil {
+		return err
+	}
+	if len(od.PyDict) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(od.PyDict))
+	for name := range od.PyDict {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	state := types.NewDict()
+	for _, name := range names {
+		state.Set(name, od.PyDict[name])
+	}
+	if err := p.save(state); err != nil {
+		return err
+	}
+	p.write(opcodeBuild)
+	return nil
+}
+
+func (p *Pickler) saveSet(s *types.Set) error {
+	if p.proto < 4 {
+		// as in Python, sets are reduced to a call of set() with a list
+		return p.saveReduce(s, p.builtinsModule(), "set", types.NewListFromSlice(s.Items()))
+	}
+	p.write(opcodeEmptySet)
+	p.memoize(s)
+	items := s.Items()
+	for len(items) > 0 {
+		n := len(items)
+		if n > batchSize {
+			n = batchSize
+		}
+		p.write(opcodeMark)
+		for _, item := range items[:n] {
+			if err := p.save(item); err != nil {
+				return err
+			}
+		}
+		p.write(opcodeAdditems)
+		items = items[n:]
+	}
+	return nil
+}
+
+func (p *Pickler) saveFrozenSet(fs *types.FrozenSet) error {
+	if p.proto < 4 {
+		return p.saveReduce(fs, p.builtinsModule(), "frozenset", types.NewListFromSlice(fs.Items()))
+	}
+	p.write(opcodeMark)
+	for _, item := range fs.Items() {
+		if err := p.save(item); err != nil {
+			return err
+		}
+	}
+	if i, ok := p.memo[fs]; ok {
+		p.write(opcodePopMark)
+		p.writeGet(i)
+		return nil
+	}
+	p.write(opcodeFrozenset)
+	p.memoize(fs)
+	return nil
+}
+
+// saveGlobal saves a reference to the global module.name. The value v, if
+// not nil, is the memo key of the global; otherwise, the global is memoized
+// by module and name.
+func (p *Pickler) saveGlobal(v interface{}, module, name string) error {
+	if v == nil {
+		v = extensionKey{module: module, name: name}
+		if i, ok := p.memo[v]; ok {
+			p.writeGet(i)
+			return nil
+		}
+	}
+	if p.Extensions != nil {
+		if code, ok := p.Extensions.Code(module, name); ok {
+			switch {
+			case code <= 0xff:
+				p.write(opcodeExt1, byte(code))
+			case code <= 0xffff:
+				p.write(opcodeExt2, byte(code), byte(code>>8))
+			default:
+				p.writeUint32(opcodeExt4, code)
+			}
+			return nil
+		}
+	}
+	if p.proto >= 4 {
+		if err := p.saveValue(module); err != nil {
+			return err
+		}
+		if err := p.saveValue(name); err != nil {
+			return err
+		}
+		p.write(opcodeStackGlobal)
+	} else {
+		p.write(opcodeGlobal)
+		p.buf = append(p.buf, module...)
+		p.buf = append(p.buf, '\n')
+		p.buf = append(p.buf, name...)
+		p.buf = append(p.buf, '\n')
+	}
+	p.memoize(v)
+	return nil
+}
+
+// saveReduce saves v as the result of calling the global module.name with
+// args, like the "save_reduce" method of Python "pickle.Pickler". The value
+// v is the memo key of the result.
+func (p *Pickler) saveReduce(v interface{}, module, name string, args ...interface{}) error {
+	if err := p.saveGlobal(nil, module, name); err != nil {
+		return err
+	}
+	if err := p.save(types.NewTupleFromSlice(args)); err != nil {
+		return err
+	}
+	p.write(opcodeReduce)
+	if v == nil {
+		v = memoPlaceholder{len(p.memo)}
+	}
+	p.memoize(v)
+	return nil
+}
+
+// builtinsModule returns the name of the module of the Python builtins,
+// which is "__builtin__" in Python 2, and thus with protocol 2, like with
+// the "fix_imports" argument of Python "pickle.Pickler".
+func (p *Pickler) builtinsModule() string {
+	if p.proto < 3 {
+		return "__builtin__"
+	}
+	return "builtins"
+}
+
+// memoPlaceholder is the memo key of values which are memoized, but never
+// looked up, such as []byte values, which are not comparable.
+type memoPlaceholder struct{ i int }
+
+// saveGenericObject saves an object as Python does for classes without
+// custom reduction: the class and the constructor arguments with NEWOBJ,
+// followed by the state, if any, with BUILD.
+func (p *Pickler) saveGenericObject(obj *types.GenericObject) error {
+	if err := p.save(obj.Class); err != nil {
+		return err
+	}
+	if err := p.save(types.NewTupleFromSlice(obj.ConstructorArgs)); err != nil {
+		return err
+	}
+	p.write(opcodeNewobj)
+	p.memoize(obj)
+	if obj.State == nil {
+		return nil
+	}
+	if err := p.save(obj.State); err != nil {
+		return err
+	}
+	p.write(opcodeBuild)
+	return nil
+}
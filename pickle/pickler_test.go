@@ -0,0 +1,239 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// The expected pickles of these tests are written by Python 3.11.
+
+func TestPicklerMatchesPython(t *testing.T) {
+	makeTuple := func(items ...interface{}) *types.Tuple {
+		return types.NewTupleFromSlice(items)
+	}
+	dict := types.NewDict()
+	dict.Set("a", &types.List{1, 2})
+	value := makeTuple(nil, true, false, 1, -1, 1000, int64(1)<<40, 1.5,
+		"abc", makeTuple(), makeTuple(1), makeTuple(1, 2), makeTuple(1, 2, 3),
+		makeTuple(1, 2, 3, 4), types.NewList(), types.NewDict(), dict)
+
+	testCases := []struct {
+		proto    int
+		expected string
+	}{
+		{2, "\x80\x02(N\x88\x89K\x01J\xff\xff\xff\xffM\xe8\x03\x8a\x06\x00\x00\x00\x00\x00\x01G?\xf8\x00\x00\x00\x00\x00\x00X\x03\x00\x00\x00abcq\x00)K\x01\x85q\x01K\x01K\x02\x86q\x02K\x01K\x02K\x03\x87q\x03(K\x01K\x02K\x03K\x04tq\x04]q\x05}q\x06}q\x07X\x01\x00\x00\x00aq\x08]q\t(K\x01K\x02estq\n."},
+		{3, "\x80\x03(N\x88\x89K\x01J\xff\xff\xff\xffM\xe8\x03\x8a\x06\x00\x00\x00\x00\x00\x01G?\xf8\x00\x00\x00\x00\x00\x00X\x03\x00\x00\x00abcq\x00)K\x01\x85q\x01K\x01K\x02\x86q\x02K\x01K\x02K\x03\x87q\x03(K\x01K\x02K\x03K\x04tq\x04]q\x05}q\x06}q\x07X\x01\x00\x00\x00aq\x08]q\t(K\x01K\x02estq\n."},
+		{4, "\x80\x04\x95Y\x00\x00\x00\x00\x00\x00\x00(N\x88\x89K\x01J\xff\xff\xff\xffM\xe8\x03\x8a\x06\x00\x00\x00\x00\x00\x01G?\xf8\x00\x00\x00\x00\x00\x00\x8c\x03abc\x94)K\x01\x85\x94K\x01K\x02\x86\x94K\x01K\x02K\x03\x87\x94(K\x01K\x02K\x03K\x04t\x94]\x94}\x94}\x94\x8c\x01a\x94]\x94(K\x01K\x02est\x94."},
+		{5, "\x80\x05\x95Y\x00\x00\x00\x00\x00\x00\x00(N\x88\x89K\x01J\xff\xff\xff\xffM\xe8\x03\x8a\x06\x00\x00\x00\x00\x00\x01G?\xf8\x00\x00\x00\x00\x00\x00\x8c\x03abc\x94)K\x01\x85\x94K\x01K\x02\x86\x94K\x01K\x02K\x03\x87\x94(K\x01K\x02K\x03K\x04t\x94]\x94}\x94}\x94\x8c\x01a\x94]\x94(K\x01K\x02est\x94."},
+	}
+	for _, tc := range testCases {
+		actual := dumpNoErr(t, value, tc.proto, nil)
+		if string(actual) != tc.expected {
+			t.Errorf("protocol %d:\nexpected %q\nactual   %q", tc.proto, tc.expected, actual)
+		}
+	}
+}
+
+func TestPicklerMatchesPythonOtherTypes(t *testing.T) {
+	od := types.NewOrderedDict()
+	od.Set("a", 1)
+	od.PyDictSet("x", 2)
+	state := types.NewDict()
+	state.Set("a", 1)
+	obj := &types.GenericObject{
+		Class: types.NewGenericClass("argparse", "Namespace"),
+		State: state,
+	}
+	ba := types.ByteArray("z")
+	extensions := NewExtensionRegistry()
+	if err := extensions.Add("argparse", "Namespace", 70000); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		value      interface{}
+		proto      int
+		extensions *ExtensionRegistry
+		expected   string
+	}{
+		{"bytes, sets and bytearray",
+			types.NewTupleFromSlice([]interface{}{
+				[]byte("xy"), types.NewSetFromSlice([]interface{}{1}),
+				types.NewFrozenSetFromSlice([]interface{}{2}), &ba,
+			}),
+			5, nil,
+			"\x80\x05\x95\x1f\x00\x00\x00\x00\x00\x00\x00(C\x02xy\x94\x8f\x94(K\x01\x90(K\x02\x91\x94\x96\x01\x00\x00\x00\x00\x00\x00\x00z\x94t\x94."},
+		{"OrderedDict with attributes", od, 4, nil,
+			"\x80\x04\x953\x00\x00\x00\x00\x00\x00\x00\x8c\x0bcollections\x94\x8c\x0bOrderedDict\x94\x93\x94)R\x94\x8c\x01a\x94K\x01s}\x94\x8c\x01x\x94K\x02sb."},
+		{"object with extension code", obj, 4, extensions,
+			"\x80\x04\x95\x13\x00\x00\x00\x00\x00\x00\x00\x84p\x11\x01\x00)\x81\x94}\x94\x8c\x01a\x94K\x01sb."},
+	}
+	for _, tc := range testCases {
+		actual := dumpNoErr(t, tc.value, tc.proto, tc.extensions)
+		if string(actual) != tc.expected {
+			t.Errorf("%s:\nexpected %q\nactual   %q", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestPicklerReductions(t *testing.T) {
+	testCases := []struct {
+		value    interface{}
+		proto    int
+		expected string
+	}{
+		{[]byte("ab\xff"), 2, "\x80\x02c_codecs\nencode\nq\x00X\x04\x00\x00\x00ab\xc3\xbfq\x01X\x06\x00\x00\x00latin1q\x02\x86q\x03Rq\x04."},
+		{[]byte{}, 2, "\x80\x02c__builtin__\nbytes\nq\x00)Rq\x01."},
+		{types.NewSetFromSlice([]interface{}{1, 2}), 2, "\x80\x02c__builtin__\nset\nq\x00]q\x01(K\x01K\x02e\x85q\x02Rq\x03."},
+		{types.NewFrozenSetFromSlice([]interface{}{3}), 2, "\x80\x02c__builtin__\nfrozenset\nq\x00]q\x01K\x03a\x85q\x02Rq\x03."},
+		{types.NewSetFromSlice([]interface{}{1, 2}), 3, "\x80\x03cbuiltins\nset\nq\x00]q\x01(K\x01K\x02e\x85q\x02Rq\x03."},
+		{types.NewFrozenSetFromSlice([]interface{}{3}), 3, "\x80\x03cbuiltins\nfrozenset\nq\x00]q\x01K\x03a\x85q\x02Rq\x03."},
+	}
+	for _, tc := range testCases {
+		actual := dumpNoErr(t, tc.value, tc.proto, nil)
+		if string(actual) != tc.expected {
+			t.Errorf("%T with protocol %d:\nexpected %q\nactual   %q", tc.value, tc.proto, tc.expected, actual)
+		}
+		loaded, err := Loads(string(actual))
+		if err != nil {
+			t.Errorf("%T with protocol %d: %v", tc.value, tc.proto, err)
+			continue
+		}
+		if !types.Equal(loaded, tc.value) {
+			t.Errorf("%T with protocol %d: loaded %#v", tc.value, tc.proto, loaded)
+		}
+	}
+}
+
+func TestPicklerBigInts(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"127", "\x80\x04K\x7f."},
+		{"-129", "\x80\x04\x95\x06\x00\x00\x00\x00\x00\x00\x00J\x7f\xff\xff\xff."},
+		{"2147483648", "\x80\x04\x95\x08\x00\x00\x00\x00\x00\x00\x00\x8a\x05\x00\x00\x00\x80\x00."},
+		{"-2147483649", "\x80\x04\x95\x08\x00\x00\x00\x00\x00\x00\x00\x8a\x05\xff\xff\xff\x7f\xff."},
+		{"-9223372036854775808", "\x80\x04\x95\x0b\x00\x00\x00\x00\x00\x00\x00\x8a\x08\x00\x00\x00\x00\x00\x00\x00\x80."},
+		{"18446744073709551615", "\x80\x04\x95\x0c\x00\x00\x00\x00\x00\x00\x00\x8a\t\xff\xff\xff\xff\xff\xff\xff\xff\x00."},
+	}
+	for _, tc := range testCases {
+		value, _ := new(big.Int).SetString(tc.value, 10)
+		actual := dumpNoErr(t, value, 4, nil)
+		if string(actual) != tc.expected {
+			t.Errorf("%s:\nexpected %q\nactual   %q", tc.value, tc.expected, actual)
+		}
+		loaded, err := Loads(string(actual))
+		if err != nil {
+			t.Errorf("%s: %v", tc.value, err)
+			continue
+		}
+		if !types.Equal(loaded, value) {
+			t.Errorf("%s: loaded %v", tc.value, loaded)
+		}
+	}
+}
+
+func TestPicklerRoundTripSharedAndRecursive(t *testing.T) {
+	shared := types.NewDict()
+	shared.Set("k", "v")
+	list := types.NewList()
+	tuple := types.NewTupleFromSlice([]interface{}{list, shared})
+	list.Append(tuple)
+	list.Append(shared)
+
+	for _, proto := range []int{2, 3, 4, 5} {
+		data := dumpNoErr(t, tuple, proto, nil)
+		loaded, err := Loads(string(data))
+		if err != nil {
+			t.Fatalf("protocol %d: %v", proto, err)
+		}
+		lt := loaded.(*types.Tuple)
+		ll := lt.Get(0).(*types.List)
+		if ll.Get(0) != lt {
+			t.Errorf("protocol %d: recursive tuple not preserved", proto)
+		}
+		if ll.Get(1) != lt.Get(1) {
+			t.Errorf("protocol %d: shared dict not preserved", proto)
+		}
+	}
+}
+
+func TestPicklerLargeList(t *testing.T) {
+	// more than one batch of APPENDS, and more than one frame
+	list := types.NewList()
+	for i := 0; i < 2*batchSize+1; i++ {
+		list.Append(string(bytes.Repeat([]byte{'a' + byte(i%26)}, 100)))
+	}
+	data, err := Dumps(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Loads(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !types.Equal(loaded, list) {
+		t.Errorf("expected %d items, actual %v", list.Len(), loaded)
+	}
+}
+
+func TestPicklerErrors(t *testing.T) {
+	testCases := []struct {
+		value interface{}
+		proto int
+	}{
+		{nil, 1},
+		{nil, 6},
+		{&types.ByteArray{}, 4},
+		{struct{}{}, 4},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		p := NewPickler(&buf, tc.proto)
+		if err := p.Dump(tc.value); err == nil {
+			t.Errorf("%T with protocol %d: expected error", tc.value, tc.proto)
+		}
+	}
+}
+
+func TestPicklerCheckSize32(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("sizes over 4 GiB need a 64-bit int")
+	}
+	var size uint64 = math.MaxUint32
+	for proto := lowestPicklerProtocol; proto <= int(HighestProtocol); proto++ {
+		p := NewPickler(nil, proto)
+		if err := p.checkSize32("string", int(size)); err != nil {
+			t.Errorf("protocol %d: unexpected error %v", proto, err)
+		}
+		err := p.checkSize32("string", int(size+1))
+		if proto < 4 && err == nil {
+			t.Errorf("protocol %d: expected error", proto)
+		}
+		if proto >= 4 && err != nil {
+			t.Errorf("protocol %d: unexpected error %v", proto, err)
+		}
+	}
+}
+
+func dumpNoErr(t *testing.T, v interface{}, proto int, extensions *ExtensionRegistry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	p := NewPickler(&buf, proto)
+	p.Extensions = extensions
+	if err := p.Dump(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
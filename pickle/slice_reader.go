@@ -0,0 +1,100 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bytes"
+	"io"
+)
+
+// sliceReader reads from an in-memory byte slice. Unlike bytes.Reader, it
+// can return sub-slices aliasing the underlying data, avoiding copies.
+//
+// The slices returned by Peek and next remain valid for as long as the
+// underlying data is.
+type sliceReader struct {
+	data []byte
+	off  int
+}
+
+var _ reader = &sliceReader{}
+
+// Len returns the number of unread bytes.
+func (r *sliceReader) Len() int {
+	return len(r.data) - r.off
+}
+
+func (r *sliceReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.data) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.off:])
+	r.off += n
+	return n, nil
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if r.off >= len(r.data) {
+		return 0, io.EOF
+	}
+	b := r.data[r.off]
+	r.off++
+	return b, nil
+}
+
+// next returns the next n bytes, aliasing the underlying data, with the
+// same error semantics of io.ReadFull.
+func (r *sliceReader) next(n int) ([]byte, error) {
+	if n > r.Len() {
+		b := r.data[r.off:]
+		r.off = len(r.data)
+		if len(b) == 0 {
+			return b, io.EOF
+		}
+		return b, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.off : r.off+n : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *sliceReader) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, io.ErrShortBuffer
+	}
+	if n > r.Len() {
+		if r.Len() == 0 {
+			return r.data[r.off:], io.EOF
+		}
+		return r.data[r.off:], io.ErrUnexpectedEOF
+	}
+	return r.data[r.off : r.off+n], nil
+}
+
+func (r *sliceReader) Discard(n int) (int, error) {
+	if n > r.Len() {
+		n = r.Len()
+		r.off = len(r.data)
+		return n, io.EOF
+	}
+	r.off += n
+	return n, nil
+}
+
+// nextLine returns the bytes up to the next newline, which is consumed but
+// not included. If no newline is found, it returns the remaining data,
+// along with io.EOF if there is none, or io.ErrUnexpectedEOF.
+func (r *sliceReader) nextLine() ([]byte, error) {
+	i := bytes.IndexByte(r.data[r.off:], '\n')
+	if i < 0 {
+		return r.next(r.Len() + 1)
+	}
+	line := r.data[r.off : r.off+i : r.off+i]
+	r.off += i + 1
+	return line, nil
+}
@@ -0,0 +1,82 @@
+#!/usr/bin/env python3
+
+# Copyright 2023 NLP Odyssey Authors. All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+# Generates the seed corpus for FuzzLoads, in the format expected by
+# "go test" (see https://go.dev/security/fuzz/).
+
+import array
+import collections
+import os
+import pickle
+import string
+
+
+class Foo:
+    def __init__(self):
+        self.a = 1
+        self.b = [2, 3]
+
+
+class Slotted:
+    __slots__ = ('x', 'y')
+
+    def __init__(self):
+        self.x = 'x'
+        self.y = None
+
+
+def values():
+    shared = [1, 2]
+    recursive = []
+    recursive.append(recursive)
+    return {
+        'none': None,
+        'bool': [True, False],
+        'ints': [0, 1, -1, 255, 256, 65535, 65536, -2**31, 2**31, 2**63, -2**63 - 1],
+        'big_int': 2**200 + 12345,
+        'floats': [0.0, -1.5, 1e300, float('inf')],
+        'str': 'Café',
+        'long_str': 'x' * 300,
+        'bytes': b'\x00\x01\xff',
+        'long_bytes': b'y' * 300,
+        'bytearray': bytearray(b'abc'),
+        'tuples': [(), (1,), (1, 2), (1, 2, 3), (1, 2, 3, 4)],
+        'list': [1, 'a', None],
+        'dict': {'a': 1, 2: 'b', (3, 4): [5]},
+        'set': {1, 2, 'three'},
+        'frozenset': frozenset([1, (2, 3)]),
+        'ordered_dict': collections.OrderedDict([('z', 1), ('a', 2)]),
+        'shared': [shared, shared],
+        'recursive': recursive,
+        'object': Foo(),
+        'slotted': Slotted(),
+        'array': array.array('i', [1, -2, 3]),
+    }
+
+
+def main():
+    out_dir = os.path.join('fuzz', 'FuzzLoads')
+    os.makedirs(out_dir, exist_ok=True)
+    for name, value in values().items():
+        for proto in range(0, pickle.HIGHEST_PROTOCOL + 1):
+            if name == 'slotted' and proto < 2:
+                continue
+            data = pickle.dumps(value, protocol=proto)
+            write_seed(os.path.join(out_dir, f'{name}_proto{proto}'), data)
+
+
+SAFE = set(string.ascii_letters + string.digits + ' ')
+
+
+def write_seed(filename, data):
+    literal = ''.join(chr(b) if chr(b) in SAFE else f'\\x{b:02x}' for b in data)
+    with open(filename, 'w') as f:
+        f.write('go test fuzz v1\n')
+        f.write(f'[]byte("{literal}")\n')
+
+
+if __name__ == '__main__':
+    main()
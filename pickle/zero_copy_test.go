@@ -0,0 +1,124 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pickle
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// pickle.dumps([b'ab', bytearray(b'cd')], protocol=5)
+const bytesListP5 = "\x80\x05\x95\x16\x00\x00\x00\x00\x00\x00\x00]\x94(C\x02ab" +
+	"\x94\x96\x02\x00\x00\x00\x00\x00\x00\x00cd\x94e."
+
+func TestNewUnpicklerBytes(t *testing.T) {
+	input := []byte(bytesListP5)
+	u := NewUnpicklerBytes(input)
+	result, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ba := assertBytesList(t, result, "ab", "cd")
+
+	// Values must not alias the input.
+	copy(input, bytes.Repeat([]byte{'z'}, len(input)))
+	if string(b) != "ab" || string(*ba) != "cd" {
+		t.Errorf("values must not alias the input, got %q and %q", b, *ba)
+	}
+}
+
+func TestNewUnpicklerBytesZeroCopy(t *testing.T) {
+	input := []byte(bytesListP5)
+	u := NewUnpicklerBytes(input)
+	u.ZeroCopy = true
+	result, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ba := assertBytesList(t, result, "ab", "cd")
+
+	input[bytes.Index(input, []byte("ab"))] = 'A'
+	input[bytes.Index(input, []byte("cd"))] = 'C'
+	if string(b) != "Ab" || string(*ba) != "Cd" {
+		t.Errorf("values must alias the input, got %q and %q", b, *ba)
+	}
+
+	// Appending to an aliasing slice must not overwrite the input.
+	_ = append(b, 'X')
+	if input[bytes.Index(input, []byte("Ab"))+2] == 'X' {
+		t.Error("appending to a value must not modify the input")
+	}
+}
+
+func TestNewUnpicklerBytesLargeBytes(t *testing.T) {
+	data := bytes.Repeat([]byte{'x'}, 70000)
+	// pickle.dumps(b'x'*70000, protocol=4)
+	input := append([]byte("\x80\x04Bp\x11\x01\x00"), data...)
+	input = append(input, '.')
+	u := NewUnpicklerBytes(input)
+	u.ZeroCopy = true
+	result, err := u.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := result.([]byte); !ok || !bytes.Equal(b, data) {
+		t.Fatalf("unexpected result %T", result)
+	}
+}
+
+func TestLoadMmap(t *testing.T) {
+	filename := path.Join(t.TempDir(), "test.pkl")
+	if err := os.WriteFile(filename, []byte(bytesListP5), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, closer, err := LoadMmap(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBytesList(t, result, "ab", "cd")
+	if err = closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMmapError(t *testing.T) {
+	filename := path.Join(t.TempDir(), "test.pkl")
+	if err := os.WriteFile(filename, []byte(bytesListP5[:10]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, closer, err := LoadMmap(filename)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if closer != nil {
+		t.Errorf("expected nil closer, got %#v", closer)
+	}
+
+	_, _, err = LoadMmap(path.Join(t.TempDir(), "missing.pkl"))
+	if !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func assertBytesList(t *testing.T, result interface{}, b, ba string) ([]byte, *types.ByteArray) {
+	t.Helper()
+	list, ok := result.(*types.List)
+	if !ok || list.Len() != 2 {
+		t.Fatalf("expected *List of length 2, got %#v", result)
+	}
+	actualB, ok := list.Get(0).([]byte)
+	if !ok || string(actualB) != b {
+		t.Fatalf("expected bytes %q, got %#v", b, list.Get(0))
+	}
+	actualBa, ok := list.Get(1).(*types.ByteArray)
+	if !ok || string(*actualBa) != ba {
+		t.Fatalf("expected bytearray %q, got %#v", ba, list.Get(1))
+	}
+	return actualB, actualBa
+}
@@ -0,0 +1,235 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// DType is a PyTorch data type (torch.dtype), such as "float32". DTypes are
+// pickled as globals of the torch module, named after their value (such as
+// torch.float32).
+type DType string
+
+const (
+	Float16      DType = "float16"
+	Float32      DType = "float32"
+	Float64      DType = "float64"
+	BFloat16     DType = "bfloat16"
+	Int8         DType = "int8"
+	Int16        DType = "int16"
+	Int32        DType = "int32"
+	Int64        DType = "int64"
+	UInt8        DType = "uint8"
+	Bool         DType = "bool"
+	Complex64    DType = "complex64"
+	Complex128   DType = "complex128"
+	QInt8        DType = "qint8"
+	QUInt8       DType = "quint8"
+	QInt32       DType = "qint32"
+	Float8E4M3FN DType = "float8_e4m3fn"
+	Float8E5M2   DType = "float8_e5m2"
+	UInt16       DType = "uint16"
+	UInt32       DType = "uint32"
+	UInt64       DType = "uint64"
+	Bits1x8      DType = "bits1x8"
+	Bits2x4      DType = "bits2x4"
+	Bits4x2      DType = "bits4x2"
+	Bits8        DType = "bits8"
+	Bits16       DType = "bits16"
+)
+
+// ErrUnsupportedDType is returned for data types without a storage type.
+var ErrUnsupportedDType = errors.New("unsupported dtype")
+
+type dtypeInfo struct {
+	elementSize  int
+	storageClass StorageClassInterface
+}
+
+var dtypes = map[DType]dtypeInfo{
+	Float16:      {2, &HalfStorageClass{}},
+	Float32:      {4, &FloatStorageClass{}},
+	Float64:      {8, &DoubleStorageClass{}},
+	BFloat16:     {2, &BFloat16StorageClass{}},
+	Int8:         {1, &CharStorageClass{}},
+	Int16:        {2, &ShortStorageClass{}},
+	Int32:        {4, &IntStorageClass{}},
+	Int64:        {8, &LongStorageClass{}},
+	UInt8:        {1, &ByteStorageClass{}},
+	Bool:         {1, &BoolStorageClass{}},
+	Complex64:    {8, &ComplexFloatStorageClass{}},
+	Complex128:   {16, &ComplexDoubleStorageClass{}},
+	QInt8:        {1, &QInt8StorageClass{}},
+	QUInt8:       {1, &QUInt8StorageClass{}},
+	QInt32:       {4, &QInt32StorageClass{}},
+	Float8E4M3FN: {1, &Float8E4M3FNStorageClass{}},
+	Float8E5M2:   {1, &Float8E5M2StorageClass{}},
+	UInt16:       {2, &UInt16StorageClass{}},
+	UInt32:       {4, &UInt32StorageClass{}},
+	UInt64:       {8, &UInt64StorageClass{}},
+	Bits1x8:      {1, &Bits1x8StorageClass{}},
+	Bits2x4:      {1, &Bits2x4StorageClass{}},
+	Bits4x2:      {1, &Bits4x2StorageClass{}},
+	Bits8:        {1, &Bits8StorageClass{}},
+	Bits16:       {2, &Bits16StorageClass{}},
+}
+
+// ElementSize returns the size in bytes of the elements of the data type,
+// or 0 if it is not supported.
+func (d DType) ElementSize() int {
+	return dtypes[d].elementSize
+}
+
+// StorageClass returns the class of the storages of the data type, such as
+// *FloatStorageClass for Float32.
+func (d DType) StorageClass() (StorageClassInterface, error) {
+	info, ok := dtypes[d]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedDType, string(d))
+	}
+	return info.storageClass, nil
+}
+
+// ----- Untyped -----
+
+// UntypedStorageClass is the class of UntypedStorage, torch.UntypedStorage.
+type UntypedStorageClass struct{}
+
+var _ StorageClassInterface = &UntypedStorageClass{}
+
+func (f *UntypedStorageClass) New(size int, location string) StorageInterface {
+	return &UntypedStorage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// UntypedStorage is a storage of bytes, whose data type is given by the
+// tensors using it, as pickled by PyTorch 2.x for the data types without a
+// legacy storage class (see RebuildTensorV3). Its Size is in bytes.
+type UntypedStorage struct {
+	BaseStorage
+	Data []byte
+
+	// typed holds the storages decoded by Tensor.Storage, by data type.
+	mu    sync.Mutex
+	typed map[DType]StorageInterface
+}
+
+var _ StorageViewInterface = &UntypedStorage{}
+
+func (f *UntypedStorage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *UntypedStorage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]byte, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.setData(data)
+	return nil
+}
+
+func (f *UntypedStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*UntypedStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *UntypedStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.setData(rs.Data[offset : offset+size : offset+size])
+	return nil
+}
+
+func (f *UntypedStorage) setData(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Data = data
+	f.typed = nil
+}
+
+// Typed decodes the bytes of the storage as elements of the data type,
+// returning a new storage of its storage class, such as a *FloatStorage for
+// Float32.
+func (f *UntypedStorage) Typed(dtype DType) (StorageInterface, error) {
+	class, err := dtype.StorageClass()
+	if err != nil {
+		return nil, err
+	}
+	elementSize := dtype.ElementSize()
+	if len(f.Data)%elementSize != 0 {
+		return nil, fmt.Errorf("untyped storage of %d bytes: not a multiple of the %s element size %d",
+			len(f.Data), dtype, elementSize)
+	}
+	size := len(f.Data) / elementSize
+	storage := class.New(size, f.Location)
+	if err = storage.SetFromFileWithSize(bytes.NewReader(f.Data), size); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// cachedTyped is like Typed, decoding the storage only on the first call
+// for each data type, and returning the same storage afterwards.
+func (f *UntypedStorage) cachedTyped(dtype DType) (StorageInterface, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if storage, ok := f.typed[dtype]; ok {
+		return storage, nil
+	}
+	storage, err := f.Typed(dtype)
+	if err != nil {
+		return nil, err
+	}
+	if f.typed == nil {
+		f.typed = make(map[DType]StorageInterface)
+	}
+	f.typed[dtype] = storage
+	return storage, nil
+}
+
+// RebuildTensorV3 represents the "torch._utils._rebuild_tensor_v3"
+// function, with arguments (storage, storage_offset, size, stride,
+// requires_grad, backward_hooks, dtype[, metadata]), used by PyTorch 2.x
+// for the tensors of the data types without a legacy storage class, such as
+// float8_e4m3fn and uint16. The storage is usually an *UntypedStorage: use
+// Tensor.Storage to decode it according to Tensor.DType.
+type RebuildTensorV3 struct{}
+
+var _ types.Callable = &RebuildTensorV3{}
+
+func (r *RebuildTensorV3) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 7 && len(args) != 8 {
+		return nil, fmt.Errorf("RebuildTensorV3 unexpected args: %#v", args)
+	}
+	dtype, ok := args[6].(DType)
+	if !ok {
+		return nil, fmt.Errorf("RebuildTensorV3: unexpected dtype %#v", args[6])
+	}
+	// the first 6 arguments are the same as _rebuild_tensor_v2, metadata is
+	// unused
+	result, err := (&RebuildTensorV2{}).Call(args[:6]...)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensorV3: %w", err)
+	}
+	tensor := result.(*Tensor)
+	tensor.DType = dtype
+	return tensor, nil
+}
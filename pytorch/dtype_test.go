@@ -0,0 +1,183 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// The fixtures of these tests are emulated checkpoints, written without
+// torch by testdata/generate_dtype_fixtures.py, which reproduces the
+// "torch.save" of PyTorch 2.x: they are not files written by PyTorch itself.
+
+func TestRebuildTensorV3(t *testing.T) {
+	t.Run("float8_e4m3fn", func(t *testing.T) {
+		tensor := loadTensorFromFile(t, "tensor_float8_e4m3fn_v3_zip.pt")
+		if tensor.DType != Float8E4M3FN {
+			t.Errorf("expected dtype %q, actual %q", Float8E4M3FN, tensor.DType)
+		}
+		untyped, ok := tensor.Source.(*UntypedStorage)
+		if !ok {
+			t.Fatalf("expected *UntypedStorage, got %#v", tensor.Source)
+		}
+		assertBaseStorageFields(t, untyped.BaseStorage, 4, "cpu")
+		assertIntSliceEqual(t, tensor.Size, []int{4})
+
+		storage, err := tensor.Storage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs, ok := storage.(*Float8E4M3FNStorage)
+		if !ok {
+			t.Fatalf("expected *Float8E4M3FNStorage, got %#v", storage)
+		}
+		assertBaseStorageFields(t, fs.BaseStorage, 4, "cpu")
+		if !reflect.DeepEqual(fs.Data, []uint8{0x38, 0xb8, 0x7e, 0x01}) {
+			t.Errorf("unexpected data %v", fs.Data)
+		}
+		values := make([]float32, 4)
+		fs.ToFloat32(values)
+		assertFloat32SliceEqual(t, values, []float32{1, -1, 448, 0x1p-9}, 0)
+	})
+
+	t.Run("uint16 with metadata", func(t *testing.T) {
+		tensor := loadTensorFromFile(t, "tensor_uint16_v3_zip.pt")
+		if tensor.DType != UInt16 {
+			t.Errorf("expected dtype %q, actual %q", UInt16, tensor.DType)
+		}
+		storage, err := tensor.Storage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		us, ok := storage.(*UInt16Storage)
+		if !ok {
+			t.Fatalf("expected *UInt16Storage, got %#v", storage)
+		}
+		if !reflect.DeepEqual(us.Data, []uint16{1, 65535, 256}) {
+			t.Errorf("unexpected data %v", us.Data)
+		}
+
+		// the decoded storage is shared by the tensors of the storage
+		view := &Tensor{Source: tensor.Source, DType: UInt16}
+		if again, err := view.Storage(); err != nil || again != storage {
+			t.Errorf("expected the same storage, got %#v, %v", again, err)
+		}
+		if bits, err := (&Tensor{Source: tensor.Source, DType: Bits16}).Storage(); err != nil || bits == storage {
+			t.Errorf("expected a storage for another dtype, got %#v, %v", bits, err)
+		}
+		untyped := tensor.Source.(*UntypedStorage)
+		if err = untyped.SetFromFileWithSize(bytes.NewReader([]byte{2, 0}), 2); err != nil {
+			t.Fatal(err)
+		}
+		if again, err := tensor.Storage(); err != nil || again.(*UInt16Storage).Data[0] != 2 {
+			t.Errorf("expected the storage to be decoded again, got %#v, %v", again, err)
+		}
+	})
+
+	t.Run("lazy", func(t *testing.T) {
+		lf, err := LoadLazy("testdata/tensor_uint16_v3_zip.pt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lf.Close()
+		storage, err := lf.Data.(*Tensor).Storage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if us, ok := storage.(*UInt16Storage); !ok || len(us.Data) != 3 {
+			t.Errorf("unexpected storage %#v", storage)
+		}
+	})
+}
+
+// PyTorch itself never writes a torch.dtype in place of the storage class
+// of a persistent ID, so this is checked without a fixture.
+func TestDTypeStorageID(t *testing.T) {
+	args := types.NewTupleFromSlice([]interface{}{Float32, "0", "cpu", 4})
+	dataType, key, location, size, err := (&Loader{}).storageArgs(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dataType.(*FloatStorageClass); !ok {
+		t.Errorf("expected *FloatStorageClass, got %#v", dataType)
+	}
+	if key != "0" || location != "cpu" || size != 4 {
+		t.Errorf("unexpected key %q, location %q, size %d", key, location, size)
+	}
+}
+
+func TestDType(t *testing.T) {
+	for dtype, info := range dtypes {
+		if dtype.ElementSize() != info.elementSize {
+			t.Errorf("%s: unexpected element size %d", dtype, dtype.ElementSize())
+		}
+		class, err := dtype.StorageClass()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the element size matches the storage type
+		storage := class.New(0, "cpu")
+		data := make([]byte, 3*info.elementSize)
+		untyped := &UntypedStorage{Data: data}
+		typed, err := untyped.Typed(dtype)
+		if err != nil {
+			t.Fatalf("%s: %v", dtype, err)
+		}
+		if reflect.TypeOf(typed) != reflect.TypeOf(storage) {
+			t.Errorf("%s: expected %T, actual %T", dtype, storage, typed)
+		}
+		if n := reflect.ValueOf(typed).Elem().FieldByName("Data").Len(); n != 3 {
+			t.Errorf("%s: expected 3 elements, actual %d", dtype, n)
+		}
+
+		found, err := makePickleFindClass(nil)("torch", string(dtype))
+		if err != nil || found != dtype {
+			t.Errorf("%s: unexpected global %#v, %v", dtype, found, err)
+		}
+	}
+
+	if _, err := DType("float4").StorageClass(); !errors.Is(err, ErrUnsupportedDType) {
+		t.Errorf("expected ErrUnsupportedDType, got %v", err)
+	}
+	if DType("float4").ElementSize() != 0 {
+		t.Errorf("expected element size 0 for an unsupported dtype")
+	}
+	if _, err := (&UntypedStorage{Data: make([]byte, 3)}).Typed(Float32); err == nil {
+		t.Errorf("expected an error for a partial element")
+	}
+	if _, err := (&UntypedStorage{}).Typed("float4"); !errors.Is(err, ErrUnsupportedDType) {
+		t.Errorf("expected ErrUnsupportedDType, got %v", err)
+	}
+}
+
+func TestFindClassStorageGlobals(t *testing.T) {
+	if _, err := makePickleFindClass(nil)("torch.storage", "float32"); err == nil {
+		t.Errorf("expected dtypes to be found only in the torch module")
+	}
+	for _, name := range []string{"torch.UntypedStorage", "torch.storage.UntypedStorage"} {
+		i := len(name) - len("UntypedStorage")
+		class, err := makePickleFindClass(nil)(name[:i-1], name[i:])
+		if _, ok := class.(*UntypedStorageClass); !ok || err != nil {
+			t.Errorf("%s: unexpected class %#v, %v", name, class, err)
+		}
+	}
+}
+
+func TestLoaderRawFloat16DTypeStorageID(t *testing.T) {
+	args := types.NewTupleFromSlice([]interface{}{Float16, "0", "cpu", 2})
+	dataType, _, _, _, err := (&Loader{RawFloat16: true}).storageArgs(args)
+	if _, ok := dataType.(*RawHalfStorageClass); !ok || err != nil {
+		t.Errorf("expected *RawHalfStorageClass, got %#v, %v", dataType, err)
+	}
+	dataType, _, _, _, err = (&Loader{}).storageArgs(args)
+	if _, ok := dataType.(*HalfStorageClass); !ok || err != nil {
+		t.Errorf("expected *HalfStorageClass, got %#v, %v", dataType, err)
+	}
+}
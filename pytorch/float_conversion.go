@@ -11,6 +11,104 @@ func FloatBits16to32(u16 uint16) uint32 {
 	return mantissaTable[offsetTable[u16>>10]+(uint32(u16)&0x3ff)] + exponentTable[u16>>10]
 }
 
+// FloatBits32to16 converts an IEEE 754 float representation (32 bits) to
+// the bits representation of the nearest Half Float (16 bits) number,
+// rounding ties to even. Values too large for a Half Float become infinite;
+// NaN values stay NaN.
+func FloatBits32to16(u32 uint32) uint16 {
+	sign := uint16(u32>>16) & 0x8000
+	exp := int32(u32>>23) & 0xff
+	mant := u32 & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			// NaN: keep the high payload bits, making it quiet.
+			return sign | 0x7e00 | uint16(mant>>13)
+		}
+		return sign | 0x7c00
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+	if e <= 0 {
+		// Subnormal Half Float (or zero), whose value is half * 2^-24.
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	// A carry out of the mantissa correctly increments the exponent, up to
+	// infinity.
+	half := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// BFloatBits16to32 converts the bits representation of a Brain Float
+// (bfloat16) number to an IEEE 754 float representation (32 bits).
+func BFloatBits16to32(u16 uint16) uint32 {
+	return uint32(u16) << 16
+}
+
+// BFloatBits32to16 converts an IEEE 754 float representation (32 bits) to
+// the bits representation of the nearest Brain Float (bfloat16) number,
+// rounding ties to even. NaN values stay NaN.
+func BFloatBits32to16(u32 uint32) uint16 {
+	if u32&0x7fffffff > 0x7f800000 {
+		// NaN: truncating could clear all the mantissa bits.
+		return uint16(u32>>16) | 0x40
+	}
+	rounding := uint32(0x7fff) + (u32>>16)&1
+	return uint16((u32 + rounding) >> 16)
+}
+
+// Float8E4M3FNBits8to32 converts the bits representation of a
+// float8_e4m3fn number (1 sign bit, 4 exponent bits, 3 mantissa bits, no
+// infinities) to an IEEE 754 float representation (32 bits).
+func Float8E4M3FNBits8to32(u8 uint8) uint32 {
+	sign := uint32(u8&0x80) << 24
+	exp := uint32(u8>>3) & 0xf
+	mant := uint32(u8 & 0x7)
+	switch {
+	case exp == 0xf && mant == 0x7:
+		return sign | 0x7fc00000 // NaN
+	case exp == 0:
+		if mant == 0 {
+			return sign
+		}
+		// Subnormal number, mant * 2^-9: normalize it.
+		e := uint32(127 - 6)
+		for mant&0x8 == 0 {
+			mant <<= 1
+			e--
+		}
+		return sign | e<<23 | (mant&0x7)<<20
+	}
+	return sign | (exp+127-7)<<23 | mant<<20
+}
+
+// Float8E5M2Bits8to32 converts the bits representation of a float8_e5m2
+// number (1 sign bit, 5 exponent bits, 2 mantissa bits) to an IEEE 754 float
+// representation (32 bits). A float8_e5m2 number has the bits of the
+// highest byte of a Half Float.
+func Float8E5M2Bits8to32(u8 uint8) uint32 {
+	return FloatBits16to32(uint16(u8) << 8)
+}
+
 var mantissaTable [2048]uint32
 var exponentTable [64]uint32
 var offsetTable [64]uint32
@@ -45,20 +143,17 @@ func initExponentTable() {
 }
 
 func initOffsetTable() {
-	offsetTable[0] = 0
-	offsetTable[32] = 0
-	for i := uint32(1); i < 31; i++ {
-		offsetTable[i] = 1024
-	}
-	for i := uint32(32); i < 64; i++ {
+	for i := range offsetTable {
 		offsetTable[i] = 1024
 	}
+	offsetTable[0] = 0
+	offsetTable[32] = 0
 }
 
 func convertMantissa(i uint32) uint32 {
 	var m uint32 = i << 13  // zero pad mantissa bits
 	var e uint32 = 0        // zero exponent
-	for m&0x00800000 != 0 { // while not normalized
+	for m&0x00800000 == 0 { // while not normalized
 		e -= 0x00800000 // decrement exponent (1 << 23)
 		m <<= 1         // shift mantissa
 	}
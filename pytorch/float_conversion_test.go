@@ -0,0 +1,205 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"math"
+	"testing"
+)
+
+// halfToFloat64 decodes a Half Float from its definition, as a reference
+// for FloatBits16to32.
+func halfToFloat64(u16 uint16) float64 {
+	sign := 1.0
+	if u16&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(u16>>10) & 0x1f
+	mant := float64(u16 & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant != 0 {
+			return math.NaN()
+		}
+		return sign * math.Inf(1)
+	}
+	return sign * math.Ldexp(1+mant/1024, exp-15)
+}
+
+func TestFloatBits16to32(t *testing.T) {
+	for i := 0; i < 1<<16; i++ {
+		u16 := uint16(i)
+		actual := math.Float32frombits(FloatBits16to32(u16))
+		expected := halfToFloat64(u16)
+		if math.IsNaN(expected) {
+			if !math.IsNaN(float64(actual)) {
+				t.Fatalf("%#04x: expected NaN, actual %v", u16, actual)
+			}
+			continue
+		}
+		if float64(actual) != expected || math.Signbit(float64(actual)) != math.Signbit(expected) {
+			t.Fatalf("%#04x: expected %v, actual %v", u16, expected, actual)
+		}
+	}
+}
+
+// TestFloatBits16to32Regression checks the values which were converted
+// incorrectly before the fixes to initOffsetTable (the offset of the
+// exponent 31, for infinities and NaN, was left to 0, and the one of the
+// negative exponent 0 was set to 1024) and convertMantissa (the
+// normalization loop condition of subnormal numbers was inverted).
+func TestFloatBits16to32Regression(t *testing.T) {
+	testCases := []struct {
+		u16      uint16
+		expected uint32
+		old      uint32
+	}{
+		{0x8000, 0x80000000, 0xb8000000}, // -0, was -3.0517578e-05
+		{0x0001, 0x33800000, 0x38802000}, // 2^-24, was 6.109476e-05
+		{0x03ff, 0x387fc000, 0x38ffe000}, // the largest subnormal, was 0.00012201071
+		{0x8001, 0xb3800000, 0xb8002000}, // -2^-24, was -3.054738e-05
+		{0x7c00, 0x7f800000, 0x47800000}, // +Inf, was 65536
+		{0x7e00, 0x7fc00000, 0x80400000}, // NaN, was -5.877472e-39
+	}
+	for _, tc := range testCases {
+		actual := FloatBits16to32(tc.u16)
+		if actual != tc.expected {
+			t.Errorf("%#04x: expected %#08x, actual %#08x (old value %#08x)",
+				tc.u16, tc.expected, actual, tc.old)
+		}
+	}
+}
+
+func TestFloatBits32to16(t *testing.T) {
+	// Expected values from Python struct.pack("<e", x), which rounds ties
+	// to even.
+	testCases := []struct {
+		u32      uint32
+		expected uint16
+	}{
+		{0x00000000, 0x0000}, // 0
+		{0x80000000, 0x8000}, // -0
+		{0x3f800000, 0x3c00}, // 1
+		{0xc0000000, 0xc000}, // -2
+		{0x477fe000, 0x7bff}, // 65504, the largest Half Float
+		{0x477feffd, 0x7bff}, // 65519.99
+		{0x477ff000, 0x7c00}, // 65520, rounded to infinity
+		{0x322bcc77, 0x0000}, // 1e-08
+		{0x33800000, 0x0001}, // 2^-24, the smallest subnormal
+		{0x33000000, 0x0000}, // 2^-25, a tie rounded to even
+		{0x33400000, 0x0001}, // 1.5 * 2^-25
+		{0x33c00000, 0x0002}, // 3 * 2^-25, a tie rounded to even
+		{0x387fda40, 0x03ff}, // 6.1e-05
+		{0x3f801000, 0x3c00}, // 1 + 2^-11, a tie rounded to even
+		{0x3f803000, 0x3c02}, // 1 + 3 * 2^-11, a tie rounded to even
+		{0x3dcccccd, 0x2e66}, // 0.1
+		{0x7f800000, 0x7c00}, // +Inf
+		{0xff800000, 0xfc00}, // -Inf
+	}
+	for _, tc := range testCases {
+		if actual := FloatBits32to16(tc.u32); actual != tc.expected {
+			t.Errorf("%#08x: expected %#04x, actual %#04x", tc.u32, tc.expected, actual)
+		}
+	}
+
+	for _, u32 := range []uint32{0x7f800001, 0x7fc00000, 0xffffffff} {
+		if actual := FloatBits32to16(u32); actual&0x7c00 != 0x7c00 || actual&0x3ff == 0 {
+			t.Errorf("%#08x: expected NaN, actual %#04x", u32, actual)
+		}
+	}
+}
+
+func TestFloatBits16RoundTrip(t *testing.T) {
+	for i := 0; i < 1<<16; i++ {
+		u16 := uint16(i)
+		actual := FloatBits32to16(FloatBits16to32(u16))
+		if u16&0x7c00 == 0x7c00 && u16&0x3ff != 0 {
+			// NaN payloads are made quiet.
+			if actual != u16|0x200 {
+				t.Fatalf("%#04x: expected %#04x, actual %#04x", u16, u16|0x200, actual)
+			}
+			continue
+		}
+		if actual != u16 {
+			t.Fatalf("%#04x: expected %#04x, actual %#04x", u16, u16, actual)
+		}
+	}
+}
+
+func TestBFloatBits32to16(t *testing.T) {
+	testCases := []struct {
+		u32      uint32
+		expected uint16
+	}{
+		{0x00000000, 0x0000}, // 0
+		{0x80000000, 0x8000}, // -0
+		{0x3f800000, 0x3f80}, // 1
+		{0x3f808000, 0x3f80}, // 1 + 2^-8, a tie rounded to even
+		{0x3f818000, 0x3f82}, // 1 + 3 * 2^-8, a tie rounded to even
+		{0x3f808001, 0x3f81}, // just above a tie
+		{0x3dcccccd, 0x3dcd}, // 0.1
+		{0x7f7fffff, 0x7f80}, // the largest float, rounded to infinity
+		{0x7f800000, 0x7f80}, // +Inf
+		{0xff800000, 0xff80}, // -Inf
+		{0x7f800001, 0x7fc0}, // NaN
+	}
+	for _, tc := range testCases {
+		if actual := BFloatBits32to16(tc.u32); actual != tc.expected {
+			t.Errorf("%#08x: expected %#04x, actual %#04x", tc.u32, tc.expected, actual)
+		}
+	}
+
+	for i := 0; i < 1<<16; i++ {
+		u16 := uint16(i)
+		if actual := BFloatBits32to16(BFloatBits16to32(u16)); actual != u16 && !(u16&0x7f80 == 0x7f80 && u16&0x7f != 0) {
+			t.Fatalf("%#04x: expected %#04x, actual %#04x", u16, u16, actual)
+		}
+	}
+}
+
+func TestFloat8E4M3FNBits8to32(t *testing.T) {
+	for i := 0; i < 1<<8; i++ {
+		u8 := uint8(i)
+		actual := math.Float32frombits(Float8E4M3FNBits8to32(u8))
+		sign := 1.0
+		if u8&0x80 != 0 {
+			sign = -1
+		}
+		exp := int(u8>>3) & 0xf
+		mant := float64(u8 & 0x7)
+		var expected float64
+		switch {
+		case exp == 0xf && mant == 0x7:
+			expected = math.NaN()
+		case exp == 0:
+			expected = sign * math.Ldexp(mant, -9)
+		default:
+			expected = sign * math.Ldexp(1+mant/8, exp-7)
+		}
+		if math.IsNaN(expected) {
+			if !math.IsNaN(float64(actual)) {
+				t.Fatalf("%#02x: expected NaN, actual %v", u8, actual)
+			}
+			continue
+		}
+		if float64(actual) != expected || math.Signbit(float64(actual)) != math.Signbit(expected) {
+			t.Fatalf("%#02x: expected %v, actual %v", u8, expected, actual)
+		}
+	}
+}
+
+func TestFloat8E5M2Bits8to32(t *testing.T) {
+	for i := 0; i < 1<<8; i++ {
+		u8 := uint8(i)
+		actual := math.Float32frombits(Float8E5M2Bits8to32(u8))
+		expected := halfToFloat64(uint16(u8) << 8)
+		if math.IsNaN(expected) != math.IsNaN(float64(actual)) ||
+			!math.IsNaN(expected) && float64(actual) != expected {
+			t.Fatalf("%#02x: expected %v, actual %v", u8, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzLoad checks that loading any file never panics.
+//
+// Besides the checked-in corpus in testdata/fuzz/FuzzLoad, all the
+// testdata/*.pt fixtures are used as seeds.
+func FuzzLoad(f *testing.F) {
+	filenames, err := filepath.Glob(path.Join("testdata", "*.pt"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		filename := path.Join(dir, "fuzz.pt")
+		if err := os.WriteFile(filename, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = Load(filename)
+	})
+}
+
+// FuzzLoadScript checks that loading any file with LoadScript never panics.
+func FuzzLoadScript(f *testing.F) {
+	for _, filename := range []string{"script_module.pt", "tensor_float32_proto2_zip.pt"} {
+		data, err := os.ReadFile(path.Join("testdata", filename))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		filename := path.Join(dir, "fuzz.pt")
+		if err := os.WriteFile(filename, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = LoadScript(filename)
+	})
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrLazyFileClosed is returned when materializing a LazyStorage whose
+// LazyFile has been closed.
+var ErrLazyFileClosed = errors.New("lazy file closed")
+
+// LazyFile is a PyTorch file loaded by LoadLazy or LoadLazyReaderAt, or
+// the Loader methods of the same names.
+//
+// The storages of files in the zip format are not read while loading: they
+// are LazyStorage placeholders, read and decoded on demand. This allows
+// inspecting the metadata of tensors, and reading only some of them, without
+// the memory needed by the whole file. Files in the legacy formats are
+// loaded as by Load.
+type LazyFile struct {
+	// Data is the loaded object.
+	Data    interface{}
+	archive *lazyArchive
+}
+
+// LoadLazy is like Load, returning a LazyFile whose storages are read on
+// demand. The file is kept open until LazyFile.Close is called.
+func LoadLazy(filename string) (*LazyFile, error) {
+	return (&Loader{}).LoadLazy(filename)
+}
+
+// LoadLazyReaderAt is like LoadLazy, reading the size bytes of a file from
+// r, which must stay readable until the storages are materialized.
+func LoadLazyReaderAt(r io.ReaderAt, size int64) (*LazyFile, error) {
+	return (&Loader{}).LoadLazyReaderAt(r, size)
+}
+
+// LoadLazy loads a PyTorch file lazily, like the package function LoadLazy.
+func (l *Loader) LoadLazy(filename string) (*LazyFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	lf, err := l.loadLazy(f, fi.Size(), f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return lf, nil
+}
+
+// LoadLazyReaderAt loads the size bytes of a PyTorch file from r lazily,
+// like the package function LoadLazyReaderAt.
+func (l *Loader) LoadLazyReaderAt(r io.ReaderAt, size int64) (*LazyFile, error) {
+	return l.loadLazy(r, size, nil)
+}
+
+func (l *Loader) loadLazy(r io.ReaderAt, size int64, closer io.Closer) (*LazyFile, error) {
+	archive := &lazyArchive{closer: closer}
+	data, err := l.loadReaderAt(r, size, archive)
+	if err != nil {
+		return nil, err
+	}
+	return &LazyFile{Data: data, archive: archive}, nil
+}
+
+// Storages returns the lazy storages of the file, in the order they are
+// referenced by the data.
+func (f *LazyFile) Storages() []*LazyStorage {
+	return f.archive.storages
+}
+
+// Materialize reads all the storages which are not read yet.
+func (f *LazyFile) Materialize() error {
+	for _, s := range f.archive.storages {
+		if _, err := s.Materialize(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the file. The storages already materialized stay
+// available; the others can no longer be read.
+func (f *LazyFile) Close() error {
+	return f.archive.close()
+}
+
+// lazyArchive is the zip archive of a LazyFile, shared by its storages.
+type lazyArchive struct {
+	// mu is held for reading while reading storages, and for writing while
+	// closing the archive.
+	mu       sync.RWMutex
+	closed   bool
+	closer   io.Closer
+	storages []*LazyStorage
+}
+
+// newStorage returns a LazyStorage for the zip record with the given key.
+func (a *lazyArchive) newStorage(
+	dataType StorageClassInterface,
+	size int,
+	location, key string,
+	zipFileRecords map[string]*zip.File,
+) (*LazyStorage, error) {
+	file, err := storageRecord(size, key, zipFileRecords)
+	if err != nil {
+		return nil, err
+	}
+	s := &LazyStorage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		DataType:    dataType,
+		Key:         key,
+		record:      file,
+		archive:     a,
+	}
+	a.storages = append(a.storages, s)
+	return s, nil
+}
+
+func (a *lazyArchive) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// LazyStorage is a placeholder for a storage of a LazyFile, holding its zip
+// record, data type and size. The data is read and decoded by Materialize.
+//
+// A LazyStorage is safe for concurrent use by multiple goroutines.
+type LazyStorage struct {
+	BaseStorage
+	// DataType is the class of the storage, such as *FloatStorageClass.
+	DataType StorageClassInterface
+	// Key is the name of the zip record of the storage.
+	Key string
+
+	record  *zip.File
+	archive *lazyArchive
+
+	mu      sync.Mutex
+	storage StorageInterface
+}
+
+var _ StorageInterface = &LazyStorage{}
+
+// Materialize returns the storage, reading and decoding its data on the
+// first call, such as a *FloatStorage for a *FloatStorageClass DataType.
+func (s *LazyStorage) Materialize() (StorageInterface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.storage != nil {
+		return s.storage, nil
+	}
+	if s.archive == nil {
+		return nil, fmt.Errorf("storage '%s': no data source", s.Key)
+	}
+
+	s.archive.mu.RLock()
+	defer s.archive.mu.RUnlock()
+	if s.archive.closed {
+		return nil, fmt.Errorf("storage '%s': %w", s.Key, ErrLazyFileClosed)
+	}
+	storage, err := readStorageRecord(s.DataType, s.Size, s.Location, s.record)
+	if err != nil {
+		return nil, err
+	}
+	s.storage = storage
+	return storage, nil
+}
+
+// IsMaterialized reports whether the data of the storage has been read.
+func (s *LazyStorage) IsMaterialized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storage != nil
+}
+
+// SetFromFile reads the storage from r, rather than from its zip record.
+func (s *LazyStorage) SetFromFile(r io.Reader) error {
+	return setFromFile(s, r)
+}
+
+// SetFromFileWithSize reads the storage from r, rather than from its zip
+// record.
+func (s *LazyStorage) SetFromFileWithSize(r io.Reader, size int) error {
+	storage := s.DataType.New(size, s.Location)
+	if err := storage.SetFromFileWithSize(r, size); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Size = size
+	s.storage = storage
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLoadLazy(t *testing.T) {
+	filename := path.Join("testdata", "tensor_float32_proto2_zip.pt")
+	expected := loadTensorFromFile(t, "tensor_float32_proto2_zip.pt")
+
+	lf, err := LoadLazy(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+
+	tensor, ok := lf.Data.(*Tensor)
+	if !ok {
+		t.Fatalf("expected *Tensor, got %#v", lf.Data)
+	}
+	lazy, ok := tensor.Source.(*LazyStorage)
+	if !ok {
+		t.Fatalf("expected *LazyStorage, got %#v", tensor.Source)
+	}
+	assertBaseStorageFields(t, lazy.BaseStorage, 4, "cpu")
+	if _, ok := lazy.DataType.(*FloatStorageClass); !ok {
+		t.Errorf("expected *FloatStorageClass, got %#v", lazy.DataType)
+	}
+	if lazy.IsMaterialized() {
+		t.Errorf("expected the storage not to be materialized yet")
+	}
+	if storages := lf.Storages(); len(storages) != 1 || storages[0] != lazy {
+		t.Errorf("unexpected storages %v", storages)
+	}
+	assertIntSliceEqual(t, tensor.Size, expected.Size)
+
+	// concurrent materializations read the storage once
+	results := make([]StorageInterface, 4)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = tensor.Storage()
+		}(i)
+	}
+	wg.Wait()
+	for _, storage := range results {
+		if storage == nil || storage != results[0] {
+			t.Fatalf("unexpected storages %v", results)
+		}
+	}
+	if !reflect.DeepEqual(results[0], expected.Source) {
+		t.Errorf("expected %#v, actual %#v", expected.Source, results[0])
+	}
+
+	if err = lf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// materialized storages stay available
+	if storage, err := lazy.Materialize(); err != nil || storage != results[0] {
+		t.Errorf("unexpected Materialize result %v, %v", storage, err)
+	}
+}
+
+func TestLoadLazyClosed(t *testing.T) {
+	data, err := os.ReadFile(path.Join("testdata", "tensor_int64_proto4_zip.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf, err := LoadLazyReaderAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = lf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = lf.Materialize(); !errors.Is(err, ErrLazyFileClosed) {
+		t.Errorf("expected ErrLazyFileClosed, got %v", err)
+	}
+}
+
+func TestLoadLazyLegacy(t *testing.T) {
+	lf, err := LoadLazy(path.Join("testdata", "tensor_int64_proto4.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+	tensor := lf.Data.(*Tensor)
+	if _, ok := tensor.Source.(*LongStorage); !ok {
+		t.Errorf("expected *LongStorage, got %#v", tensor.Source)
+	}
+	if len(lf.Storages()) != 0 {
+		t.Errorf("unexpected lazy storages %v", lf.Storages())
+	}
+	if err = lf.Materialize(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoaderLoadLazy(t *testing.T) {
+	loader := &Loader{RawFloat16: true}
+	filename := path.Join("testdata", "tensor_float16_proto2_zip.pt")
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, load := range map[string]func() (*LazyFile, error){
+		"LoadLazy":         func() (*LazyFile, error) { return loader.LoadLazy(filename) },
+		"LoadLazyReaderAt": func() (*LazyFile, error) { return loader.LoadLazyReaderAt(bytes.NewReader(b), int64(len(b))) },
+	} {
+		lf, err := load()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		storage, err := lf.Data.(*Tensor).Storage()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, ok := storage.(*RawHalfStorage); !ok {
+			t.Errorf("%s: expected *RawHalfStorage, got %#v", name, storage)
+		}
+		lf.Close()
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// loadLegacyTar loads a file in the tar format used by PyTorch before
+// version 0.1.10, like Python "torch.serialization.legacy_load". The
+// archive is made of these members:
+//
+//   - "sys_info": a pickled dict describing the saving system (ignored);
+//   - "pickle": the pickled object, referring to tensors and storages by
+//     persistent IDs (their Python id(), as string);
+//   - "tensors": the metadata of the tensors (storage, size, stride and
+//     offset);
+//   - "storages": the data of the storages, followed by the list of the
+//     storage views.
+//
+// Storages are read while streaming the archive; the "pickle" and "tensors"
+// members, which are small, are kept in memory until all the storages are
+// loaded.
+func (l *Loader) loadLegacyTar(r io.Reader) (interface{}, error) {
+	objects := make(map[string]interface{})
+	var pickleData, tensorsData []byte
+	var hasPickle, hasTensors, hasStorages bool
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Name {
+		case "pickle":
+			if pickleData, err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			hasPickle = true
+		case "tensors":
+			if tensorsData, err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			hasTensors = true
+		case "storages":
+			lr := &io.LimitedReader{R: tr, N: hdr.Size}
+			if err = l.loadLegacyTarStorages(lr, objects); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			hasStorages = true
+		}
+	}
+	switch {
+	case !hasStorages:
+		return nil, fmt.Errorf("storages not found in tar archive")
+	case !hasTensors:
+		return nil, fmt.Errorf("tensors not found in tar archive")
+	case !hasPickle:
+		return nil, fmt.Errorf("pickle not found in tar archive")
+	}
+
+	if err := l.loadLegacyTarTensors(bytes.NewReader(tensorsData), objects); err != nil {
+		return nil, fmt.Errorf("tensors: %w", err)
+	}
+
+	u := l.newUnpickler(bytes.NewReader(pickleData))
+	u.FindClass = l.makePickleFindClass(u.FindClass)
+	u.PersistentLoad = nil
+	u.PersistentResolver = pickle.PersistentResolverFunc(func(id pickle.PersistentID) (interface{}, error) {
+		if t, ok := id.Value.(*types.Tuple); ok {
+			// a module class, with its source file and code
+			if t.Len() == 0 {
+				return nil, fmt.Errorf("empty persistent ID")
+			}
+			return t.Get(0), nil
+		}
+		key, err := legacyObjectKey(id.Value)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := objects[key]
+		if !ok {
+			return nil, fmt.Errorf("tensor or storage not found for key '%s'", key)
+		}
+		return obj, nil
+	})
+	result, err := u.Load()
+	if err != nil {
+		return nil, fmt.Errorf("pickle: %w", err)
+	}
+	return result, nil
+}
+
+// loadLegacyTarStorages reads the "storages" member of a legacy tar file:
+// the number of storages, then for each of them a pickled (key, location,
+// storage_type) tuple followed by the storage data, and finally the list of
+// storage views.
+func (l *Loader) loadLegacyTarStorages(r *io.LimitedReader, objects map[string]interface{}) error {
+	u := l.newUnpickler(r)
+	u.FindClass = l.makePickleFindClass(u.FindClass)
+	load := func() (interface{}, error) {
+		u.Reset(r)
+		return u.Load()
+	}
+
+	classes := make(map[string]legacyStorageClass)
+	obj, err := load()
+	if err != nil {
+		return err
+	}
+	numStorages, err := legacyCount(obj)
+	if err != nil || numStorages < 0 {
+		return fmt.Errorf("invalid number of storages %#v", obj)
+	}
+	for i := 0; i < numStorages; i++ {
+		obj, err = load()
+		if err != nil {
+			return err
+		}
+		args, ok := obj.(*types.Tuple)
+		if !ok || args.Len() != 3 {
+			return fmt.Errorf("invalid storage arguments %#v", obj)
+		}
+		key, err := legacyObjectKey(args.Get(0))
+		if err != nil {
+			return err
+		}
+		location, err := args.GetString(1)
+		if err != nil {
+			return fmt.Errorf("invalid storage location: %w", err)
+		}
+		dataType, ok := args.Get(2).(StorageClassInterface)
+		if !ok {
+			return fmt.Errorf("unexpected storage type %#v", args.Get(2))
+		}
+
+		var sizeBuf [8]byte
+		if _, err = io.ReadFull(r, sizeBuf[:]); err != nil {
+			return err
+		}
+		size := binary.LittleEndian.Uint64(sizeBuf[:])
+		if err = checkStorageSize(size, uint64(r.N)); err != nil {
+			return fmt.Errorf("storage '%s': %w", key, err)
+		}
+		storage := dataType.New(int(size), location)
+		if err = storage.SetFromFileWithSize(r, int(size)); err != nil {
+			return fmt.Errorf("storage '%s': %w", key, err)
+		}
+		objects[key] = storage
+		classes[key] = legacyStorageClass{dataType, location}
+	}
+
+	obj, err = load()
+	if err != nil {
+		return err
+	}
+	views, ok := obj.(*types.List)
+	if !ok {
+		return fmt.Errorf("invalid storage views %#v", obj)
+	}
+	for i := 0; i < views.Len(); i++ {
+		if err = loadLegacyTarStorageView(views.Get(i), objects, classes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyStorageClass is the type and location of a storage of a legacy tar
+// file, used to create its views.
+type legacyStorageClass struct {
+	dataType StorageClassInterface
+	location string
+}
+
+// loadLegacyTarStorageView sets up a storage view, described by a
+// (target_key, root_key, offset, size) tuple, onto a storage already loaded.
+func loadLegacyTarStorageView(obj interface{}, objects map[string]interface{}, classes map[string]legacyStorageClass) error {
+	t, ok := obj.(*types.Tuple)
+	if !ok || t.Len() != 4 {
+		return fmt.Errorf("invalid storage view %#v", obj)
+	}
+	key, err := legacyObjectKey(t.Get(0))
+	if err != nil {
+		return err
+	}
+	rootKey, err := legacyObjectKey(t.Get(1))
+	if err != nil {
+		return fmt.Errorf("storage view '%s': %w", key, err)
+	}
+	class, ok := classes[rootKey]
+	if !ok {
+		return fmt.Errorf("storage view '%s': storage not found for key '%s'", key, rootKey)
+	}
+	offset, err := t.GetInt(2)
+	if err != nil {
+		return fmt.Errorf("storage view '%s': invalid offset: %w", key, err)
+	}
+	size, err := t.GetInt(3)
+	if err != nil {
+		return fmt.Errorf("storage view '%s': invalid size: %w", key, err)
+	}
+	view, ok := class.dataType.New(size, class.location).(StorageViewInterface)
+	if !ok {
+		return fmt.Errorf("storage view '%s': views of type %T are not supported", key, class.dataType)
+	}
+	if err = view.SetFromStorage(objects[rootKey].(StorageInterface), offset, size); err != nil {
+		return fmt.Errorf("storage view '%s': %w", key, err)
+	}
+	objects[key] = view
+	return nil
+}
+
+// loadLegacyTarTensors reads the "tensors" member of a legacy tar file: the
+// number of tensors, then for each of them a pickled (key, storage_id,
+// tensor_type) tuple followed by the binary metadata of the tensor.
+func (l *Loader) loadLegacyTarTensors(r *bytes.Reader, objects map[string]interface{}) error {
+	u := l.newUnpickler(r)
+	u.FindClass = l.makeLegacyTensorFindClass(u.FindClass)
+	load := func() (interface{}, error) {
+		u.Reset(r)
+		return u.Load()
+	}
+
+	obj, err := load()
+	if err != nil {
+		return err
+	}
+	numTensors, err := legacyCount(obj)
+	if err != nil || numTensors < 0 {
+		return fmt.Errorf("invalid number of tensors %#v", obj)
+	}
+	for i := 0; i < numTensors; i++ {
+		obj, err = load()
+		if err != nil {
+			return err
+		}
+		args, ok := obj.(*types.Tuple)
+		if !ok || args.Len() != 3 {
+			return fmt.Errorf("invalid tensor arguments %#v", obj)
+		}
+		key, err := legacyObjectKey(args.Get(0))
+		if err != nil {
+			return err
+		}
+		storageKey, err := legacyObjectKey(args.Get(1))
+		if err != nil {
+			return fmt.Errorf("tensor '%s': %w", key, err)
+		}
+		storage, ok := objects[storageKey].(StorageInterface)
+		if !ok {
+			return fmt.Errorf("tensor '%s': storage not found for key '%s'", key, storageKey)
+		}
+		tensor, err := readLegacyTensorMetadata(r)
+		if err != nil {
+			return fmt.Errorf("tensor '%s': %w", key, err)
+		}
+		tensor.Source = storage
+		objects[key] = tensor
+	}
+	return nil
+}
+
+// readLegacyTensorMetadata reads the number of dimensions (as a 32-bit
+// integer, followed by 4 unused bytes), the size and the stride of each
+// dimension, and the storage offset, all as little-endian 64-bit integers.
+func readLegacyTensorMetadata(r *bytes.Reader) (*Tensor, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, err
+	}
+	ndim := int32(binary.LittleEndian.Uint32(buf[:4]))
+	if ndim < 0 || int64(ndim)*16+8 > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid number of dimensions %d", ndim)
+	}
+	values := make([]int, 2*ndim+1)
+	for i := range values {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		v := int64(binary.LittleEndian.Uint64(buf[:]))
+		if v < 0 || v > math.MaxInt {
+			return nil, fmt.Errorf("invalid tensor metadata value %d", v)
+		}
+		values[i] = int(v)
+	}
+	return &Tensor{
+		Size:          values[:ndim:ndim],
+		Stride:        values[ndim : 2*ndim : 2*ndim],
+		StorageOffset: values[2*ndim],
+	}, nil
+}
+
+// legacyCount returns the number of storages or tensors at the beginning of
+// the "storages" and "tensors" members of a legacy tar file, whose Go type
+// depends on the IntMode of the Unpickler.
+func legacyCount(v interface{}) (int, error) {
+	return types.NewTupleFromSlice([]interface{}{v}).GetInt(0)
+}
+
+// legacyObjectKey returns the key of a tensor or storage of a legacy tar
+// file, which is an integer in the "tensors" and "storages" members, and a
+// string in the persistent IDs of the "pickle" member.
+func legacyObjectKey(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case *big.Int:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("invalid tensor or storage key %#v", v)
+	}
+}
+
+// makeLegacyTensorFindClass is like makePickleFindClass, also accepting the
+// legacy tensor classes, such as torch.FloatTensor, which are only used as
+// markers in the "tensors" member of legacy tar files.
+func (l *Loader) makeLegacyTensorFindClass(fallback func(module, name string) (interface{}, error)) func(module, name string) (interface{}, error) {
+	findClass := l.makePickleFindClass(fallback)
+	return func(module, name string) (interface{}, error) {
+		if module == "torch" && strings.HasSuffix(name, "Tensor") {
+			return types.NewGenericClass(module, name), nil
+		}
+		return findClass(module, name)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// legacy_tar.pt is an emulated checkpoint, written without torch by
+// testdata/generate_legacy_fixtures.py, which reproduces the "torch.save" of
+// PyTorch 0.1.9, the last version using the tar format: it is not a file
+// written by such an old PyTorch version, which can no longer be installed.
+
+func TestLegacyTar(t *testing.T) {
+	result, err := Load(path.Join("testdata", "legacy_tar.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLegacyTar(t, result)
+}
+
+func TestLegacyTarIntModes(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		mode pickle.IntMode
+	}{
+		{"AlwaysInt64", pickle.AlwaysInt64},
+		{"AlwaysBigInt", pickle.AlwaysBigInt},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			loader := &Loader{
+				NewUnpickler: func(r io.Reader) pickle.Unpickler {
+					u := pickle.NewUnpickler(r)
+					u.IntMode = tc.mode
+					return u
+				},
+			}
+			result, err := loader.Load(path.Join("testdata", "legacy_tar.pt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertLegacyTar(t, result)
+		})
+	}
+}
+
+func assertLegacyTar(t *testing.T, result interface{}) {
+	t.Helper()
+	dict, ok := result.(*types.Dict)
+	if !ok {
+		t.Fatalf("expected *types.Dict, got %#v", result)
+	}
+	get := func(key string) interface{} {
+		value, ok := dict.Get(key)
+		if !ok {
+			t.Fatalf("key %q not found", key)
+		}
+		return value
+	}
+
+	weight, ok := get("weight").(*Tensor)
+	if !ok {
+		t.Fatalf("weight: expected *Tensor, got %#v", get("weight"))
+	}
+	fs, ok := weight.Source.(*FloatStorage)
+	if !ok {
+		t.Fatalf("expected *FloatStorage, got %#v", weight.Source)
+	}
+	assertBaseStorageFields(t, fs.BaseStorage, 9, "cpu")
+	assertFloat32SliceEqual(t, fs.Data,
+		[]float32{1.5, -2.5, 3.5, -4.5, 5.5, -6.5, 0.25, 0.5, 0.75}, 0)
+	assertIntSliceEqual(t, weight.Size, []int{2, 3})
+	assertIntSliceEqual(t, weight.Stride, []int{3, 1})
+	if weight.StorageOffset != 0 {
+		t.Errorf("weight: expected offset 0, got %d", weight.StorageOffset)
+	}
+
+	for _, tc := range []struct {
+		key    string
+		offset int
+	}{
+		{"bias", 6},
+		{"rebuilt", 3}, // torch._utils._rebuild_tensor
+	} {
+		tensor, ok := get(tc.key).(*Tensor)
+		if !ok {
+			t.Fatalf("%s: expected *Tensor, got %#v", tc.key, get(tc.key))
+		}
+		if tensor.Source != fs {
+			t.Errorf("%s: expected the storage of weight, got %#v", tc.key, tensor.Source)
+		}
+		assertIntSliceEqual(t, tensor.Size, []int{3})
+		assertIntSliceEqual(t, tensor.Stride, []int{1})
+		if tensor.StorageOffset != tc.offset {
+			t.Errorf("%s: expected offset %d, got %d", tc.key, tc.offset, tensor.StorageOffset)
+		}
+	}
+
+	window, ok := get("window").(*Tensor)
+	if !ok {
+		t.Fatalf("window: expected *Tensor, got %#v", get("window"))
+	}
+	view, ok := window.Source.(*FloatStorage)
+	if !ok || view == fs {
+		t.Fatalf("window: expected a *FloatStorage view, got %#v", window.Source)
+	}
+	assertBaseStorageFields(t, view.BaseStorage, 4, "cpu")
+	assertFloat32SliceEqual(t, view.Data, []float32{3.5, -4.5, 5.5, -6.5}, 0)
+	if &view.Data[0] != &fs.Data[2] {
+		t.Errorf("window: the view doesn't share the memory of its root storage")
+	}
+
+	steps, ok := get("steps").(*LongStorage)
+	if !ok {
+		t.Fatalf("steps: expected *LongStorage, got %#v", get("steps"))
+	}
+	assertBaseStorageFields(t, steps.BaseStorage, 3, "cpu")
+	if len(steps.Data) != 3 || steps.Data[0] != 1 || steps.Data[1] != 2 || steps.Data[2] != 3 {
+		t.Errorf("steps: unexpected data %v", steps.Data)
+	}
+}
+
+func TestLegacyTarMissingMember(t *testing.T) {
+	filename := path.Join(t.TempDir(), "missing.pt")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err = tw.WriteHeader(&tar.Header{Name: "sys_info", Mode: 0o644}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load(filename)
+	if err == nil || !strings.Contains(err.Error(), "storages not found") {
+		t.Errorf("expected missing storages error, got %v", err)
+	}
+}
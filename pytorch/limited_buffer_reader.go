@@ -41,7 +41,7 @@ func (br *LimitedBufferReader) ReadNext() ([]byte, error) {
 		if br.remainingBytes < len(br.buf) {
 			br.buf = br.buf[0:br.remainingBytes]
 		}
-		_, err := br.r.Read(br.buf)
+		_, err := io.ReadFull(br.r, br.buf)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,188 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// Loader loads PyTorch files, with custom settings.
+//
+// The zero value is ready to use, loading files as Load.
+type Loader struct {
+	// NewUnpickler, if not nil, is used to create new customized
+	// pickle.Unpickler instances (see LoadWithUnpickler).
+	NewUnpickler func(r io.Reader) pickle.Unpickler
+	// Concurrency is the maximum number of goroutines reading and decoding
+	// the storages of files in the zip format, after data.pkl is unpickled.
+	// If it is 0, runtime.GOMAXPROCS(0) is used; 1 decodes the storages
+	// sequentially.
+	Concurrency int
+	// RawFloat16, if true, loads half-precision (float16) and bfloat16
+	// storages as RawHalfStorage and RawBFloat16Storage, keeping the bits of
+	// their elements, rather than as HalfStorage and BFloat16Storage, which
+	// widen them to float32.
+	RawFloat16 bool
+}
+
+// Load loads a PyTorch file, like the package function Load.
+func (l *Loader) Load(filename string) (interface{}, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return l.loadReaderAt(f, fi.Size(), nil)
+}
+
+// LoadReaderAt loads the size bytes of a PyTorch file from r, like the
+// package function LoadReaderAt.
+func (l *Loader) LoadReaderAt(r io.ReaderAt, size int64) (interface{}, error) {
+	return l.loadReaderAt(r, size, nil)
+}
+
+// loadReaderAt loads a file of any format. If lazy is not nil, the storages
+// of zip files are loaded lazily (see loadZipFile).
+func (l *Loader) loadReaderAt(r io.ReaderAt, size int64, lazy *lazyArchive) (interface{}, error) {
+	format, err := detectFormat(r, size)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case zipFormat:
+		return l.loadZipFile(r, size, lazy)
+	case legacyTarFormat:
+		return l.loadLegacyTar(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+	default:
+		return l.loadLegacyNoTar(io.NewSectionReader(r, 0, size))
+	}
+}
+
+func (l *Loader) newUnpickler(r io.Reader) pickle.Unpickler {
+	if l.NewUnpickler != nil {
+		return l.NewUnpickler(r)
+	}
+	return pickle.NewUnpickler(r)
+}
+
+// makePickleFindClass is like the package function makePickleFindClass,
+// applying the settings of the Loader.
+func (l *Loader) makePickleFindClass(fallback func(module, name string) (interface{}, error)) func(module, name string) (interface{}, error) {
+	findClass := makePickleFindClass(fallback)
+	if !l.RawFloat16 {
+		return findClass
+	}
+	return func(module, name string) (interface{}, error) {
+		switch module + "." + name {
+		case "torch.HalfStorage":
+			return &RawHalfStorageClass{}, nil
+		case "torch.BFloat16Storage":
+			return &RawBFloat16StorageClass{}, nil
+		}
+		return findClass(module, name)
+	}
+}
+
+// storageArgs is like the package function storageArgs, applying the
+// settings of the Loader to storage types given as a torch.dtype.
+func (l *Loader) storageArgs(args *types.Tuple) (StorageClassInterface, string, string, int, error) {
+	dataType, key, location, size, err := storageArgs(args)
+	if err != nil || !l.RawFloat16 {
+		return dataType, key, location, size, err
+	}
+	switch dataType.(type) {
+	case *HalfStorageClass:
+		dataType = &RawHalfStorageClass{}
+	case *BFloat16StorageClass:
+		dataType = &RawBFloat16StorageClass{}
+	}
+	return dataType, key, location, size, nil
+}
+
+func (l *Loader) concurrency() int {
+	if l.Concurrency > 0 {
+		return l.Concurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// pendingStorage is a storage whose data is still to be read from its zip
+// record.
+type pendingStorage struct {
+	storage StorageInterface
+	size    int
+	file    *zip.File
+}
+
+// decodeStorages reads the data of the storages from their zip records,
+// with up to concurrency goroutines.
+//
+// Errors are deterministic: the error of the first failing storage, in the
+// order of the slice, is returned, as when decoding sequentially. After a
+// failure, the storages following the failing one are skipped.
+func decodeStorages(pending []pendingStorage, concurrency int) error {
+	if concurrency > len(pending) {
+		concurrency = len(pending)
+	}
+	if concurrency <= 1 {
+		for _, p := range pending {
+			if err := setFromRecord(p.storage, p.size, p.file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, len(pending))
+	var mu sync.Mutex
+	next, failed := 0, len(pending)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				next++
+				skip := i >= failed
+				mu.Unlock()
+				if skip {
+					return
+				}
+				p := pending[i]
+				if err := setFromRecord(p.storage, p.size, p.file); err != nil {
+					mu.Lock()
+					errs[i] = err
+					if i < failed {
+						failed = i
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// makeZipCheckpoint returns a file in the zip format, whose data.pkl is a
+// list of FloatStorages, with the given records.
+func makeZipCheckpoint(t *testing.T, records [][]byte, sizes []int) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	type storageRef int
+	storages := make(types.List, len(records))
+	for i := range storages {
+		storages[i] = storageRef(i)
+	}
+	var pkl bytes.Buffer
+	p := pickle.NewPickler(&pkl, 2)
+	p.PersistentID = func(v interface{}) (interface{}, error) {
+		i, ok := v.(storageRef)
+		if !ok {
+			return nil, nil
+		}
+		return types.NewTupleFromSlice([]interface{}{
+			"storage", types.NewGenericClass("torch", "FloatStorage"),
+			strconv.Itoa(int(i)), "cpu", sizes[i],
+		}), nil
+	}
+	if err := p.Dump(&storages); err != nil {
+		t.Fatal(err)
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "archive/data.pkl", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(pkl.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	for i, record := range records {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: "archive/data/" + strconv.Itoa(i), Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func float32Record(values ...float32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+func TestLoaderConcurrency(t *testing.T) {
+	const n = 100
+	records := make([][]byte, n)
+	sizes := make([]int, n)
+	for i := range records {
+		records[i] = float32Record(float32(i), float32(-i), 0.5)
+		sizes[i] = 3
+	}
+	data := makeZipCheckpoint(t, records, sizes)
+
+	for _, concurrency := range []int{0, 1, 4, 16, 200} {
+		l := &Loader{Concurrency: concurrency}
+		result, err := l.LoadReaderAt(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("concurrency %d: %v", concurrency, err)
+		}
+		list := result.(*types.List)
+		if list.Len() != n {
+			t.Fatalf("concurrency %d: expected %d storages, got %d", concurrency, n, list.Len())
+		}
+		for i := 0; i < n; i++ {
+			fs := list.Get(i).(*FloatStorage)
+			assertFloat32SliceEqual(t, fs.Data, []float32{float32(i), float32(-i), 0.5}, 0)
+		}
+	}
+}
+
+func TestLoaderConcurrencyDeterministicError(t *testing.T) {
+	const n = 64
+	records := make([][]byte, n)
+	sizes := make([]int, n)
+	for i := range records {
+		records[i] = float32Record(1, 2)
+		sizes[i] = 2
+	}
+	// the records of storages 20 and 40 are too short for their size, but
+	// not for the size check done while unpickling
+	records[20], records[40] = records[20][:5], records[40][:5]
+	data := makeZipCheckpoint(t, records, sizes)
+
+	for _, concurrency := range []int{1, 3, 8, 64} {
+		for attempt := 0; attempt < 10; attempt++ {
+			l := &Loader{Concurrency: concurrency}
+			_, err := l.LoadReaderAt(bytes.NewReader(data), int64(len(data)))
+			if err == nil || !strings.HasPrefix(err.Error(), "archive/data/20:") {
+				t.Fatalf("concurrency %d: expected error of storage 20, got %v", concurrency, err)
+			}
+		}
+	}
+}
+
+func TestLoaderRawFloat16(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		toBits   func(uint32) uint16
+		expected func(StorageInterface) []float32
+	}{
+		{"tensor_float16", FloatBits32to16, func(s StorageInterface) []float32 { return s.(*HalfStorage).Data }},
+		{"tensor_bfloat16", BFloatBits32to16, func(s StorageInterface) []float32 { return s.(*BFloat16Storage).Data }},
+	}
+	for _, tc := range testCases {
+		for _, filename := range makeFilenames(tc.prefix) {
+			t.Run(filename, func(t *testing.T) {
+				widened := loadTensorFromFile(t, filename)
+				expected := tc.expected(widened.Source)
+
+				result, err := (&Loader{RawFloat16: true}).Load(path.Join("testdata", filename))
+				if err != nil {
+					t.Fatal(err)
+				}
+				tensor, ok := result.(*Tensor)
+				if !ok {
+					t.Fatalf("expected *Tensor, got %#v", result)
+				}
+
+				var bits []uint16
+				var toFloat32 func([]float32) int
+				var float32At func(int) float32
+				switch s := tensor.Source.(type) {
+				case *RawHalfStorage:
+					assertBaseStorageFields(t, s.BaseStorage, 4, "cpu")
+					bits, toFloat32, float32At = s.Data, s.ToFloat32, s.Float32At
+				case *RawBFloat16Storage:
+					assertBaseStorageFields(t, s.BaseStorage, 4, "cpu")
+					bits, toFloat32, float32At = s.Data, s.ToFloat32, s.Float32At
+				default:
+					t.Fatalf("unexpected storage %#v", tensor.Source)
+				}
+				if len(bits) != len(expected) {
+					t.Fatalf("expected %d elements, actual %d", len(expected), len(bits))
+				}
+				for i, v := range expected {
+					if b := tc.toBits(math.Float32bits(v)); bits[i] != b {
+						t.Errorf("element %d: expected bits %#04x, actual %#04x", i, b, bits[i])
+					}
+					if actual := float32At(i); actual != v {
+						t.Errorf("Float32At(%d): expected %v, actual %v", i, v, actual)
+					}
+				}
+
+				dst := make([]float32, len(expected)+1)
+				if n := toFloat32(dst); n != len(expected) {
+					t.Errorf("ToFloat32: expected %d, actual %d", len(expected), n)
+				}
+				assertFloat32SliceEqual(t, dst[:len(expected)], expected, 0)
+				if n := toFloat32(dst[:2]); n != 2 {
+					t.Errorf("ToFloat32: expected 2, actual %d", n)
+				}
+			})
+		}
+	}
+}
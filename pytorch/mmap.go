@@ -0,0 +1,168 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"unsafe"
+
+	"github.com/nlpodyssey/gopickle/internal/mmap"
+)
+
+// LoadMmap is like Load, memory-mapping the file on Linux, so that large
+// files are loaded with little copying.
+//
+// The storages of files in the zip format, whose records are not
+// compressed (as written by "torch.save"), alias the mapped file data when
+// their elements are stored as in memory: this is the case for the
+// FloatStorage, DoubleStorage, CharStorage, ShortStorage, IntStorage,
+// LongStorage and ByteStorage types, for the unsigned integer, complex,
+// 8-bit floating point, quantized, bits and untyped types, and for the
+// RawHalfStorage and RawBFloat16Storage types of Loader.RawFloat16, on
+// little-endian machines. Other storages are copied as by Load.
+//
+// Aliased storage data must not be used after the returned Closer is
+// closed. Modifications to it are never written back to the file. The
+// CRC-32 checksums of aliased records are not verified.
+func LoadMmap(filename string) (interface{}, io.Closer, error) {
+	return (&Loader{}).LoadMmap(filename)
+}
+
+// LoadMmap loads a memory-mapped PyTorch file, like the package function
+// LoadMmap.
+func (l *Loader) LoadMmap(filename string) (interface{}, io.Closer, error) {
+	m, err := mmap.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := m.Bytes()
+	r := &mappedReader{Reader: bytes.NewReader(data), data: data}
+	result, err := l.loadReaderAt(r, int64(len(data)), nil)
+	if err != nil {
+		_ = m.Close()
+		return nil, nil, err
+	}
+	return result, m, nil
+}
+
+// mappedReader reads a memory-mapped file, whose data can be aliased by the
+// storages of zip records (see loadZipFile).
+type mappedReader struct {
+	*bytes.Reader
+	data []byte
+}
+
+// aliasRecord makes the storage alias the data of a zip record of a
+// memory-mapped file, if possible (see aliasStorage). It reports whether the
+// data has been set.
+func aliasRecord(storage StorageInterface, size int, file *zip.File, data []byte) bool {
+	if file.Method != zip.Store {
+		return false
+	}
+	offset, err := file.DataOffset()
+	if err != nil || offset < 0 || uint64(offset) > uint64(len(data)) ||
+		file.UncompressedSize64 > uint64(len(data))-uint64(offset) {
+		return false
+	}
+	record := data[offset : uint64(offset)+file.UncompressedSize64]
+	return aliasStorage(storage, record, size)
+}
+
+// isLittleEndian reports whether the machine is little-endian, as the data
+// of PyTorch files.
+var isLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// aliasStorage sets the data of the storage to the first size elements of
+// b, without copying, if the storage type holds elements as they are
+// stored, and b is large enough and suitably aligned. It reports whether
+// the data has been set.
+func aliasStorage(storage StorageInterface, b []byte, size int) bool {
+	// Complex numbers are aligned as their parts.
+	var elementSize, alignment int
+	switch storage.(type) {
+	case *CharStorage, *ByteStorage, *UntypedStorage, *QInt8Storage, *QUInt8Storage,
+		*Bits1x8Storage, *Bits2x4Storage, *Bits4x2Storage, *Bits8Storage,
+		*Float8E4M3FNStorage, *Float8E5M2Storage:
+		elementSize, alignment = 1, 1
+	case *ShortStorage, *UInt16Storage, *Bits16Storage, *RawHalfStorage, *RawBFloat16Storage:
+		elementSize, alignment = 2, 2
+	case *FloatStorage, *IntStorage, *UInt32Storage, *QInt32Storage:
+		elementSize, alignment = 4, 4
+	case *DoubleStorage, *LongStorage, *UInt64Storage:
+		elementSize, alignment = 8, 8
+	case *ComplexFloatStorage:
+		elementSize, alignment = 8, 4
+	case *ComplexDoubleStorage:
+		elementSize, alignment = 16, 8
+	default:
+		return false
+	}
+	if !isLittleEndian || size == 0 || size > len(b)/elementSize {
+		return false
+	}
+	p := unsafe.Pointer(&b[0])
+	if uintptr(p)%uintptr(alignment) != 0 {
+		return false
+	}
+
+	switch s := storage.(type) {
+	case *CharStorage:
+		s.Data = unsafe.Slice((*int8)(p), size)
+	case *ByteStorage:
+		s.Data = b[:size:size]
+	case *ShortStorage:
+		s.Data = unsafe.Slice((*int16)(p), size)
+	case *FloatStorage:
+		s.Data = unsafe.Slice((*float32)(p), size)
+	case *IntStorage:
+		s.Data = unsafe.Slice((*int32)(p), size)
+	case *DoubleStorage:
+		s.Data = unsafe.Slice((*float64)(p), size)
+	case *LongStorage:
+		s.Data = unsafe.Slice((*int64)(p), size)
+	case *UInt16Storage:
+		s.Data = unsafe.Slice((*uint16)(p), size)
+	case *UInt32Storage:
+		s.Data = unsafe.Slice((*uint32)(p), size)
+	case *UInt64Storage:
+		s.Data = unsafe.Slice((*uint64)(p), size)
+	case *ComplexFloatStorage:
+		s.Data = unsafe.Slice((*complex64)(p), size)
+	case *ComplexDoubleStorage:
+		s.Data = unsafe.Slice((*complex128)(p), size)
+	case *QInt8Storage:
+		s.Data = unsafe.Slice((*int8)(p), size)
+	case *UntypedStorage:
+		s.Data = b[:size:size]
+	case *QUInt8Storage:
+		s.Data = b[:size:size]
+	case *QInt32Storage:
+		s.Data = unsafe.Slice((*int32)(p), size)
+	case *Bits1x8Storage:
+		s.Data = b[:size:size]
+	case *Bits2x4Storage:
+		s.Data = b[:size:size]
+	case *Bits4x2Storage:
+		s.Data = b[:size:size]
+	case *Bits8Storage:
+		s.Data = b[:size:size]
+	case *Bits16Storage:
+		s.Data = unsafe.Slice((*uint16)(p), size)
+	case *Float8E4M3FNStorage:
+		s.Data = b[:size:size]
+	case *Float8E5M2Storage:
+		s.Data = b[:size:size]
+	case *RawHalfStorage:
+		s.Data = unsafe.Slice((*uint16)(p), size)
+	case *RawBFloat16Storage:
+		s.Data = unsafe.Slice((*uint16)(p), size)
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestLoadMmap(t *testing.T) {
+	filenames, err := filepath.Glob(path.Join("testdata", "tensor_*.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range filenames {
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			expected, err := Load(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, closer, err := LoadMmap(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closer.Close()
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected %#v, actual %#v", expected, actual)
+			}
+		})
+	}
+
+	_, closer, err := LoadMmap(path.Join(t.TempDir(), "missing.pt"))
+	if !os.IsNotExist(err) || closer != nil {
+		t.Errorf("expected not exist error and nil closer, got %v, %v", err, closer)
+	}
+}
+
+func TestLoadMappedStorageAliasing(t *testing.T) {
+	for _, tc := range []struct {
+		filename string
+		aliased  bool
+	}{
+		{"tensor_float32_proto2_zip.pt", true},
+		{"tensor_int64_proto5_zip.pt", true},
+		{"tensor_uint8_proto3_zip.pt", true},
+		{"tensor_qint8_zip.pt", true},
+		{"tensor_float16_proto2_zip.pt", false}, // decoded to float32
+		{"tensor_float32_proto2.pt", false},     // legacy format
+		{"tensor_float8_e4m3fn_v3_zip.pt", true},
+	} {
+		data, err := os.ReadFile(path.Join("testdata", tc.filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &mappedReader{Reader: bytes.NewReader(data), data: data}
+		result, err := (&Loader{}).loadReaderAt(r, int64(len(data)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tensor := result.(*Tensor)
+		var p unsafe.Pointer
+		switch s := tensor.Source.(type) {
+		case *FloatStorage:
+			p = unsafe.Pointer(&s.Data[0])
+		case *LongStorage:
+			p = unsafe.Pointer(&s.Data[0])
+		case *ByteStorage:
+			p = unsafe.Pointer(&s.Data[0])
+		case *HalfStorage:
+			p = unsafe.Pointer(&s.Data[0])
+		case *QInt8Storage:
+			p = unsafe.Pointer(&s.Data[0])
+		case *UntypedStorage:
+			p = unsafe.Pointer(&s.Data[0])
+		}
+		start := uintptr(unsafe.Pointer(&data[0]))
+		aliased := uintptr(p) >= start && uintptr(p) < start+uintptr(len(data))
+		if aliased != tc.aliased {
+			t.Errorf("%s: expected aliased %v, actual %v", tc.filename, tc.aliased, aliased)
+		}
+	}
+}
+
+func TestAliasStorageAlignment(t *testing.T) {
+	b := make([]byte, 24)
+	aligned := 0
+	for uintptr(unsafe.Pointer(&b[aligned]))%4 != 0 {
+		aligned++
+	}
+	if aliasStorage(&FloatStorage{}, b[aligned+1:], 4) {
+		t.Errorf("expected unaligned data not to be aliased")
+	}
+	if aliasStorage(&FloatStorage{}, b[aligned:aligned+15], 4) {
+		t.Errorf("expected data too short not to be aliased")
+	}
+	s := &FloatStorage{}
+	if !aliasStorage(s, b[aligned:], 3) || len(s.Data) != 3 || cap(s.Data) != 3 {
+		t.Errorf("expected aligned data to be aliased, got %v", s.Data)
+	}
+	if aliasStorage(&HalfStorage{}, b[aligned:], 3) {
+		t.Errorf("expected half-precision data not to be aliased")
+	}
+	raw := &RawHalfStorage{}
+	if !aliasStorage(raw, b[aligned:], 3) || len(raw.Data) != 3 {
+		t.Errorf("expected raw half-precision data to be aliased, got %v", raw.Data)
+	}
+	float8 := &Float8E5M2Storage{}
+	if !aliasStorage(float8, b[aligned+1:], 3) || len(float8.Data) != 3 {
+		t.Errorf("expected float8 data to be aliased, got %v", float8.Data)
+	}
+}
+
+func TestLoaderLoadMmap(t *testing.T) {
+	loader := &Loader{RawFloat16: true}
+	for _, filename := range []string{"tensor_float16_proto2_zip.pt", "tensor_bfloat16_proto2_zip.pt"} {
+		filename = path.Join("testdata", filename)
+		expected, err := loader.Load(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, closer, err := loader.LoadMmap(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: expected %#v, actual %#v", filename, expected, actual)
+		}
+		closer.Close()
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &mappedReader{Reader: bytes.NewReader(data), data: data}
+		result, err := loader.loadReaderAt(r, int64(len(data)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p unsafe.Pointer
+		switch s := result.(*Tensor).Source.(type) {
+		case *RawHalfStorage:
+			p = unsafe.Pointer(&s.Data[0])
+		case *RawBFloat16Storage:
+			p = unsafe.Pointer(&s.Data[0])
+		}
+		start := uintptr(unsafe.Pointer(&data[0]))
+		if aliased := uintptr(p) >= start && uintptr(p) < start+uintptr(len(data)); !aliased {
+			t.Errorf("%s: expected the storage data to be aliased", filename)
+		}
+	}
+}
@@ -5,13 +5,13 @@
 package pytorch
 
 import (
-	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math/big"
-	"os"
 	"path"
 
 	"github.com/nlpodyssey/gopickle/pickle"
@@ -25,37 +25,121 @@ var ErrInvalidMagicNumber = errors.New("invalid pytorch magic number")
 var ErrInvalidProtocolVersion = errors.New("invalid pytorch protocol version")
 
 func Load(filename string) (interface{}, error) {
-	newUnpickler := func(r io.Reader) pickle.Unpickler {
-		return pickle.NewUnpickler(r)
-	}
-	return LoadWithUnpickler(filename, newUnpickler)
+	return (&Loader{}).Load(filename)
 }
 
 // LoadWithUnpickler is like Load, but it accepts a newUnpickler function which
 // is used to create new customized pickle.Unpickler instances.
 func LoadWithUnpickler(filename string, newUnpickler func(r io.Reader) pickle.Unpickler) (interface{}, error) {
-	if !isZipFile(filename) {
-		return loadLegacyFile(filename, newUnpickler)
+	return (&Loader{NewUnpickler: newUnpickler}).Load(filename)
+}
+
+// LoadReaderAt is like Load, reading the size bytes of a file from r, such
+// as an object in a remote storage.
+func LoadReaderAt(r io.ReaderAt, size int64) (interface{}, error) {
+	return (&Loader{}).LoadReaderAt(r, size)
+}
+
+// LoadBytes is like Load, reading a file from memory.
+func LoadBytes(b []byte) (interface{}, error) {
+	return LoadReaderAt(bytes.NewReader(b), int64(len(b)))
+}
+
+// LoadFS is like Load, reading the named file from fsys, such as an
+// embed.FS. If the file implements io.ReaderAt, it is read in place;
+// otherwise, it is read into memory first.
+func LoadFS(fsys fs.FS, name string) (interface{}, error) {
+	var result interface{}
+	err := readFS(fsys, name, func(r io.ReaderAt, size int64) (err error) {
+		result, err = LoadReaderAt(r, size)
+		return err
+	})
+	return result, err
+}
+
+// readFS calls read with the named file of fsys, in place if it implements
+// io.ReaderAt, or else after reading it into memory.
+func readFS(fsys fs.FS, name string, read func(r io.ReaderAt, size int64) error) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if ra, ok := f.(io.ReaderAt); ok {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return read(ra, fi.Size())
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return read(bytes.NewReader(b), int64(len(b)))
+}
+
+// fileFormat is a format of PyTorch files.
+type fileFormat int
+
+const (
+	// legacyFormat is the format of PyTorch from version 0.1.10 to 1.5:
+	// a sequence of pickles followed by the data of the storages.
+	legacyFormat fileFormat = iota
+	// legacyTarFormat is the tar format of PyTorch before version 0.1.10.
+	legacyTarFormat
+	// zipFormat is the zip format of PyTorch from version 1.6.
+	zipFormat
+)
+
+// detectFormat detects the format of a PyTorch file from its first bytes:
+// the signature of a zip local file header (or of the end of an empty
+// archive), or the "ustar" magic of a tar header. Other files are assumed
+// to be in the legacy format, which is verified when loading.
+func detectFormat(r io.ReaderAt, size int64) (fileFormat, error) {
+	var header [512]byte
+	n := len(header)
+	if size < int64(n) {
+		n = int(size)
+	}
+	if _, err := r.ReadAt(header[:n], 0); err != nil && err != io.EOF {
+		return 0, err
+	}
+	switch {
+	case n >= 4 && (string(header[:4]) == "PK\x03\x04" || string(header[:4]) == "PK\x05\x06"):
+		return zipFormat, nil
+	case n >= 262 && string(header[257:262]) == "ustar":
+		return legacyTarFormat, nil
+	default:
+		return legacyFormat, nil
 	}
-	return loadZipFile(filename, newUnpickler)
 }
 
-func loadZipFile(filename string, newUnpickler func(r io.Reader) pickle.Unpickler) (interface{}, error) {
-	// Open a zip archive for reading.
-	r, err := zip.OpenReader(filename)
+// loadZipFile loads a file in the zip format. If lazy is not nil, the
+// storages are not read, but added to it as LazyStorage placeholders.
+// If ra is a memory-mapped file, the storages alias its data, if possible
+// (see LoadMmap).
+//
+// The storages are created while unpickling data.pkl, but their data is
+// read afterwards, by up to l.Concurrency goroutines.
+func (l *Loader) loadZipFile(ra io.ReaderAt, size int64, lazy *lazyArchive) (interface{}, error) {
+	r, err := zip.NewReader(ra, size)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
 
 	fileRecords := make(map[string]*zip.File, len(r.File))
 	for _, f := range r.File {
+		if err = checkRecordSize(f, size); err != nil {
+			return nil, err
+		}
 		_, recordName := path.Split(f.Name)
 		fileRecords[recordName] = f
 	}
 
 	if _, isTorchScript := fileRecords["constants.pkl"]; isTorchScript {
-		return nil, fmt.Errorf("TorchScript is not supported")
+		return nil, fmt.Errorf("TorchScript archives must be loaded with LoadScript")
 	}
 
 	dataFile, hasDataFile := fileRecords["data.pkl"]
@@ -69,94 +153,111 @@ func loadZipFile(filename string, newUnpickler func(r io.Reader) pickle.Unpickle
 	defer df.Close()
 
 	loadedStorages := make(map[string]StorageInterface)
+	var pending []pendingStorage
+	mapped, _ := ra.(*mappedReader)
 
-	u := newUnpickler(df)
-	u.FindClass = makePickleFindClass(u.FindClass)
-	u.PersistentLoad = func(savedId interface{}) (interface{}, error) {
-		tuple, tupleOk := savedId.(*types.Tuple)
-		if !tupleOk || tuple.Len() == 0 {
-			return nil, fmt.Errorf("PersistentLoad: non-empty tuple expected, got %#v", savedId)
-		}
-		typename, typenameOk := tuple.Get(0).(string)
-		if !typenameOk {
-			return nil, fmt.Errorf("PersistentLoad: cannot get typename")
-		}
-		if typename != "storage" {
-			return nil, fmt.Errorf("unknown typename for PersistentLoad, expected 'storage' but got '%s'", typename)
-		}
-		if tuple.Len() < 5 {
-			return nil, fmt.Errorf("PersistentLoad: unexpected storage data length")
-		}
-		dataType, dataTypeOk := tuple.Get(1).(StorageClassInterface)
-		key, keyOk := tuple.Get(2).(string)
-		location, locationOk := tuple.Get(3).(string)
-		size, sizeOk := tuple.Get(4).(int)
-		if !dataTypeOk || !keyOk || !locationOk || !sizeOk {
-			return nil, fmt.Errorf("PersistentLoad: unexpected data types")
+	persistent := pickle.NewPersistentRegistry()
+	persistent.RegisterFunc("storage", func(id pickle.PersistentID) (interface{}, error) {
+		dataType, key, location, size, err := l.storageArgs(id.Args())
+		if err != nil {
+			return nil, err
 		}
 		storage, storageExists := loadedStorages[key]
-		if !storageExists {
-			storage, err = loadTensor(dataType, size, location, key, fileRecords)
+		if storageExists {
+			return storage, nil
+		}
+		if lazy != nil {
+			storage, err = lazy.newStorage(dataType, size, location, key, fileRecords)
 			if err != nil {
 				return nil, err
 			}
-			loadedStorages[key] = storage
+		} else {
+			file, err := storageRecord(size, key, fileRecords)
+			if err != nil {
+				return nil, err
+			}
+			storage = dataType.New(size, location)
+			if mapped == nil || !aliasRecord(storage, size, file, mapped.data) {
+				pending = append(pending, pendingStorage{storage: storage, size: size, file: file})
+			}
 		}
+		loadedStorages[key] = storage
 		return storage, nil
+	})
+
+	u := l.newUnpickler(df)
+	u.FindClass = l.makePickleFindClass(u.FindClass)
+	setPersistentRegistry(&u, persistent)
+	result, err := u.Load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dataFile.Name, err)
 	}
-	return u.Load()
+	if err = decodeStorages(pending, l.concurrency()); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func loadTensor(
-	dataType StorageClassInterface,
-	size int,
-	location, key string,
-	zipFileRecords map[string]*zip.File,
-) (StorageInterface, error) {
+// storageRecord returns the zip record of the storage with the given key,
+// checking that it can hold size elements.
+func storageRecord(size int, key string, zipFileRecords map[string]*zip.File) (*zip.File, error) {
 	file, fileOk := zipFileRecords[key]
 	if !fileOk {
 		return nil, fmt.Errorf("cannot find zip record '%s'", key)
 	}
-	f, err := file.Open()
-	if err != nil {
-		return nil, err
+	// record sizes are checked in loadZipFile
+	if err := checkStorageSize(uint64(size), file.UncompressedSize64); err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
 	}
-	defer f.Close()
+	return file, nil
+}
 
+// readStorageRecord reads a storage of size elements from a zip record.
+func readStorageRecord(dataType StorageClassInterface, size int, location string, file *zip.File) (StorageInterface, error) {
 	storage := dataType.New(size, location)
-	err = storage.SetFromFileWithSize(f, size)
-	return storage, err
+	if err := setFromRecord(storage, size, file); err != nil {
+		return nil, err
+	}
+	return storage, nil
 }
 
-func loadLegacyFile(filename string, newUnpickler func(r io.Reader) pickle.Unpickler) (interface{}, error) {
-	f, err := os.Open(filename)
+// setFromRecord reads the size elements of a storage from a zip record.
+func setFromRecord(storage StorageInterface, size int, file *zip.File) error {
+	f, err := file.Open()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer f.Close()
 
-	tr := tar.NewReader(f)
-	for {
-		_, err := tr.Next()
-		switch err {
-		case nil:
-			// TODO: ...
-			panic("legacy load from tar not implemented")
-		case io.EOF:
-			break // End of archive
-		case tar.ErrHeader, io.ErrUnexpectedEOF:
-			_, err = f.Seek(0, io.SeekStart)
-			if err != nil {
-				return nil, err
-			}
-			return loadLegacyNoTar(f, newUnpickler)
-		default:
-			return nil, err
-		}
+	if err = storage.SetFromFileWithSize(f, size); err != nil {
+		return fmt.Errorf("%s: %w", file.Name, err)
 	}
+	return nil
+}
+
+// maxDeflateRatio is the maximum compression ratio achievable with the
+// deflate algorithm.
+const maxDeflateRatio = 1032
+
+// checkRecordSize verifies that the sizes declared by the header of a zip
+// record are plausible, given the size of the whole archive.
+func checkRecordSize(f *zip.File, archiveSize int64) error {
+	if f.CompressedSize64 > uint64(archiveSize) {
+		return fmt.Errorf("%s: compressed size %d exceeds archive size %d",
+			f.Name, f.CompressedSize64, archiveSize)
+	}
+	maxSize := f.CompressedSize64
+	if f.Method != zip.Store {
+		maxSize *= maxDeflateRatio
+	}
+	if f.UncompressedSize64 > maxSize {
+		return fmt.Errorf("%s: implausible uncompressed size %d",
+			f.Name, f.UncompressedSize64)
+	}
+	return nil
 }
 
-func loadLegacyNoTar(f *os.File, newUnpickler func(r io.Reader) pickle.Unpickler) (interface{}, error) {
+func (l *Loader) loadLegacyNoTar(f *io.SectionReader) (interface{}, error) {
 	if err := readAndCheckMagicNumber(f); err != nil {
 		return nil, err
 	}
@@ -168,64 +269,71 @@ func loadLegacyNoTar(f *os.File, newUnpickler func(r io.Reader) pickle.Unpickler
 	}
 
 	deserializedObjects := make(map[string]StorageInterface)
+	var views []*legacyStorageView
 
-	u := newUnpickler(f)
-	u.FindClass = makePickleFindClass(u.FindClass)
-	u.PersistentLoad = func(savedId interface{}) (interface{}, error) {
-		tuple, tupleOk := savedId.(*types.Tuple)
-		if !tupleOk || tuple.Len() == 0 {
-			return nil, fmt.Errorf("PersistentLoad: non-empty tuple expected, got %#v", savedId)
+	persistent := pickle.NewPersistentRegistry()
+	persistent.RegisterFunc("storage", func(id pickle.PersistentID) (interface{}, error) {
+		args := id.Args()
+		if args.Len() < 5 {
+			return nil, fmt.Errorf("unexpected storage data length")
 		}
-		typename, typenameOk := tuple.Get(0).(string)
-		if !typenameOk {
-			return nil, fmt.Errorf("PersistentLoad: cannot get typename")
+		dataType, rootKey, location, size, err := l.storageArgs(args)
+		if err != nil {
+			return nil, err
 		}
-
-		switch typename {
-		case "storage":
-			if tuple.Len() < 6 {
-				return nil, fmt.Errorf(
-					"PersistentLoad: unexpected storage data length")
+		viewMetadata := args.Get(4)
+		storage, storageExists := deserializedObjects[rootKey]
+		if !storageExists {
+			storage = dataType.New(size, location)
+			deserializedObjects[rootKey] = storage
+		}
+		switch vm := viewMetadata.(type) {
+		case nil:
+			return storage, nil
+		case *types.Tuple:
+			// (view_key, offset, view_size)
+			if vm.Len() != 3 {
+				return nil, fmt.Errorf("unexpected view metadata length")
+			}
+			viewKey, err := vm.GetString(0)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage view key: %w", err)
 			}
-			dataType, dataTypeOk := tuple.Get(1).(StorageClassInterface)
-			rootKey, rootKeyOk := tuple.Get(2).(string)
-			location, locationOk := tuple.Get(3).(string)
-			size, sizeOk := tuple.Get(4).(int)
-			viewMetadata := tuple.Get(5)
-			if !dataTypeOk || !rootKeyOk || !locationOk || !sizeOk {
-				return nil, fmt.Errorf("PersistentLoad: unexpected data types")
+			if view, ok := deserializedObjects[viewKey]; ok {
+				return view, nil
 			}
-			storage, storageExists := deserializedObjects[rootKey]
-			if !storageExists {
-				storage = dataType.New(size, location)
-				deserializedObjects[rootKey] = storage
+			offset, err := vm.GetInt(1)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage view offset: %w", err)
 			}
-			switch vm := viewMetadata.(type) {
-			case nil:
-				return storage, nil
-			case []interface{}:
-				if len(vm) != 3 {
-					return nil, fmt.Errorf(
-						"PersistentLoad: unexpected view metadata length")
-				}
-				panic("viewMetadata not implemented")
-				// TODO: ...
-				// view_key, offset, view_size = view_metadata
-				// if view_key not in deserialized_objects:
-				//     deserialized_objects[view_key] = storage[offset:offset + view_size]
-				// return deserialized_objects[view_key]
-			default:
-				return nil, fmt.Errorf("PersistentLoad: unexpected view metadata type")
+			viewSize, err := vm.GetInt(2)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage view size: %w", err)
 			}
-		case "module":
-			if tuple.Len() < 2 {
-				return nil, fmt.Errorf("PersistentLoad: unexpected module data length")
+			if err = checkStorageView(size, offset, viewSize); err != nil {
+				return nil, err
 			}
-			return tuple.Get(1), nil
+			// The data of the root storage is read after the pickle: the
+			// view is set up afterwards.
+			view := dataType.New(viewSize, location)
+			deserializedObjects[viewKey] = view
+			views = append(views, &legacyStorageView{view: view, root: storage, offset: offset, size: viewSize})
+			return view, nil
 		default:
-			return nil, fmt.Errorf("Unexpected saved ID type: %s", typename)
+			return nil, fmt.Errorf("unexpected view metadata type")
 		}
-	}
+	})
+	persistent.RegisterFunc("module", func(id pickle.PersistentID) (interface{}, error) {
+		args := id.Args()
+		if args.Len() < 1 {
+			return nil, fmt.Errorf("unexpected module data length")
+		}
+		return args.Get(0), nil
+	})
+
+	u := l.newUnpickler(f)
+	u.FindClass = l.makePickleFindClass(u.FindClass)
+	setPersistentRegistry(&u, persistent)
 	result, err := u.Load()
 	if err != nil {
 		return nil, err
@@ -251,19 +359,85 @@ func loadLegacyNoTar(f *os.File, newUnpickler func(r io.Reader) pickle.Unpickler
 		}
 	}
 
+	for _, v := range views {
+		if err = v.set(); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
+// legacyStorageView is a storage view of a legacy file, which can be set up
+// only when the data of its root storage has been read.
+type legacyStorageView struct {
+	view   StorageInterface
+	root   StorageInterface
+	offset int
+	size   int
+}
+
+func (v *legacyStorageView) set() error {
+	view, ok := v.view.(StorageViewInterface)
+	if !ok {
+		return fmt.Errorf("storage views of type %T are not supported", v.view)
+	}
+	return view.SetFromStorage(v.root, v.offset, v.size)
+}
+
+// setPersistentRegistry makes u resolve persistent IDs with the given
+// registry. A PersistentResolver set by the user (through the newUnpickler
+// function of LoadWithUnpickler) resolves the IDs of other typenames.
+func setPersistentRegistry(u *pickle.Unpickler, persistent *pickle.PersistentRegistry) {
+	persistent.Default = u.PersistentResolver
+	u.PersistentLoad = nil
+	u.PersistentResolver = persistent
+}
+
+// storageArgs returns the storage type, key, location and size from the
+// arguments of the persistent ID of a storage: ('storage', storage_type,
+// key, location, size, ...). The storage type is a storage class, such as
+// torch.FloatStorage or torch.UntypedStorage (whose size is in bytes), or a
+// torch.dtype, such as torch.float32.
+func storageArgs(args *types.Tuple) (dataType StorageClassInterface, key, location string, size int, err error) {
+	if args.Len() < 4 {
+		return nil, "", "", 0, fmt.Errorf("unexpected storage data length")
+	}
+	switch v := args.Get(0).(type) {
+	case StorageClassInterface:
+		dataType = v
+	case DType:
+		if dataType, err = v.StorageClass(); err != nil {
+			return nil, "", "", 0, err
+		}
+	default:
+		return nil, "", "", 0, fmt.Errorf("unexpected storage type %#v", args.Get(0))
+	}
+	if key, err = args.GetString(1); err != nil {
+		return nil, "", "", 0, fmt.Errorf("invalid storage key: %w", err)
+	}
+	if location, err = args.GetString(2); err != nil {
+		return nil, "", "", 0, fmt.Errorf("invalid storage location: %w", err)
+	}
+	if size, err = args.GetInt(3); err != nil {
+		return nil, "", "", 0, fmt.Errorf("invalid storage size: %w", err)
+	}
+	if size < 0 {
+		return nil, "", "", 0, fmt.Errorf("negative storage size %d", size)
+	}
+	return dataType, key, location, size, nil
+}
+
 func makeStorageKeys(obj interface{}) ([]string, error) {
 	list, ok := obj.(*types.List)
 	if !ok {
 		return nil, fmt.Errorf("invalid storage keys data")
 	}
-	keys := make([]string, len(*list))
-	for i, rawKey := range *list {
-		key, keyOk := rawKey.(string)
-		if !keyOk {
-			return nil, fmt.Errorf("invalid storage key")
+	keys := make([]string, list.Len())
+	for i := range keys {
+		key, err := list.GetString(i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage key: %w", err)
 		}
 		keys[i] = key
 	}
@@ -297,18 +471,11 @@ func unpickle(r io.Reader) (interface{}, error) {
 	return u.Load()
 }
 
-func isZipFile(filename string) bool {
-	r, err := zip.OpenReader(filename)
-	if err != nil {
-		return false
-	}
-	r.Close()
-	return true
-}
-
 func makePickleFindClass(fallback func(module, name string) (interface{}, error)) func(module, name string) (interface{}, error) {
 	return func(module, name string) (interface{}, error) {
 		switch module + "." + name {
+		case "torch._utils._rebuild_tensor":
+			return &RebuildTensor{}, nil
 		case "torch._utils._rebuild_tensor_v2":
 			return &RebuildTensorV2{}, nil
 		case "torch.FloatStorage":
@@ -331,10 +498,32 @@ func makePickleFindClass(fallback func(module, name string) (interface{}, error)
 			return &BoolStorageClass{}, nil
 		case "torch.BFloat16Storage":
 			return &BFloat16StorageClass{}, nil
+		case "torch.ComplexFloatStorage":
+			return &ComplexFloatStorageClass{}, nil
+		case "torch.ComplexDoubleStorage":
+			return &ComplexDoubleStorageClass{}, nil
+		case "torch.QInt8Storage":
+			return &QInt8StorageClass{}, nil
+		case "torch.QUInt8Storage":
+			return &QUInt8StorageClass{}, nil
+		case "torch.QInt32Storage":
+			return &QInt32StorageClass{}, nil
+		case "torch._utils._rebuild_tensor_v3":
+			return &RebuildTensorV3{}, nil
+		case "torch._utils._rebuild_qtensor":
+			return &RebuildQTensor{}, nil
+		case "torch.UntypedStorage", "torch.storage.UntypedStorage":
+			return &UntypedStorageClass{}, nil
 		case "torch.nn.backends.thnn._get_thnn_function_backend":
 			// this is for historical pickle deserilaization, it is not used otherwise
 			return getThnnFunctionBackend{}, nil
 		default:
+			if qscheme, ok := qschemes[module+"."+name]; ok {
+				return qscheme, nil
+			}
+			if _, ok := dtypes[DType(name)]; ok && module == "torch" {
+				return DType(name), nil
+			}
 			if fallback == nil {
 				return nil, fmt.Errorf("class not found: %s %s", module, name)
 			}
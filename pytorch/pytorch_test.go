@@ -5,9 +5,22 @@
 package pytorch
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
 	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
 )
 
 func TestFloat16Tensors(t *testing.T) { // Half
@@ -324,3 +337,189 @@ func assertBaseStorageFields(t *testing.T, bs BaseStorage, size int, location st
 		t.Errorf("expected storage Location %#v, got %#v", location, bs.Location)
 	}
 }
+
+func TestLoadErrorWrapsRecordName(t *testing.T) {
+	filename := path.Join(t.TempDir(), "truncated.pt")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("archive/data.pkl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("\x80\x02K\x01")); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load(filename)
+	if !errors.Is(err, pickle.ErrTruncated) {
+		t.Fatalf("expected pickle.ErrTruncated, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "archive/data.pkl") {
+		t.Errorf("expected the record name in %q", err)
+	}
+}
+
+// legacy_views.pt is an emulated checkpoint, written without torch by
+// testdata/generate_legacy_fixtures.py, which reproduces the "torch.save" of
+// PyTorch 0.3.1, the last version writing storage views.
+func TestLegacyStorageViews(t *testing.T) {
+	result, err := Load(path.Join("testdata", "legacy_views.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, ok := result.(*types.Dict)
+	if !ok {
+		t.Fatalf("expected *types.Dict, got %#v", result)
+	}
+	storage := func(key string) *FloatStorage {
+		value, _ := dict.Get(key)
+		tensor, ok := value.(*Tensor)
+		if !ok {
+			t.Fatalf("%s: expected *Tensor, got %#v", key, value)
+		}
+		fs, ok := tensor.Source.(*FloatStorage)
+		if !ok {
+			t.Fatalf("%s: expected *FloatStorage, got %#v", key, tensor.Source)
+		}
+		return fs
+	}
+
+	root := storage("weight")
+	assertBaseStorageFields(t, root.BaseStorage, 9, "cpu")
+	assertFloat32SliceEqual(t, root.Data,
+		[]float32{1.5, -2.5, 3.5, -4.5, 5.5, -6.5, 0.25, 0.5, 0.75}, 0)
+
+	window := storage("window")
+	if storage("window_again") != window {
+		t.Errorf("expected the same storage for the same view key")
+	}
+	assertBaseStorageFields(t, window.BaseStorage, 4, "cpu")
+	assertFloat32SliceEqual(t, window.Data, []float32{3.5, -4.5, 5.5, -6.5}, 0)
+
+	tail := storage("tail")
+	assertBaseStorageFields(t, tail.BaseStorage, 3, "cpu")
+	assertFloat32SliceEqual(t, tail.Data, []float32{0.25, 0.5, 0.75}, 0)
+
+	// views share the memory of the root storage
+	root.Data[2] = 42
+	if window.Data[0] != 42 {
+		t.Errorf("expected the view to share the memory of its root storage")
+	}
+	if len(window.Data) != cap(window.Data) {
+		t.Errorf("expected the view capacity to be limited to its size")
+	}
+}
+
+func TestStorageViewErrors(t *testing.T) {
+	root := &FloatStorage{BaseStorage: BaseStorage{Size: 3}, Data: []float32{1, 2, 3}}
+	view := &FloatStorage{}
+	if err := view.SetFromStorage(&LongStorage{}, 0, 0); !errors.Is(err, ErrStorageViewType) {
+		t.Errorf("expected ErrStorageViewType, got %v", err)
+	}
+	for _, tc := range []struct{ offset, size int }{{-1, 1}, {0, 4}, {2, 2}, {4, 0}, {1, -1}} {
+		if err := view.SetFromStorage(root, tc.offset, tc.size); err == nil {
+			t.Errorf("offset %d, size %d: expected error", tc.offset, tc.size)
+		}
+	}
+	if err := view.SetFromStorage(root, 3, 0); err != nil {
+		t.Errorf("empty view at the end: unexpected error %v", err)
+	}
+}
+
+func TestStorageSizeErrors(t *testing.T) {
+	// a legacy storage claiming more elements than the bytes following it
+	data := []byte{5, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+	r := io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+	if err := (&ByteStorage{}).SetFromFile(r); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("expected a size error, got %v", err)
+	}
+	if err := checkStorageSize(math.MaxUint64, math.MaxUint64); err == nil {
+		t.Errorf("expected error for a size overflowing int")
+	}
+	if err := checkStorageSize(4, 4); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// readerOnlyFS wraps the files of an fs.FS, hiding their io.ReaderAt
+// implementation.
+type readerOnlyFS struct{ fs.FS }
+
+func (r readerOnlyFS) Open(name string) (fs.File, error) {
+	f, err := r.FS.Open(name)
+	return struct{ fs.File }{f}, err
+}
+
+func TestLoadFromMemoryAndFS(t *testing.T) {
+	filenames, err := filepath.Glob(path.Join("testdata", "*.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := os.DirFS("testdata")
+	for _, filename := range filenames {
+		if strings.HasPrefix(filepath.Base(filename), "script_") {
+			continue
+		}
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			expected, err := Load(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaders := map[string]func() (interface{}, error){
+				"LoadBytes": func() (interface{}, error) { return LoadBytes(data) },
+				"LoadReaderAt": func() (interface{}, error) {
+					return LoadReaderAt(bytes.NewReader(data), int64(len(data)))
+				},
+				"LoadFS": func() (interface{}, error) {
+					return LoadFS(fsys, filepath.Base(filename))
+				},
+				"LoadFS without ReaderAt": func() (interface{}, error) {
+					return LoadFS(readerOnlyFS{fsys}, filepath.Base(filename))
+				},
+			}
+			for name, load := range loaders {
+				actual, err := load()
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+				} else if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("%s: expected %#v, actual %#v", name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tarHeader := make([]byte, 512)
+	copy(tarHeader[257:], "ustar\x0000")
+	for _, tc := range []struct {
+		data     string
+		expected fileFormat
+	}{
+		{"", legacyFormat},
+		{"PK\x03\x04", zipFormat},
+		{"PK\x05\x06" + strings.Repeat("\x00", 18), zipFormat},
+		{string(tarHeader), legacyTarFormat},
+		{"\x80\x02\x8a\x0al\xfc\x9cF\xf9 j\xa8P\x19.", legacyFormat},
+	} {
+		actual, err := detectFormat(strings.NewReader(tc.data), int64(len(tc.data)))
+		if err != nil {
+			t.Errorf("%q: %v", tc.data, err)
+		} else if actual != tc.expected {
+			t.Errorf("%q: expected %d, actual %d", tc.data, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"fmt"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// QScheme is a quantization scheme of PyTorch quantized tensors, such as
+// torch.per_tensor_affine. QSchemes are pickled as globals of the torch
+// module, named after their value.
+type QScheme string
+
+const (
+	PerTensorAffine              QScheme = "per_tensor_affine"
+	PerChannelAffine             QScheme = "per_channel_affine"
+	PerTensorSymmetric           QScheme = "per_tensor_symmetric"
+	PerChannelSymmetric          QScheme = "per_channel_symmetric"
+	PerChannelAffineFloatQParams QScheme = "per_channel_affine_float_qparams"
+)
+
+// qschemes maps the names of the torch globals to their QScheme.
+var qschemes = map[string]QScheme{
+	"torch.per_tensor_affine":                PerTensorAffine,
+	"torch.per_channel_affine":               PerChannelAffine,
+	"torch.per_tensor_symmetric":             PerTensorSymmetric,
+	"torch.per_channel_symmetric":            PerChannelSymmetric,
+	"torch.per_channel_affine_float_qparams": PerChannelAffineFloatQParams,
+}
+
+// QuantizerParams are the quantization parameters of a quantized tensor,
+// whose real values are (q - zero_point) * scale, for each integer value q
+// of its storage (such as a QInt8Storage).
+type QuantizerParams struct {
+	QScheme QScheme
+	// Scale and ZeroPoint are the parameters of the per-tensor schemes.
+	Scale     float64
+	ZeroPoint int
+	// Scales and ZeroPoints are the parameters of the per-channel schemes,
+	// for each index along the dimension Axis: 1-dimensional tensors of
+	// float64 and int64 values for PerChannelAffine, or of float32 values
+	// for PerChannelAffineFloatQParams.
+	Scales     *Tensor
+	ZeroPoints *Tensor
+	Axis       int
+}
+
+// RebuildQTensor represents the "torch._utils._rebuild_qtensor" function,
+// with arguments (storage, storage_offset, size, stride, quantizer_params,
+// requires_grad, backward_hooks), used by the pickles of quantized tensors.
+type RebuildQTensor struct{}
+
+var _ types.Callable = &RebuildQTensor{}
+
+func (r *RebuildQTensor) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 7 {
+		return nil, fmt.Errorf("RebuildQTensor unexpected args: %#v", args)
+	}
+	storage, storageOk := args[0].(StorageInterface)
+	if !storageOk {
+		return nil, fmt.Errorf("RebuildQTensor unexpected args: %#v", args)
+	}
+	// arg[6] "backward hooks" is unused
+	tArgs := types.NewTupleFromSlice(args)
+	storageOffset, err := tArgs.GetInt(1)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildQTensor: storage offset: %w", err)
+	}
+	size, err := tArgs.GetTuple(2)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildQTensor: size: %w", err)
+	}
+	stride, err := tArgs.GetTuple(3)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildQTensor: stride: %w", err)
+	}
+	params, err := tArgs.GetTuple(4)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildQTensor: quantizer_params: %w", err)
+	}
+	requiresGrad, err := tArgs.GetBool(5)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildQTensor: requires_grad: %w", err)
+	}
+
+	tensor := &Tensor{
+		Source:        storage,
+		StorageOffset: storageOffset,
+		RequiresGrad:  requiresGrad,
+	}
+	tensor.Size, err = tupleToIntSlice(size)
+	if err != nil {
+		return nil, err
+	}
+	tensor.Stride, err = tupleToIntSlice(stride)
+	if err != nil {
+		return nil, err
+	}
+	tensor.Quantizer, err = quantizerParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildQTensor: quantizer_params: %w", err)
+	}
+	return tensor, nil
+}
+
+// quantizerParams converts the quantizer_params tuple of _rebuild_qtensor:
+// (qscheme, scale, zero_point) for the per-tensor schemes, or (qscheme,
+// scales, zero_points, axis) for the per-channel ones.
+func quantizerParams(params *types.Tuple) (*QuantizerParams, error) {
+	if params.Len() == 0 {
+		return nil, fmt.Errorf("empty tuple")
+	}
+	qscheme, ok := params.Get(0).(QScheme)
+	if !ok {
+		return nil, fmt.Errorf("unexpected qscheme %#v", params.Get(0))
+	}
+	q := &QuantizerParams{QScheme: qscheme}
+	var err error
+
+	switch qscheme {
+	case PerTensorAffine, PerTensorSymmetric:
+		if params.Len() != 3 {
+			return nil, fmt.Errorf("%s: unexpected params %#v", qscheme, params)
+		}
+		if q.Scale, err = params.GetFloat(1); err != nil {
+			return nil, fmt.Errorf("%s: scale: %w", qscheme, err)
+		}
+		if q.ZeroPoint, err = params.GetInt(2); err != nil {
+			return nil, fmt.Errorf("%s: zero_point: %w", qscheme, err)
+		}
+	case PerChannelAffine, PerChannelSymmetric, PerChannelAffineFloatQParams:
+		if params.Len() != 4 {
+			return nil, fmt.Errorf("%s: unexpected params %#v", qscheme, params)
+		}
+		if q.Scales, ok = params.Get(1).(*Tensor); !ok {
+			return nil, fmt.Errorf("%s: scales: expected *Tensor, got %#v", qscheme, params.Get(1))
+		}
+		if q.ZeroPoints, ok = params.Get(2).(*Tensor); !ok {
+			return nil, fmt.Errorf("%s: zero_points: expected *Tensor, got %#v", qscheme, params.Get(2))
+		}
+		if q.Axis, err = params.GetInt(3); err != nil {
+			return nil, fmt.Errorf("%s: axis: %w", qscheme, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported qscheme %q", qscheme)
+	}
+	return q, nil
+}
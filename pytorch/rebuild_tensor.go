@@ -9,6 +9,50 @@ import (
 	"github.com/nlpodyssey/gopickle/types"
 )
 
+// RebuildTensor represents the legacy "torch._utils._rebuild_tensor"
+// function, with arguments (storage, storage_offset, size, stride), used by
+// the pickles of PyTorch versions before 0.4.
+type RebuildTensor struct{}
+
+var _ types.Callable = &RebuildTensor{}
+
+func (r *RebuildTensor) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 4 {
+		return nil, fmt.Errorf("RebuildTensor unexpected args: %#v", args)
+	}
+	storage, storageOk := args[0].(StorageInterface)
+	if !storageOk {
+		return nil, fmt.Errorf("RebuildTensor unexpected args: %#v", args)
+	}
+	tArgs := types.NewTupleFromSlice(args)
+	storageOffset, err := tArgs.GetInt(1)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensor: storage offset: %w", err)
+	}
+	size, err := tArgs.GetTuple(2)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensor: size: %w", err)
+	}
+	stride, err := tArgs.GetTuple(3)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensor: stride: %w", err)
+	}
+
+	tensor := &Tensor{
+		Source:        storage,
+		StorageOffset: storageOffset,
+	}
+	tensor.Size, err = tupleToIntSlice(size)
+	if err != nil {
+		return nil, err
+	}
+	tensor.Stride, err = tupleToIntSlice(stride)
+	if err != nil {
+		return nil, err
+	}
+	return tensor, nil
+}
+
 type RebuildTensorV2 struct{}
 
 var _ types.Callable = &RebuildTensorV2{}
@@ -18,22 +62,33 @@ func (r *RebuildTensorV2) Call(args ...interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("RebuildTensorV2 unexpected args: %#v", args)
 	}
 	storage, storageOk := args[0].(StorageInterface)
-	storageOffset, storageOffsetOk := args[1].(int)
-	size, sizeOk := args[2].(*types.Tuple)
-	stride, strideOk := args[3].(*types.Tuple)
-	requiresGrad, requiresGradOk := args[4].(bool)
-	// arg[5] "backward hooks" is unused
-	if !storageOk || !storageOffsetOk || !sizeOk || !strideOk ||
-		!requiresGradOk {
+	if !storageOk {
 		return nil, fmt.Errorf("RebuildTensorV2 unexpected args: %#v", args)
 	}
+	// arg[5] "backward hooks" is unused
+	tArgs := types.NewTupleFromSlice(args)
+	storageOffset, err := tArgs.GetInt(1)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensorV2: storage offset: %w", err)
+	}
+	size, err := tArgs.GetTuple(2)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensorV2: size: %w", err)
+	}
+	stride, err := tArgs.GetTuple(3)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensorV2: stride: %w", err)
+	}
+	requiresGrad, err := tArgs.GetBool(4)
+	if err != nil {
+		return nil, fmt.Errorf("RebuildTensorV2: requires_grad: %w", err)
+	}
 
 	tensor := &Tensor{
 		Source:        storage,
 		StorageOffset: storageOffset,
 		RequiresGrad:  requiresGrad,
 	}
-	var err error
 	tensor.Size, err = tupleToIntSlice(size)
 	if err != nil {
 		return nil, err
@@ -49,9 +104,9 @@ func tupleToIntSlice(tuple *types.Tuple) ([]int, error) {
 	length := tuple.Len()
 	slice := make([]int, length)
 	for i := 0; i < length; i++ {
-		value, ok := tuple.Get(i).(int)
-		if !ok {
-			return nil, fmt.Errorf("tuple of ints expected: %#v", tuple)
+		value, err := tuple.GetInt(i)
+		if err != nil {
+			return nil, fmt.Errorf("tuple of ints expected: %w", err)
 		}
 		slice[i] = value
 	}
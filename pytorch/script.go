@@ -0,0 +1,378 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// ScriptArchive is the content of a TorchScript archive, as written by
+// Python "torch.jit.save".
+//
+// The TorchScript code is not executed: it is only made available as
+// source.
+type ScriptArchive struct {
+	// Module is the root module, unpickled from data.pkl.
+	Module *ScriptModule
+	// Constants is the tuple of the tensor constants referenced by the code
+	// (as CONSTANTS.c0, CONSTANTS.c1, ...), unpickled from constants.pkl.
+	Constants *types.Tuple
+	// Code maps the paths of the TorchScript source files, relative to the
+	// code/ directory of the archive (such as
+	// "__torch__/torch/nn/modules/linear.py"), to their content.
+	Code map[string]string
+}
+
+// ScriptModuleClass is the class of a TorchScript object, found in the
+// "__torch__" namespace.
+type ScriptModuleClass struct {
+	// Type is the qualified name of the class, such as "__torch__.Net" or
+	// "__torch__.torch.nn.modules.linear.Linear".
+	Type string
+}
+
+var _ types.PyNewable = &ScriptModuleClass{}
+
+// PyNew returns a new empty ScriptModule.
+func (c *ScriptModuleClass) PyNew(_ ...interface{}) (interface{}, error) {
+	return &ScriptModule{Type: c.Type}, nil
+}
+
+// ScriptModule is a TorchScript module, or an instance of another
+// TorchScript class, loaded from a TorchScript archive.
+type ScriptModule struct {
+	// Type is the qualified name of the TorchScript class, such as
+	// "__torch__.torch.nn.modules.linear.Linear".
+	Type string
+	// Attributes are the attributes of the object, in order. Parameters and
+	// buffers are *Tensor values, submodules are *ScriptModule values.
+	Attributes *types.Dict
+	// State is the value returned by "__getstate__", for objects of classes
+	// defining it. In this case, Attributes is nil.
+	State interface{}
+}
+
+var _ types.PyStateSettable = &ScriptModule{}
+
+// PySetState sets the attributes of the object, or its custom state.
+func (m *ScriptModule) PySetState(state interface{}) error {
+	if attributes, ok := state.(*types.Dict); ok {
+		m.Attributes = attributes
+		return nil
+	}
+	m.State = state
+	return nil
+}
+
+// Get returns the value of an attribute.
+func (m *ScriptModule) Get(name string) (interface{}, bool) {
+	if m.Attributes == nil {
+		return nil, false
+	}
+	return m.Attributes.Get(name)
+}
+
+// Children returns the direct submodules, by attribute name, in order.
+func (m *ScriptModule) Children() *types.OrderedDict {
+	children := types.NewOrderedDict()
+	if m.Attributes == nil {
+		return children
+	}
+	for _, entry := range m.Attributes.Entries() {
+		if child, ok := entry.Value.(*ScriptModule); ok {
+			children.Set(entry.Key, child)
+		}
+	}
+	return children
+}
+
+// StateDict returns all the tensors of the module and its submodules, such
+// as parameters and buffers, like Python "Module.state_dict": the keys are
+// the dotted paths of the attributes (e.g. "fc.weight"), the values are
+// *Tensor.
+func (m *ScriptModule) StateDict() *types.OrderedDict {
+	stateDict := types.NewOrderedDict()
+	m.collectTensors("", stateDict, make(map[*ScriptModule]struct{}))
+	return stateDict
+}
+
+func (m *ScriptModule) collectTensors(prefix string, stateDict *types.OrderedDict, visited map[*ScriptModule]struct{}) {
+	if _, ok := visited[m]; ok || m.Attributes == nil {
+		return
+	}
+	visited[m] = struct{}{}
+	for _, entry := range m.Attributes.Entries() {
+		name, ok := entry.Key.(string)
+		if !ok {
+			continue
+		}
+		switch v := entry.Value.(type) {
+		case *Tensor:
+			stateDict.Set(prefix+name, v)
+		case *ScriptModule:
+			v.collectTensors(prefix+name+".", stateDict, visited)
+		}
+	}
+}
+
+// LoadScript loads a TorchScript archive, as written by Python
+// "torch.jit.save".
+func LoadScript(filename string) (*ScriptArchive, error) {
+	return (&Loader{}).LoadScript(filename)
+}
+
+// LoadScriptWithUnpickler is like LoadScript, but it accepts a newUnpickler
+// function which is used to create new customized pickle.Unpickler
+// instances.
+func LoadScriptWithUnpickler(filename string, newUnpickler func(r io.Reader) pickle.Unpickler) (*ScriptArchive, error) {
+	return (&Loader{NewUnpickler: newUnpickler}).LoadScript(filename)
+}
+
+// LoadScriptReaderAt is like LoadScript, reading the size bytes of an
+// archive from r.
+func LoadScriptReaderAt(r io.ReaderAt, size int64) (*ScriptArchive, error) {
+	return (&Loader{}).LoadScriptReaderAt(r, size)
+}
+
+// LoadScriptBytes is like LoadScript, reading an archive from memory.
+func LoadScriptBytes(b []byte) (*ScriptArchive, error) {
+	return LoadScriptReaderAt(bytes.NewReader(b), int64(len(b)))
+}
+
+// LoadScriptFS is like LoadScript, reading the named archive from fsys, as
+// LoadFS.
+func LoadScriptFS(fsys fs.FS, name string) (*ScriptArchive, error) {
+	var archive *ScriptArchive
+	err := readFS(fsys, name, func(r io.ReaderAt, size int64) (err error) {
+		archive, err = LoadScriptReaderAt(r, size)
+		return err
+	})
+	return archive, err
+}
+
+// LoadScript loads a TorchScript archive, like the package function
+// LoadScript.
+func (l *Loader) LoadScript(filename string) (*ScriptArchive, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return l.LoadScriptReaderAt(f, fi.Size())
+}
+
+// LoadScriptReaderAt loads the size bytes of a TorchScript archive from r,
+// like the package function LoadScriptReaderAt.
+func (l *Loader) LoadScriptReaderAt(ra io.ReaderAt, size int64) (*ScriptArchive, error) {
+	r, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// All the records are in a directory named after the archive: they are
+	// mapped by their path inside of it.
+	records := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		if err = checkRecordSize(f, size); err != nil {
+			return nil, err
+		}
+		if i := strings.IndexByte(f.Name, '/'); i >= 0 {
+			records[f.Name[i+1:]] = f
+		}
+	}
+	if _, ok := records["constants.pkl"]; !ok {
+		return nil, fmt.Errorf("constants.pkl not found in zip file: not a TorchScript archive")
+	}
+
+	data, err := l.loadScriptPickle("data", records)
+	if err != nil {
+		return nil, err
+	}
+	module, ok := data.(*ScriptModule)
+	if !ok {
+		return nil, fmt.Errorf("data.pkl: expected a TorchScript module, got %#v", data)
+	}
+	constants, err := l.loadScriptPickle("constants", records)
+	if err != nil {
+		return nil, err
+	}
+	constantsTuple, ok := constants.(*types.Tuple)
+	if !ok {
+		return nil, fmt.Errorf("constants.pkl: expected a tuple, got %#v", constants)
+	}
+	code, err := readScriptCode(records)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScriptArchive{
+		Module:    module,
+		Constants: constantsTuple,
+		Code:      code,
+	}, nil
+}
+
+// loadScriptPickle unpickles the record name+".pkl" of a TorchScript
+// archive, whose storages are the records in the directory with the same
+// name (such as data.pkl and data/0, data/1, ...). As in loadZipFile, the
+// data of the storages is read after unpickling.
+func (l *Loader) loadScriptPickle(name string, records map[string]*zip.File) (interface{}, error) {
+	pklFile, ok := records[name+".pkl"]
+	if !ok {
+		return nil, fmt.Errorf("%s.pkl not found in zip file", name)
+	}
+	storageRecords := make(map[string]*zip.File)
+	prefix := name + "/"
+	for recordName, f := range records {
+		if strings.HasPrefix(recordName, prefix) {
+			storageRecords[recordName[len(prefix):]] = f
+		}
+	}
+
+	loadedStorages := make(map[string]StorageInterface)
+	var pending []pendingStorage
+	persistent := pickle.NewPersistentRegistry()
+	persistent.RegisterFunc("storage", func(id pickle.PersistentID) (interface{}, error) {
+		dataType, key, location, size, err := l.storageArgs(id.Args())
+		if err != nil {
+			return nil, err
+		}
+		storage, storageExists := loadedStorages[key]
+		if storageExists {
+			return storage, nil
+		}
+		file, err := storageRecord(size, key, storageRecords)
+		if err != nil {
+			return nil, err
+		}
+		storage = dataType.New(size, location)
+		pending = append(pending, pendingStorage{storage: storage, size: size, file: file})
+		loadedStorages[key] = storage
+		return storage, nil
+	})
+
+	f, err := pklFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	u := l.newUnpickler(f)
+	u.FindClass = l.makeScriptFindClass(u.FindClass)
+	setPersistentRegistry(&u, persistent)
+	result, err := u.Load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pklFile.Name, err)
+	}
+	if err = decodeStorages(pending, l.concurrency()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readScriptCode reads the TorchScript source files (*.py) under the code/
+// directory of an archive.
+func readScriptCode(records map[string]*zip.File) (map[string]string, error) {
+	const prefix = "code/"
+	code := make(map[string]string)
+	for recordName, f := range records {
+		if !strings.HasPrefix(recordName, prefix) || !strings.HasSuffix(recordName, ".py") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		src, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		code[recordName[len(prefix):]] = string(src)
+	}
+	return code, nil
+}
+
+// makeScriptFindClass is like makePickleFindClass, also resolving the
+// TorchScript classes, in the "__torch__" namespace, and the functions used
+// by the TorchScript pickler.
+func (l *Loader) makeScriptFindClass(fallback func(module, name string) (interface{}, error)) func(module, name string) (interface{}, error) {
+	findClass := l.makePickleFindClass(fallback)
+	return func(module, name string) (interface{}, error) {
+		if module == "__torch__" || strings.HasPrefix(module, "__torch__.") {
+			return &ScriptModuleClass{Type: module + "." + name}, nil
+		}
+		switch module + "." + name {
+		case "torch.jit._pickle.restore_type_tag":
+			return scriptRestoreTypeTag{}, nil
+		case "torch.jit._pickle.build_intlist",
+			"torch.jit._pickle.build_doublelist",
+			"torch.jit._pickle.build_boollist",
+			"torch.jit._pickle.build_tensorlist":
+			return scriptBuildList{}, nil
+		case "torch.device":
+			return scriptDevice{}, nil
+		}
+		return findClass(module, name)
+	}
+}
+
+// scriptRestoreTypeTag is torch.jit._pickle.restore_type_tag(value,
+// type_str): it returns the value, discarding its TorchScript type (such as
+// "Dict[str, int]").
+type scriptRestoreTypeTag struct{}
+
+var _ types.Callable = scriptRestoreTypeTag{}
+
+func (scriptRestoreTypeTag) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("restore_type_tag: unexpected args: %#v", args)
+	}
+	return args[0], nil
+}
+
+// scriptBuildList is torch.jit._pickle.build_intlist(list) and the other
+// specialized list functions: it returns the list.
+type scriptBuildList struct{}
+
+var _ types.Callable = scriptBuildList{}
+
+func (scriptBuildList) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("build list: unexpected args: %#v", args)
+	}
+	return args[0], nil
+}
+
+// scriptDevice is torch.device(name): it returns the device name, such as
+// "cpu" or "cuda:0".
+type scriptDevice struct{}
+
+var _ types.Callable = scriptDevice{}
+
+func (scriptDevice) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("torch.device: unexpected args: %#v", args)
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("torch.device: unexpected args: %#v", args)
+	}
+	return name, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// script_module.pt is written by testdata/generate_script_fixtures.py.
+
+func TestLoadScript(t *testing.T) {
+	archive, err := LoadScript(path.Join("testdata", "script_module.pt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net := archive.Module
+	if net.Type != "__torch__.Net" {
+		t.Errorf("unexpected module type %q", net.Type)
+	}
+	if training, _ := net.Get("training"); training != true {
+		t.Errorf("expected training true, got %#v", training)
+	}
+	config, _ := net.Get("config")
+	if repr := types.Repr(config); repr != "{'depth': 1}" {
+		t.Errorf("unexpected config %s", repr)
+	}
+
+	children := net.Children()
+	if children.Len() != 1 {
+		t.Fatalf("expected 1 child, got %d", children.Len())
+	}
+	fc, ok := children.MustGet("fc").(*ScriptModule)
+	if !ok || fc.Type != "__torch__.torch.nn.modules.linear.Linear" {
+		t.Fatalf("unexpected fc %#v", children.MustGet("fc"))
+	}
+
+	stateDict := net.StateDict()
+	var keys []string
+	for e := stateDict.List.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*types.OrderedDictEntry).Key.(string))
+	}
+	if strings.Join(keys, " ") != "fc.weight fc.bias scale" {
+		t.Errorf("unexpected state dict keys %v", keys)
+	}
+	weight := stateDict.MustGet("fc.weight").(*Tensor)
+	assertIntSliceEqual(t, weight.Size, []int{2, 3})
+	assertIntSliceEqual(t, weight.Stride, []int{3, 1})
+	assertFloat32SliceEqual(t, weight.Source.(*FloatStorage).Data,
+		[]float32{0.5, -1.0, 1.5, -2.0, 2.5, -3.0}, 0)
+	bias := stateDict.MustGet("fc.bias").(*Tensor)
+	assertFloat32SliceEqual(t, bias.Source.(*FloatStorage).Data, []float32{0.25, -0.25}, 0)
+	scale := stateDict.MustGet("scale").(*Tensor)
+	assertFloat32SliceEqual(t, scale.Source.(*FloatStorage).Data, []float32{2.0}, 0)
+
+	if archive.Constants.Len() != 1 {
+		t.Fatalf("expected 1 constant, got %d", archive.Constants.Len())
+	}
+	c0, ok := archive.Constants.Get(0).(*Tensor)
+	if !ok {
+		t.Fatalf("expected *Tensor constant, got %#v", archive.Constants.Get(0))
+	}
+	assertFloat32SliceEqual(t, c0.Source.(*FloatStorage).Data, []float32{1.0, 2.0}, 0)
+
+	if len(archive.Code) != 2 {
+		t.Errorf("expected 2 source files, got %d", len(archive.Code))
+	}
+	if !strings.Contains(archive.Code["__torch__.py"], "CONSTANTS.c0") {
+		t.Errorf("unexpected code of __torch__.py: %q", archive.Code["__torch__.py"])
+	}
+	if !strings.HasPrefix(archive.Code["__torch__/torch/nn/modules/linear.py"], "class Linear(Module):") {
+		t.Errorf("unexpected code of linear.py")
+	}
+}
+
+func TestLoadScriptFromMemoryAndFS(t *testing.T) {
+	filename := path.Join("testdata", "script_module.pt")
+	expected, err := LoadScript(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := os.DirFS("testdata")
+	loaders := map[string]func() (*ScriptArchive, error){
+		"LoadScriptBytes": func() (*ScriptArchive, error) { return LoadScriptBytes(data) },
+		"LoadScriptReaderAt": func() (*ScriptArchive, error) {
+			return LoadScriptReaderAt(bytes.NewReader(data), int64(len(data)))
+		},
+		"LoadScriptFS": func() (*ScriptArchive, error) {
+			return LoadScriptFS(fsys, "script_module.pt")
+		},
+		"LoadScriptFS without ReaderAt": func() (*ScriptArchive, error) {
+			return LoadScriptFS(readerOnlyFS{fsys}, "script_module.pt")
+		},
+		"Loader.LoadScript sequential": func() (*ScriptArchive, error) {
+			return (&Loader{Concurrency: 1}).LoadScript(filename)
+		},
+	}
+	for name, load := range loaders {
+		actual, err := load()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: expected %#v, actual %#v", name, expected, actual)
+		}
+	}
+}
+
+func TestLoadScriptErrors(t *testing.T) {
+	_, err := Load(path.Join("testdata", "script_module.pt"))
+	if err == nil || !strings.Contains(err.Error(), "LoadScript") {
+		t.Errorf("Load: expected an error suggesting LoadScript, got %v", err)
+	}
+	_, err = LoadScript(path.Join("testdata", "tensor_float32_proto2_zip.pt"))
+	if err == nil || !strings.Contains(err.Error(), "not a TorchScript archive") {
+		t.Errorf("LoadScript: expected not a TorchScript archive error, got %v", err)
+	}
+}
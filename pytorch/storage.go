@@ -6,8 +6,11 @@ package pytorch
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 	"math"
+	"os"
 )
 
 type StorageClassInterface interface {
@@ -19,6 +22,22 @@ type StorageInterface interface {
 	SetFromFileWithSize(r io.Reader, size int) error
 }
 
+// StorageViewInterface is implemented by storages which can be views onto
+// a window of another storage of the same type, like the Python expression
+// storage[offset:offset+size].
+type StorageViewInterface interface {
+	StorageInterface
+	// SetFromStorage makes the storage a view of the elements from offset
+	// to offset+size of root, which must be already loaded. The view shares
+	// the memory of root: changes to the data of either one are visible to
+	// the other.
+	SetFromStorage(root StorageInterface, offset, size int) error
+}
+
+// ErrStorageViewType is returned by StorageViewInterface.SetFromStorage
+// when the root storage is of a different type.
+var ErrStorageViewType = errors.New("storage view type mismatch")
+
 type BaseStorage struct {
 	Size     int
 	Location string
@@ -42,7 +61,7 @@ type HalfStorage struct {
 	Data []float32
 }
 
-var _ StorageInterface = &HalfStorage{}
+var _ StorageViewInterface = &HalfStorage{}
 
 func (f *HalfStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -63,6 +82,19 @@ func (f *HalfStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *HalfStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*HalfStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *HalfStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Float -----
 
 type FloatStorageClass struct{}
@@ -81,7 +113,7 @@ type FloatStorage struct {
 	Data []float32
 }
 
-var _ StorageInterface = &FloatStorage{}
+var _ StorageViewInterface = &FloatStorage{}
 
 func (f *FloatStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -101,6 +133,19 @@ func (f *FloatStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *FloatStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*FloatStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *FloatStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Double -----
 
 type DoubleStorageClass struct{}
@@ -119,7 +164,7 @@ type DoubleStorage struct {
 	Data []float64
 }
 
-var _ StorageInterface = &DoubleStorage{}
+var _ StorageViewInterface = &DoubleStorage{}
 
 func (f *DoubleStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -139,6 +184,19 @@ func (f *DoubleStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *DoubleStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*DoubleStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *DoubleStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Char -----
 
 type CharStorageClass struct{}
@@ -157,7 +215,7 @@ type CharStorage struct {
 	Data []int8
 }
 
-var _ StorageInterface = &CharStorage{}
+var _ StorageViewInterface = &CharStorage{}
 
 func (f *CharStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -177,6 +235,19 @@ func (f *CharStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *CharStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*CharStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *CharStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Short -----
 
 type ShortStorageClass struct{}
@@ -195,7 +266,7 @@ type ShortStorage struct {
 	Data []int16
 }
 
-var _ StorageInterface = &ShortStorage{}
+var _ StorageViewInterface = &ShortStorage{}
 
 func (f *ShortStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -215,6 +286,19 @@ func (f *ShortStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *ShortStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*ShortStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *ShortStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Int -----
 
 type IntStorageClass struct{}
@@ -233,7 +317,7 @@ type IntStorage struct {
 	Data []int32
 }
 
-var _ StorageInterface = &IntStorage{}
+var _ StorageViewInterface = &IntStorage{}
 
 func (f *IntStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -253,6 +337,19 @@ func (f *IntStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *IntStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*IntStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *IntStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Long -----
 
 type LongStorageClass struct{}
@@ -271,7 +368,7 @@ type LongStorage struct {
 	Data []int64
 }
 
-var _ StorageInterface = &LongStorage{}
+var _ StorageViewInterface = &LongStorage{}
 
 func (f *LongStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -291,6 +388,19 @@ func (f *LongStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *LongStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*LongStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *LongStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Byte -----
 
 type ByteStorageClass struct{}
@@ -309,7 +419,7 @@ type ByteStorage struct {
 	Data []uint8
 }
 
-var _ StorageInterface = &ByteStorage{}
+var _ StorageViewInterface = &ByteStorage{}
 
 func (f *ByteStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -329,6 +439,19 @@ func (f *ByteStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *ByteStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*ByteStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *ByteStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- Bool -----
 
 type BoolStorageClass struct{}
@@ -347,7 +470,7 @@ type BoolStorage struct {
 	Data []bool
 }
 
-var _ StorageInterface = &BoolStorage{}
+var _ StorageViewInterface = &BoolStorage{}
 
 func (f *BoolStorage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -367,6 +490,19 @@ func (f *BoolStorage) SetFromFileWithSize(r io.Reader, size int) error {
 	return nil
 }
 
+func (f *BoolStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*BoolStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *BoolStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
 // ----- BFloat16 -----
 
 type BFloat16StorageClass struct{}
@@ -385,7 +521,7 @@ type BFloat16Storage struct {
 	Data []float32
 }
 
-var _ StorageInterface = &BFloat16Storage{}
+var _ StorageViewInterface = &BFloat16Storage{}
 
 func (f *BFloat16Storage) SetFromFile(r io.Reader) error {
 	return setFromFile(f, r)
@@ -400,18 +536,239 @@ func (f *BFloat16Storage) SetFromFileWithSize(r io.Reader, size int) error {
 			return err
 		}
 		u16 := binary.LittleEndian.Uint16(bytes)
-		data[i] = math.Float32frombits(uint32(u16) << 16)
+		data[i] = math.Float32frombits(BFloatBits16to32(u16))
 	}
 	f.Data = data
 	return nil
 }
 
+func (f *BFloat16Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*BFloat16Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *BFloat16Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- RawHalf -----
+
+// RawHalfStorageClass is the class of RawHalfStorage, used in place of
+// HalfStorageClass when loading with Loader.RawFloat16.
+type RawHalfStorageClass struct{}
+
+var _ StorageClassInterface = &RawHalfStorageClass{}
+
+func (f *RawHalfStorageClass) New(size int, location string) StorageInterface {
+	return &RawHalfStorage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// RawHalfStorage is a half-precision (float16) storage keeping the bits of
+// its elements, rather than widening them to float32 as HalfStorage.
+type RawHalfStorage struct {
+	BaseStorage
+	Data []uint16
+}
+
+var _ StorageViewInterface = &RawHalfStorage{}
+
+func (f *RawHalfStorage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *RawHalfStorage) SetFromFileWithSize(r io.Reader, size int) error {
+	data, err := readUint16s(r, size)
+	if err != nil {
+		return err
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *RawHalfStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*RawHalfStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *RawHalfStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// Float32At returns the element at index i, converted to float32.
+func (f *RawHalfStorage) Float32At(i int) float32 {
+	return math.Float32frombits(FloatBits16to32(f.Data[i]))
+}
+
+// ToFloat32 converts the elements to float32, storing them in dst. Like the
+// built-in copy, it converts min(len(dst), len(f.Data)) elements, and returns
+// their number.
+func (f *RawHalfStorage) ToFloat32(dst []float32) int {
+	n := len(dst)
+	if len(f.Data) < n {
+		n = len(f.Data)
+	}
+	for i, u16 := range f.Data[:n] {
+		dst[i] = math.Float32frombits(FloatBits16to32(u16))
+	}
+	return n
+}
+
+// ----- RawBFloat16 -----
+
+// RawBFloat16StorageClass is the class of RawBFloat16Storage, used in place
+// of BFloat16StorageClass when loading with Loader.RawFloat16.
+type RawBFloat16StorageClass struct{}
+
+var _ StorageClassInterface = &RawBFloat16StorageClass{}
+
+func (f *RawBFloat16StorageClass) New(size int, location string) StorageInterface {
+	return &RawBFloat16Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// RawBFloat16Storage is a bfloat16 storage keeping the bits of its
+// elements, rather than widening them to float32 as BFloat16Storage.
+type RawBFloat16Storage struct {
+	BaseStorage
+	Data []uint16
+}
+
+var _ StorageViewInterface = &RawBFloat16Storage{}
+
+func (f *RawBFloat16Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *RawBFloat16Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data, err := readUint16s(r, size)
+	if err != nil {
+		return err
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *RawBFloat16Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*RawBFloat16Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *RawBFloat16Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// Float32At returns the element at index i, converted to float32.
+func (f *RawBFloat16Storage) Float32At(i int) float32 {
+	return math.Float32frombits(BFloatBits16to32(f.Data[i]))
+}
+
+// ToFloat32 converts the elements to float32, storing them in dst. Like the
+// built-in copy, it converts min(len(dst), len(f.Data)) elements, and returns
+// their number.
+func (f *RawBFloat16Storage) ToFloat32(dst []float32) int {
+	n := len(dst)
+	if len(f.Data) < n {
+		n = len(f.Data)
+	}
+	for i, u16 := range f.Data[:n] {
+		dst[i] = math.Float32frombits(BFloatBits16to32(u16))
+	}
+	return n
+}
+
+// readUint16s reads size little-endian 16-bit values.
+func readUint16s(r io.Reader, size int) ([]uint16, error) {
+	data := make([]uint16, size)
+	br := NewLimitedBufferReader(r, size, 2, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return nil, err
+		}
+		data[i] = binary.LittleEndian.Uint16(bytes)
+	}
+	return data, nil
+}
+
 func setFromFile(s StorageInterface, r io.Reader) error {
 	sizeBuf := make([]byte, 8)
-	_, err := r.Read(sizeBuf)
+	_, err := io.ReadFull(r, sizeBuf)
 	if err != nil {
 		return err
 	}
-	size := int(binary.LittleEndian.Uint64(sizeBuf))
-	return s.SetFromFileWithSize(r, size)
+	size := binary.LittleEndian.Uint64(sizeBuf)
+	available := uint64(math.MaxUint64)
+	if remaining, ok := remainingSize(r); ok {
+		available = uint64(remaining)
+	}
+	if err = checkStorageSize(size, available); err != nil {
+		return err
+	}
+	return s.SetFromFileWithSize(r, int(size))
+}
+
+// checkStorageSize verifies that a storage of size elements can be read
+// from available bytes of data. Each element takes at least one byte, so a
+// larger size can only come from corrupted data: rejecting it prevents huge
+// allocations.
+func checkStorageSize(size, available uint64) error {
+	if size > math.MaxInt {
+		return fmt.Errorf("invalid storage size %d", size)
+	}
+	if size > available {
+		return fmt.Errorf("storage size %d exceeds data size %d", size, available)
+	}
+	return nil
+}
+
+// remainingSize returns the amount of bytes which can still be read from r,
+// if it can be determined.
+func remainingSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case *io.SectionReader:
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return r.Size() - pos, true
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return fi.Size() - pos, true
+	default:
+		return 0, false
+	}
+}
+
+// checkStorageView verifies that the window of a storage view, from offset
+// to offset+size, lies within a root storage of rootSize elements.
+func checkStorageView(rootSize, offset, size int) error {
+	if offset < 0 || size < 0 || offset > rootSize || size > rootSize-offset {
+		return fmt.Errorf("storage view of size %d at offset %d exceeds storage size %d",
+			size, offset, rootSize)
+	}
+	return nil
 }
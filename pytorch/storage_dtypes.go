@@ -0,0 +1,846 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// This file holds the storages of the data types added by PyTorch after the
+// classic ones of storage.go: complex numbers, 8-bit floating point numbers,
+// unsigned integers larger than 8 bits, quantized integers and bits types.
+// The data types without a legacy storage class are pickled as an
+// UntypedStorage and a DType: their storages are decoded by
+// UntypedStorage.Typed.
+
+// ----- ComplexFloat -----
+
+type ComplexFloatStorageClass struct{}
+
+var _ StorageClassInterface = &ComplexFloatStorageClass{}
+
+func (f *ComplexFloatStorageClass) New(size int, location string) StorageInterface {
+	return &ComplexFloatStorage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// ComplexFloatStorage is a storage of complex64 (torch.complex64, or
+// torch.cfloat) elements, as pairs of float32 real and imaginary parts.
+type ComplexFloatStorage struct {
+	BaseStorage
+	Data []complex64
+}
+
+var _ StorageViewInterface = &ComplexFloatStorage{}
+
+func (f *ComplexFloatStorage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *ComplexFloatStorage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]complex64, size)
+	br := NewLimitedBufferReader(r, size, 8, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = complex(math.Float32frombits(binary.LittleEndian.Uint32(bytes)), math.Float32frombits(binary.LittleEndian.Uint32(bytes[4:])))
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *ComplexFloatStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*ComplexFloatStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *ComplexFloatStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- ComplexDouble -----
+
+type ComplexDoubleStorageClass struct{}
+
+var _ StorageClassInterface = &ComplexDoubleStorageClass{}
+
+func (f *ComplexDoubleStorageClass) New(size int, location string) StorageInterface {
+	return &ComplexDoubleStorage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// ComplexDoubleStorage is a storage of complex128 (torch.complex128, or
+// torch.cdouble) elements, as pairs of float64 real and imaginary parts.
+type ComplexDoubleStorage struct {
+	BaseStorage
+	Data []complex128
+}
+
+var _ StorageViewInterface = &ComplexDoubleStorage{}
+
+func (f *ComplexDoubleStorage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *ComplexDoubleStorage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]complex128, size)
+	br := NewLimitedBufferReader(r, size, 16, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = complex(math.Float64frombits(binary.LittleEndian.Uint64(bytes)), math.Float64frombits(binary.LittleEndian.Uint64(bytes[8:])))
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *ComplexDoubleStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*ComplexDoubleStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *ComplexDoubleStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- Float8E4M3FN -----
+
+type Float8E4M3FNStorageClass struct{}
+
+var _ StorageClassInterface = &Float8E4M3FNStorageClass{}
+
+func (f *Float8E4M3FNStorageClass) New(size int, location string) StorageInterface {
+	return &Float8E4M3FNStorage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Float8E4M3FNStorage is a storage of 8-bit floating point
+// torch.float8_e4m3fn elements, which keeps their bits representation:
+// Float32At and ToFloat32 convert them to float32.
+type Float8E4M3FNStorage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &Float8E4M3FNStorage{}
+
+func (f *Float8E4M3FNStorage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Float8E4M3FNStorage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Float8E4M3FNStorage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Float8E4M3FNStorage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Float8E4M3FNStorage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// Float32At returns the element at index i, converted to float32.
+func (f *Float8E4M3FNStorage) Float32At(i int) float32 {
+	return math.Float32frombits(Float8E4M3FNBits8to32(f.Data[i]))
+}
+
+// ToFloat32 converts the elements to float32, storing them in dst. Like the
+// built-in copy, it converts min(len(dst), len(f.Data)) elements, and returns
+// their number.
+func (f *Float8E4M3FNStorage) ToFloat32(dst []float32) int {
+	n := len(dst)
+	if len(f.Data) < n {
+		n = len(f.Data)
+	}
+	for i, u8 := range f.Data[:n] {
+		dst[i] = math.Float32frombits(Float8E4M3FNBits8to32(u8))
+	}
+	return n
+}
+
+// ----- Float8E5M2 -----
+
+type Float8E5M2StorageClass struct{}
+
+var _ StorageClassInterface = &Float8E5M2StorageClass{}
+
+func (f *Float8E5M2StorageClass) New(size int, location string) StorageInterface {
+	return &Float8E5M2Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Float8E5M2Storage is a storage of 8-bit floating point torch.float8_e5m2
+// elements, which keeps their bits representation: Float32At and ToFloat32
+// convert them to float32.
+type Float8E5M2Storage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &Float8E5M2Storage{}
+
+func (f *Float8E5M2Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Float8E5M2Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Float8E5M2Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Float8E5M2Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Float8E5M2Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// Float32At returns the element at index i, converted to float32.
+func (f *Float8E5M2Storage) Float32At(i int) float32 {
+	return math.Float32frombits(Float8E5M2Bits8to32(f.Data[i]))
+}
+
+// ToFloat32 converts the elements to float32, storing them in dst. Like the
+// built-in copy, it converts min(len(dst), len(f.Data)) elements, and returns
+// their number.
+func (f *Float8E5M2Storage) ToFloat32(dst []float32) int {
+	n := len(dst)
+	if len(f.Data) < n {
+		n = len(f.Data)
+	}
+	for i, u8 := range f.Data[:n] {
+		dst[i] = math.Float32frombits(Float8E5M2Bits8to32(u8))
+	}
+	return n
+}
+
+// ----- UInt16 -----
+
+type UInt16StorageClass struct{}
+
+var _ StorageClassInterface = &UInt16StorageClass{}
+
+func (f *UInt16StorageClass) New(size int, location string) StorageInterface {
+	return &UInt16Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// UInt16Storage is a storage of torch.uint16 elements.
+type UInt16Storage struct {
+	BaseStorage
+	Data []uint16
+}
+
+var _ StorageViewInterface = &UInt16Storage{}
+
+func (f *UInt16Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *UInt16Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint16, size)
+	br := NewLimitedBufferReader(r, size, 2, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = binary.LittleEndian.Uint16(bytes)
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *UInt16Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*UInt16Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *UInt16Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- UInt32 -----
+
+type UInt32StorageClass struct{}
+
+var _ StorageClassInterface = &UInt32StorageClass{}
+
+func (f *UInt32StorageClass) New(size int, location string) StorageInterface {
+	return &UInt32Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// UInt32Storage is a storage of torch.uint32 elements.
+type UInt32Storage struct {
+	BaseStorage
+	Data []uint32
+}
+
+var _ StorageViewInterface = &UInt32Storage{}
+
+func (f *UInt32Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *UInt32Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint32, size)
+	br := NewLimitedBufferReader(r, size, 4, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = binary.LittleEndian.Uint32(bytes)
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *UInt32Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*UInt32Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *UInt32Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- UInt64 -----
+
+type UInt64StorageClass struct{}
+
+var _ StorageClassInterface = &UInt64StorageClass{}
+
+func (f *UInt64StorageClass) New(size int, location string) StorageInterface {
+	return &UInt64Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// UInt64Storage is a storage of torch.uint64 elements.
+type UInt64Storage struct {
+	BaseStorage
+	Data []uint64
+}
+
+var _ StorageViewInterface = &UInt64Storage{}
+
+func (f *UInt64Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *UInt64Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint64, size)
+	br := NewLimitedBufferReader(r, size, 8, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = binary.LittleEndian.Uint64(bytes)
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *UInt64Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*UInt64Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *UInt64Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- QInt8 -----
+
+type QInt8StorageClass struct{}
+
+var _ StorageClassInterface = &QInt8StorageClass{}
+
+func (f *QInt8StorageClass) New(size int, location string) StorageInterface {
+	return &QInt8Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// QInt8Storage is a storage of quantized torch.qint8 elements, the integer
+// values of a quantized tensor (see QuantizerParams).
+type QInt8Storage struct {
+	BaseStorage
+	Data []int8
+}
+
+var _ StorageViewInterface = &QInt8Storage{}
+
+func (f *QInt8Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *QInt8Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]int8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = int8(bytes[0])
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *QInt8Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*QInt8Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *QInt8Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- QUInt8 -----
+
+type QUInt8StorageClass struct{}
+
+var _ StorageClassInterface = &QUInt8StorageClass{}
+
+func (f *QUInt8StorageClass) New(size int, location string) StorageInterface {
+	return &QUInt8Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// QUInt8Storage is a storage of quantized torch.quint8 elements, the integer
+// values of a quantized tensor (see QuantizerParams).
+type QUInt8Storage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &QUInt8Storage{}
+
+func (f *QUInt8Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *QUInt8Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *QUInt8Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*QUInt8Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *QUInt8Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- QInt32 -----
+
+type QInt32StorageClass struct{}
+
+var _ StorageClassInterface = &QInt32StorageClass{}
+
+func (f *QInt32StorageClass) New(size int, location string) StorageInterface {
+	return &QInt32Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// QInt32Storage is a storage of quantized torch.qint32 elements, the integer
+// values of a quantized tensor (see QuantizerParams).
+type QInt32Storage struct {
+	BaseStorage
+	Data []int32
+}
+
+var _ StorageViewInterface = &QInt32Storage{}
+
+func (f *QInt32Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *QInt32Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]int32, size)
+	br := NewLimitedBufferReader(r, size, 4, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = int32(binary.LittleEndian.Uint32(bytes))
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *QInt32Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*QInt32Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *QInt32Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- Bits1x8 -----
+
+type Bits1x8StorageClass struct{}
+
+var _ StorageClassInterface = &Bits1x8StorageClass{}
+
+func (f *Bits1x8StorageClass) New(size int, location string) StorageInterface {
+	return &Bits1x8Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Bits1x8Storage is a storage of torch.bits1x8 elements, opaque bytes.
+type Bits1x8Storage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &Bits1x8Storage{}
+
+func (f *Bits1x8Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Bits1x8Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Bits1x8Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Bits1x8Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Bits1x8Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- Bits2x4 -----
+
+type Bits2x4StorageClass struct{}
+
+var _ StorageClassInterface = &Bits2x4StorageClass{}
+
+func (f *Bits2x4StorageClass) New(size int, location string) StorageInterface {
+	return &Bits2x4Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Bits2x4Storage is a storage of torch.bits2x4 elements, opaque bytes.
+type Bits2x4Storage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &Bits2x4Storage{}
+
+func (f *Bits2x4Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Bits2x4Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Bits2x4Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Bits2x4Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Bits2x4Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- Bits4x2 -----
+
+type Bits4x2StorageClass struct{}
+
+var _ StorageClassInterface = &Bits4x2StorageClass{}
+
+func (f *Bits4x2StorageClass) New(size int, location string) StorageInterface {
+	return &Bits4x2Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Bits4x2Storage is a storage of torch.bits4x2 elements, opaque bytes.
+type Bits4x2Storage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &Bits4x2Storage{}
+
+func (f *Bits4x2Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Bits4x2Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Bits4x2Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Bits4x2Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Bits4x2Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- Bits8 -----
+
+type Bits8StorageClass struct{}
+
+var _ StorageClassInterface = &Bits8StorageClass{}
+
+func (f *Bits8StorageClass) New(size int, location string) StorageInterface {
+	return &Bits8Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Bits8Storage is a storage of torch.bits8 elements, opaque bytes.
+type Bits8Storage struct {
+	BaseStorage
+	Data []uint8
+}
+
+var _ StorageViewInterface = &Bits8Storage{}
+
+func (f *Bits8Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Bits8Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint8, size)
+	br := NewLimitedBufferReader(r, size, 1, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = bytes[0]
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Bits8Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Bits8Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Bits8Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
+
+// ----- Bits16 -----
+
+type Bits16StorageClass struct{}
+
+var _ StorageClassInterface = &Bits16StorageClass{}
+
+func (f *Bits16StorageClass) New(size int, location string) StorageInterface {
+	return &Bits16Storage{
+		BaseStorage: BaseStorage{Size: size, Location: location},
+		Data:        nil,
+	}
+}
+
+// Bits16Storage is a storage of torch.bits16 elements, opaque 16-bit values.
+type Bits16Storage struct {
+	BaseStorage
+	Data []uint16
+}
+
+var _ StorageViewInterface = &Bits16Storage{}
+
+func (f *Bits16Storage) SetFromFile(r io.Reader) error {
+	return setFromFile(f, r)
+}
+
+func (f *Bits16Storage) SetFromFileWithSize(r io.Reader, size int) error {
+	data := make([]uint16, size)
+	br := NewLimitedBufferReader(r, size, 2, 512)
+	for i := 0; i < size; i++ {
+		bytes, err := br.ReadNext()
+		if err != nil {
+			return err
+		}
+		data[i] = binary.LittleEndian.Uint16(bytes)
+	}
+	f.Data = data
+	return nil
+}
+
+func (f *Bits16Storage) SetFromStorage(root StorageInterface, offset, size int) error {
+	rs, ok := root.(*Bits16Storage)
+	if !ok {
+		return fmt.Errorf("%w: expected *Bits16Storage, got %T", ErrStorageViewType, root)
+	}
+	if err := checkStorageView(len(rs.Data), offset, size); err != nil {
+		return err
+	}
+	f.Size = size
+	f.Data = rs.Data[offset : offset+size : offset+size]
+	return nil
+}
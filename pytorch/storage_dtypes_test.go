@@ -0,0 +1,191 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pytorch
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// The complex and quantized fixtures are emulated checkpoints, written
+// without torch by testdata/generate_dtype_fixtures.py.
+
+func TestComplexTensors(t *testing.T) {
+	expected := []complex128{1 + 2i, -3.5 + 0.25i}
+
+	tensor := loadTensorFromFile(t, "tensor_complex64_zip.pt")
+	cs, ok := tensor.Source.(*ComplexFloatStorage)
+	if !ok {
+		t.Fatalf("expected *ComplexFloatStorage, got %#v", tensor.Source)
+	}
+	assertBaseStorageFields(t, cs.BaseStorage, 2, "cpu")
+	assertIntSliceEqual(t, tensor.Size, []int{2})
+	if !reflect.DeepEqual(cs.Data, []complex64{complex64(expected[0]), complex64(expected[1])}) {
+		t.Errorf("expected %v, actual %v", expected, cs.Data)
+	}
+
+	tensor = loadTensorFromFile(t, "tensor_complex128_zip.pt")
+	cd, ok := tensor.Source.(*ComplexDoubleStorage)
+	if !ok {
+		t.Fatalf("expected *ComplexDoubleStorage, got %#v", tensor.Source)
+	}
+	assertBaseStorageFields(t, cd.BaseStorage, 2, "cpu")
+	if !reflect.DeepEqual(cd.Data, expected) {
+		t.Errorf("expected %v, actual %v", expected, cd.Data)
+	}
+}
+
+func TestQuantizedTensors(t *testing.T) {
+	t.Run("qint8 per tensor", func(t *testing.T) {
+		tensor := loadTensorFromFile(t, "tensor_qint8_zip.pt")
+		qs, ok := tensor.Source.(*QInt8Storage)
+		if !ok {
+			t.Fatalf("expected *QInt8Storage, got %#v", tensor.Source)
+		}
+		assertBaseStorageFields(t, qs.BaseStorage, 4, "cpu")
+		if !reflect.DeepEqual(qs.Data, []int8{0, 2, 3, 4}) {
+			t.Errorf("unexpected data %v", qs.Data)
+		}
+		expected := &QuantizerParams{QScheme: PerTensorAffine, Scale: 0.5, ZeroPoint: 2}
+		if !reflect.DeepEqual(tensor.Quantizer, expected) {
+			t.Errorf("expected %#v, actual %#v", expected, tensor.Quantizer)
+		}
+	})
+
+	t.Run("qint32 per tensor", func(t *testing.T) {
+		tensor := loadTensorFromFile(t, "tensor_qint32_zip.pt")
+		qs, ok := tensor.Source.(*QInt32Storage)
+		if !ok {
+			t.Fatalf("expected *QInt32Storage, got %#v", tensor.Source)
+		}
+		if !reflect.DeepEqual(qs.Data, []int32{10, -20}) {
+			t.Errorf("unexpected data %v", qs.Data)
+		}
+		expected := &QuantizerParams{QScheme: PerTensorAffine, Scale: 0.1, ZeroPoint: 0}
+		if !reflect.DeepEqual(tensor.Quantizer, expected) {
+			t.Errorf("expected %#v, actual %#v", expected, tensor.Quantizer)
+		}
+	})
+
+	t.Run("quint8 per channel", func(t *testing.T) {
+		tensor := loadTensorFromFile(t, "tensor_quint8_per_channel_zip.pt")
+		qs, ok := tensor.Source.(*QUInt8Storage)
+		if !ok {
+			t.Fatalf("expected *QUInt8Storage, got %#v", tensor.Source)
+		}
+		assertIntSliceEqual(t, tensor.Size, []int{2, 2})
+		assertIntSliceEqual(t, tensor.Stride, []int{2, 1})
+		if !reflect.DeepEqual(qs.Data, []uint8{10, 12, 28, 32}) {
+			t.Errorf("unexpected data %v", qs.Data)
+		}
+
+		q := tensor.Quantizer
+		if q == nil || q.QScheme != PerChannelAffine || q.Axis != 0 {
+			t.Fatalf("unexpected quantizer %#v", q)
+		}
+		scales, ok := q.Scales.Source.(*DoubleStorage)
+		if !ok || !reflect.DeepEqual(scales.Data, []float64{0.5, 0.25}) {
+			t.Errorf("unexpected scales %#v", q.Scales.Source)
+		}
+		zeroPoints, ok := q.ZeroPoints.Source.(*LongStorage)
+		if !ok || !reflect.DeepEqual(zeroPoints.Data, []int64{10, 20}) {
+			t.Errorf("unexpected zero points %#v", q.ZeroPoints.Source)
+		}
+	})
+}
+
+func TestRebuildQTensorErrors(t *testing.T) {
+	storage := &QInt8Storage{}
+	testCases := []struct {
+		name   string
+		params []interface{}
+	}{
+		{"empty", nil},
+		{"unknown qscheme", []interface{}{"per_tensor_affine", 0.5, 2}},
+		{"unsupported qscheme", []interface{}{QScheme("other"), 0.5, 2}},
+		{"per tensor arity", []interface{}{PerTensorAffine, 0.5}},
+		{"per tensor scale", []interface{}{PerTensorAffine, "0.5", 2}},
+		{"per channel arity", []interface{}{PerChannelAffine, &Tensor{}, &Tensor{}}},
+		{"per channel scales", []interface{}{PerChannelAffine, 0.5, &Tensor{}, 0}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := (&RebuildQTensor{}).Call(
+				storage, 0, types.NewTupleFromSlice([]interface{}{1}),
+				types.NewTupleFromSlice([]interface{}{1}),
+				types.NewTupleFromSlice(tc.params), false, nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestFloat8StoragesToFloat32(t *testing.T) {
+	testCases := []struct {
+		storage interface {
+			Float32At(i int) float32
+			ToFloat32(dst []float32) int
+		}
+		expected []float32
+	}{
+		{&Float8E4M3FNStorage{Data: []uint8{0x38, 0xb8, 0x7e, 0x01, 0x08, 0x80}},
+			[]float32{1, -1, 448, 0x1p-9, 0x1p-6, float32(math.Copysign(0, -1))}},
+		{&Float8E5M2Storage{Data: []uint8{0x3c, 0xc0, 0x7b, 0x01, 0x7c}},
+			[]float32{1, -2, 57344, 0x1p-16, float32(math.Inf(1))}},
+	}
+	for _, tc := range testCases {
+		actual := make([]float32, len(tc.expected)+1)
+		if n := tc.storage.ToFloat32(actual); n != len(tc.expected) {
+			t.Errorf("%T: expected %d elements converted, actual %d", tc.storage, len(tc.expected), n)
+		}
+		if !reflect.DeepEqual(actual[:len(tc.expected)], tc.expected) {
+			t.Errorf("%T: expected %v, actual %v", tc.storage, tc.expected, actual)
+		}
+		for i, v := range tc.expected {
+			if actual := tc.storage.Float32At(i); actual != v || math.Signbit(float64(actual)) != math.Signbit(float64(v)) {
+				t.Errorf("%T: element %d: expected %v, actual %v", tc.storage, i, v, actual)
+			}
+		}
+	}
+}
+
+func TestDTypeStorages(t *testing.T) {
+	testCases := []struct {
+		storage  StorageInterface
+		data     []byte
+		size     int
+		expected interface{}
+	}{
+		{&Float8E4M3FNStorage{}, []byte{0x38, 0xb8}, 2, []uint8{0x38, 0xb8}},
+		{&Float8E5M2Storage{}, []byte{0x3c, 0xc0}, 2, []uint8{0x3c, 0xc0}},
+		{&UInt16Storage{}, []byte{0x01, 0x00, 0xff, 0xff}, 2, []uint16{1, 0xffff}},
+		{&UInt32Storage{}, []byte{0x01, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, 2, []uint32{1, 0xffffffff}},
+		{&UInt64Storage{}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1, []uint64{math.MaxUint64}},
+		{&Bits1x8Storage{}, []byte{0x01, 0x80}, 2, []uint8{0x01, 0x80}},
+		{&Bits2x4Storage{}, []byte{0x01, 0x80}, 2, []uint8{0x01, 0x80}},
+		{&Bits4x2Storage{}, []byte{0x01, 0x80}, 2, []uint8{0x01, 0x80}},
+		{&Bits8Storage{}, []byte{0x01, 0x80}, 2, []uint8{0x01, 0x80}},
+		{&Bits16Storage{}, []byte{0x01, 0x80}, 1, []uint16{0x8001}},
+	}
+	for _, tc := range testCases {
+		t.Run(reflect.TypeOf(tc.storage).Elem().Name(), func(t *testing.T) {
+			if err := tc.storage.SetFromFileWithSize(bytes.NewReader(tc.data), tc.size); err != nil {
+				t.Fatal(err)
+			}
+			actual := reflect.ValueOf(tc.storage).Elem().FieldByName("Data").Interface()
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, actual %v", tc.expected, actual)
+			}
+			if err := tc.storage.SetFromFileWithSize(bytes.NewReader(tc.data[:len(tc.data)-1]), tc.size); err == nil {
+				t.Error("expected an error for truncated data")
+			}
+		})
+	}
+}
@@ -10,4 +10,32 @@ type Tensor struct {
 	Size          []int
 	Stride        []int
 	RequiresGrad  bool
+	// Quantizer holds the quantization parameters of quantized tensors,
+	// loaded by _rebuild_qtensor; it is nil for the other tensors.
+	Quantizer *QuantizerParams
+	// DType is the data type of tensors loaded by _rebuild_tensor_v3,
+	// whose Source is usually an *UntypedStorage; it is empty for the other
+	// tensors, whose data type is given by their storage type.
+	DType DType
+}
+
+// Storage returns the storage of the tensor. If it is a *LazyStorage, it is
+// materialized, reading its data on the first call. If it is an
+// *UntypedStorage, and the tensor has a DType, a storage of that type is
+// decoded from its bytes (see UntypedStorage.Typed), on the first call for
+// the storage and data type: the tensors sharing the storage get the same
+// decoded storage. Changes to the Data of the untyped storage after that are
+// not reflected in the decoded storage.
+func (t *Tensor) Storage() (StorageInterface, error) {
+	storage := t.Source
+	if lazy, ok := storage.(*LazyStorage); ok {
+		var err error
+		if storage, err = lazy.Materialize(); err != nil {
+			return nil, err
+		}
+	}
+	if untyped, ok := storage.(*UntypedStorage); ok && t.DType != "" {
+		return untyped.cachedTyped(t.DType)
+	}
+	return storage, nil
 }
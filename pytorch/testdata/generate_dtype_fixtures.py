@@ -0,0 +1,223 @@
+#!/usr/bin/env python3
+
+# Copyright 2023 NLP Odyssey Authors. All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+# Writes PyTorch files in the zip format of "torch.save" (PyTorch 1.6 and
+# later) for complex and quantized tensors, as would be written by:
+#
+#     torch.save(torch.tensor([1+2j, -3.5+0.25j], dtype=torch.complex64),
+#                'tensor_complex64_zip.pt')
+#     torch.save(torch.tensor([1+2j, -3.5+0.25j], dtype=torch.complex128),
+#                'tensor_complex128_zip.pt')
+#     torch.save(torch.quantize_per_tensor(
+#                    torch.tensor([-1.0, 0.0, 0.5, 1.0]), 0.5, 2, torch.qint8),
+#                'tensor_qint8_zip.pt')
+#     torch.save(torch.quantize_per_tensor(
+#                    torch.tensor([1.0, -2.0]), 0.1, 0, torch.qint32),
+#                'tensor_qint32_zip.pt')
+#     torch.save(torch.quantize_per_channel(
+#                    torch.tensor([[0.0, 1.0], [2.0, 3.0]]),
+#                    torch.tensor([0.5, 0.25], dtype=torch.float64),
+#                    torch.tensor([10, 20]), 0, torch.quint8),
+#                'tensor_quint8_per_channel_zip.pt')
+#
+# and, with PyTorch 2.x, which pickles the tensors of the data types without
+# a legacy storage class with _rebuild_tensor_v3, an UntypedStorage (whose
+# size is in bytes) and the torch.dtype:
+#
+#     torch.save(torch.tensor([1.0, -1.0, 448.0, 2**-9],
+#                             dtype=torch.float8_e4m3fn),
+#                'tensor_float8_e4m3fn_v3_zip.pt')
+#     torch.save(torch.tensor([1, 65535, 256], dtype=torch.uint16),
+#                'tensor_uint16_v3_zip.pt')
+#
+# NOTE: these fixtures are EMULATED, not written by a real "torch.save" run:
+# no PyTorch package could be installed where they were generated. This
+# script does not depend on torch: the pickles are written by the Python
+# pickle module, with stand-ins for the torch classes and functions,
+# reproducing the opcodes and records of "torch.save" in PyTorch 2.x.
+# Replace the fixtures with the output of the calls above, run with a real
+# PyTorch 2.x, whenever such an environment is available.
+
+import collections
+import io
+import pickle
+import struct
+import sys
+import types
+import zipfile
+
+PICKLE_PROTOCOL = 2
+
+
+def _module(name):
+    m = types.ModuleType(name)
+    sys.modules[name] = m
+    return m
+
+
+torch = _module('torch')
+torch._utils = _module('torch._utils')
+torch.storage = _module('torch.storage')
+
+
+def _function(module, name):
+    def f(*args):
+        raise NotImplementedError
+    f.__module__ = module.__name__
+    f.__name__ = f.__qualname__ = name
+    setattr(module, name, f)
+    return f
+
+
+_rebuild_tensor_v2 = _function(torch._utils, '_rebuild_tensor_v2')
+_rebuild_tensor_v3 = _function(torch._utils, '_rebuild_tensor_v3')
+_rebuild_qtensor = _function(torch._utils, '_rebuild_qtensor')
+# torch.qscheme objects are pickled as globals named after them
+per_tensor_affine = _function(torch, 'per_tensor_affine')
+per_channel_affine = _function(torch, 'per_channel_affine')
+# and so are torch.dtype objects
+float8_e4m3fn = _function(torch, 'float8_e4m3fn')
+uint16 = _function(torch, 'uint16')
+
+
+def _storage_class(name, fmt):
+    cls = type(name, (), {'fmt': fmt})
+    cls.__module__ = 'torch'
+    setattr(torch, name, cls)
+    return cls
+
+
+ComplexFloatStorage = _storage_class('ComplexFloatStorage', 'f')
+ComplexDoubleStorage = _storage_class('ComplexDoubleStorage', 'd')
+QInt8Storage = _storage_class('QInt8Storage', 'b')
+QUInt8Storage = _storage_class('QUInt8Storage', 'B')
+QInt32Storage = _storage_class('QInt32Storage', 'i')
+DoubleStorage = _storage_class('DoubleStorage', 'd')
+LongStorage = _storage_class('LongStorage', 'q')
+UntypedStorage = _storage_class('UntypedStorage', 'B')
+# torch.UntypedStorage is defined in torch.storage
+UntypedStorage.__module__ = 'torch.storage'
+torch.storage.UntypedStorage = UntypedStorage
+
+
+class Storage:
+    def __init__(self, cls, data):
+        self.cls = cls
+        self.data = data
+
+    def numel(self):
+        if self.cls in (ComplexFloatStorage, ComplexDoubleStorage):
+            return len(self.data) // 2
+        return len(self.data)
+
+    def to_bytes(self):
+        return struct.pack(f'<{len(self.data)}{self.cls.fmt}', *self.data)
+
+
+def _strides(size):
+    strides = []
+    stride = 1
+    for s in reversed(size):
+        strides.insert(0, stride)
+        stride *= s
+    return tuple(strides)
+
+
+class Tensor:
+    def __init__(self, cls, data, size):
+        self.storage = Storage(cls, data)
+        self.size = size
+
+    def __reduce__(self):
+        return _rebuild_tensor_v2, (self.storage, 0, self.size,
+                                    _strides(self.size), False,
+                                    collections.OrderedDict())
+
+
+class TensorV3:
+    def __init__(self, data, dtype, size, metadata=None):
+        self.storage = Storage(UntypedStorage, data)
+        self.dtype = dtype
+        self.size = size
+        self.metadata = metadata
+
+    def __reduce__(self):
+        args = (self.storage, 0, self.size, _strides(self.size), False,
+                collections.OrderedDict(), self.dtype)
+        if self.metadata is not None:
+            args += (self.metadata,)
+        return _rebuild_tensor_v3, args
+
+
+class QTensor:
+    def __init__(self, cls, data, size, quantizer_params):
+        self.storage = Storage(cls, data)
+        self.size = size
+        self.quantizer_params = quantizer_params
+
+    def __reduce__(self):
+        return _rebuild_qtensor, (self.storage, 0, self.size,
+                                  _strides(self.size), self.quantizer_params,
+                                  False, collections.OrderedDict())
+
+
+def _write(zf, name, data):
+    # a fixed date makes the archive reproducible
+    zf.writestr(zipfile.ZipInfo(name, date_time=(1980, 1, 1, 0, 0, 0)), data)
+
+
+def save(obj, filename):
+    archive = filename[:-len('.pt')]
+    storages = []
+
+    def persistent_id(obj):
+        if isinstance(obj, Storage):
+            key = str(len(storages))
+            storages.append(obj)
+            return ('storage', obj.cls, key, 'cpu',
+                    obj.numel())
+        return None
+
+    f = io.BytesIO()
+    pickler = pickle.Pickler(f, protocol=PICKLE_PROTOCOL)
+    pickler.persistent_id = persistent_id
+    pickler.dump(obj)
+
+    with zipfile.ZipFile(filename, 'w') as zf:
+        _write(zf, f'{archive}/data.pkl', f.getvalue())
+        _write(zf, f'{archive}/byteorder', 'little')
+        for key, storage in enumerate(storages):
+            _write(zf, f'{archive}/data/{key}', storage.to_bytes())
+        _write(zf, f'{archive}/version', '3\n')
+
+
+def main():
+    complex_data = [1.0, 2.0, -3.5, 0.25]
+    save(Tensor(ComplexFloatStorage, complex_data, (2,)),
+         'tensor_complex64_zip.pt')
+    save(Tensor(ComplexDoubleStorage, complex_data, (2,)),
+         'tensor_complex128_zip.pt')
+    save(QTensor(QInt8Storage, [0, 2, 3, 4], (4,),
+                 (per_tensor_affine, 0.5, 2)),
+         'tensor_qint8_zip.pt')
+    save(QTensor(QInt32Storage, [10, -20], (2,),
+                 (per_tensor_affine, 0.1, 0)),
+         'tensor_qint32_zip.pt')
+    save(QTensor(QUInt8Storage, [10, 12, 28, 32], (2, 2),
+                 (per_channel_affine,
+                  Tensor(DoubleStorage, [0.5, 0.25], (2,)),
+                  Tensor(LongStorage, [10, 20], (2,)),
+                  0)),
+         'tensor_quint8_per_channel_zip.pt')
+
+    save(TensorV3([0x38, 0xb8, 0x7e, 0x01], float8_e4m3fn, (4,)),
+         'tensor_float8_e4m3fn_v3_zip.pt')
+    save(TensorV3(list(struct.pack('<3H', 1, 65535, 256)), uint16, (3,), {}),
+         'tensor_uint16_v3_zip.pt')
+
+
+if __name__ == '__main__':
+    main()
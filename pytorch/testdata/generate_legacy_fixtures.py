@@ -0,0 +1,241 @@
+#!/usr/bin/env python3
+
+# Copyright 2023 NLP Odyssey Authors. All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+# Writes checkpoints in legacy formats of PyTorch:
+#
+#   - the tar format of PyTorch before version 0.1.10;
+#   - the non-tar format of PyTorch before version 1.6, with storage views,
+#     which are only written by PyTorch before version 0.4.
+#
+# NOTE: legacy_tar.pt and legacy_views.pt are EMULATED checkpoints, not
+# files written by a real "torch.save" run. PyTorch 0.1.9 (the last version
+# writing the tar format) and 0.3.1 (the last version writing storage views)
+# only support Python up to 3.6 and could not be installed where these
+# fixtures were generated, so this script does not depend on torch: it
+# reproduces the "_save" function of torch/serialization.py of those
+# versions, writing the same pickles and binary layouts. The torch classes
+# referenced by the pickles are replaced by stand-ins, with the same module
+# and names. Replace the fixtures with the output of a real PyTorch 0.1.9 and
+# 0.3.1 whenever such an environment is available.
+
+import collections
+import io
+import pickle
+import struct
+import sys
+import tarfile
+import types
+
+MAGIC_NUMBER = 0x1950a86a20f9469cfc6c
+PROTOCOL_VERSION = 1001
+PICKLE_PROTOCOL = 2
+
+torch = types.ModuleType('torch')
+torch._utils = types.ModuleType('torch._utils')
+sys.modules['torch'] = torch
+sys.modules['torch._utils'] = torch._utils
+
+
+class _Storage:
+    def __init__(self, data, root=None, offset=0):
+        self.data = data
+        self.root = root
+        self.offset = offset
+
+    def view(self, offset, size):
+        """Returns storage[offset:offset+size]."""
+        return type(self)(self.data[offset:offset + size], self, offset)
+
+    def write_file(self, f):
+        f.write(struct.pack('<q', len(self.data)))
+        f.write(struct.pack(f'<{len(self.data)}{self.fmt}', *self.data))
+
+
+def _storage_class(name, fmt):
+    cls = type(name, (_Storage,), {'fmt': fmt, '__module__': 'torch'})
+    setattr(torch, name, cls)
+    return cls
+
+
+FloatStorage = _storage_class('FloatStorage', 'f')
+LongStorage = _storage_class('LongStorage', 'q')
+
+
+class _Tensor:
+    def __init__(self, storage, offset, size, stride):
+        self.storage = storage
+        self.offset = offset
+        self.size = size
+        self.stride = stride
+
+    def write_metadata(self, f):
+        # the number of dimensions takes 8 bytes, but only the first 4
+        # bytes are read by torch.serialization.legacy_load
+        f.write(struct.pack('<q', len(self.size)))
+        f.write(struct.pack(f'<{len(self.size)}q', *self.size))
+        f.write(struct.pack(f'<{len(self.stride)}q', *self.stride))
+        f.write(struct.pack('<q', self.offset))
+
+
+FloatTensor = type('FloatTensor', (_Tensor,), {'__module__': 'torch'})
+torch.FloatTensor = FloatTensor
+
+
+def _rebuild_tensor(storage, storage_offset, size, stride):
+    raise NotImplementedError
+
+
+_rebuild_tensor.__module__ = 'torch._utils'
+torch._utils._rebuild_tensor = _rebuild_tensor
+
+
+def _rebuild_tensor_v2(storage, storage_offset, size, stride, requires_grad,
+                       backward_hooks):
+    raise NotImplementedError
+
+
+_rebuild_tensor_v2.__module__ = 'torch._utils'
+torch._utils._rebuild_tensor_v2 = _rebuild_tensor_v2
+
+
+class RebuiltTensor:
+    """A tensor pickled by reduction, as with PyTorch 0.2 and 0.3."""
+
+    def __init__(self, storage, offset, size, stride):
+        self.args = (storage, offset, size, stride)
+
+    def __reduce__(self):
+        return _rebuild_tensor, self.args
+
+
+class RebuiltTensorV2(RebuiltTensor):
+    """A tensor pickled by reduction, as with PyTorch 0.4 and later."""
+
+    def __reduce__(self):
+        return _rebuild_tensor_v2, self.args + (False, collections.OrderedDict())
+
+
+def save_tar(obj, filename):
+    serialized_tensors = {}
+    serialized_storages = {}
+
+    def persistent_id(obj):
+        if isinstance(obj, _Tensor):
+            serialized_tensors[id(obj)] = obj
+            return str(id(obj))
+        if isinstance(obj, _Storage):
+            serialized_storages[id(obj)] = obj
+            return str(id(obj))
+        return None
+
+    def save_sys_info(f):
+        sys_info = dict(
+            protocol_version=PROTOCOL_VERSION,
+            little_endian=True,
+            type_sizes=dict(short=2, int=4, long=8),
+        )
+        pickle.dump(sys_info, f, protocol=PICKLE_PROTOCOL)
+
+    def pickle_objects(f):
+        pickler = pickle.Pickler(f, protocol=PICKLE_PROTOCOL)
+        pickler.persistent_id = persistent_id
+        pickler.dump(obj)
+
+    def save_tensors(f):
+        pickle.dump(len(serialized_tensors), f, protocol=PICKLE_PROTOCOL)
+        for key, tensor in serialized_tensors.items():
+            storage_id = id(tensor.storage)
+            serialized_storages[storage_id] = tensor.storage
+            pickle.dump((key, storage_id, type(tensor)), f,
+                        protocol=PICKLE_PROTOCOL)
+            tensor.write_metadata(f)
+
+    def save_storages(f):
+        storage_views = []
+        storage_views_roots = {}
+        for key, storage in serialized_storages.items():
+            if storage.root is not None:
+                storage_views_roots[id(storage.root)] = storage.root
+                storage_views.append((key, id(storage.root), storage.offset,
+                                      len(storage.data)))
+        for view_info in storage_views:
+            del serialized_storages[view_info[0]]
+        serialized_storages.update(storage_views_roots)
+
+        pickle.dump(len(serialized_storages), f, protocol=PICKLE_PROTOCOL)
+        for key, storage in serialized_storages.items():
+            pickle.dump((key, 'cpu', type(storage)), f,
+                        protocol=PICKLE_PROTOCOL)
+            storage.write_file(f)
+        pickle.dump(storage_views, f, protocol=PICKLE_PROTOCOL)
+
+    with tarfile.open(filename, mode='w:', format=tarfile.PAX_FORMAT) as tar:
+        for name, fn in [('sys_info', save_sys_info),
+                         ('pickle', pickle_objects),
+                         ('tensors', save_tensors),
+                         ('storages', save_storages)]:
+            buf = io.BytesIO()
+            fn(buf)
+            info = tarfile.TarInfo(name)
+            info.size = buf.tell()
+            buf.seek(0)
+            tar.addfile(info, buf)
+
+
+def save_no_tar(obj, filename):
+    serialized_storages = {}
+
+    def persistent_id(obj):
+        if isinstance(obj, _Storage):
+            root = obj if obj.root is None else obj.root
+            root_key = str(id(root))
+            serialized_storages[root_key] = root
+            view_metadata = None
+            if obj.root is not None:
+                view_metadata = (str(id(obj)), obj.offset, len(obj.data))
+            return ('storage', type(obj), root_key, 'cpu', len(root.data),
+                    view_metadata)
+        return None
+
+    with open(filename, 'wb') as f:
+        pickle.dump(MAGIC_NUMBER, f, protocol=PICKLE_PROTOCOL)
+        pickle.dump(PROTOCOL_VERSION, f, protocol=PICKLE_PROTOCOL)
+        pickle.dump(dict(protocol_version=PROTOCOL_VERSION, little_endian=True,
+                         type_sizes=dict(short=2, int=4, long=8)),
+                    f, protocol=PICKLE_PROTOCOL)
+        pickler = pickle.Pickler(f, protocol=PICKLE_PROTOCOL)
+        pickler.persistent_id = persistent_id
+        pickler.dump(obj)
+        keys = sorted(serialized_storages.keys())
+        pickle.dump(keys, f, protocol=PICKLE_PROTOCOL)
+        for key in keys:
+            serialized_storages[key].write_file(f)
+
+
+def main():
+    weights = FloatStorage([1.5, -2.5, 3.5, -4.5, 5.5, -6.5, 0.25, 0.5, 0.75])
+    window = weights.view(2, 4)
+    steps = LongStorage([1, 2, 3])
+    save_tar({
+        'weight': FloatTensor(weights, 0, (2, 3), (3, 1)),
+        'bias': FloatTensor(weights, 6, (3,), (1,)),
+        'rebuilt': RebuiltTensor(weights, 3, (3,), (1,)),
+        'window': FloatTensor(window, 1, (2,), (1,)),
+        'steps': steps,
+    }, 'legacy_tar.pt')
+
+    weights = FloatStorage([1.5, -2.5, 3.5, -4.5, 5.5, -6.5, 0.25, 0.5, 0.75])
+    window = weights.view(2, 4)
+    save_no_tar({
+        'weight': RebuiltTensorV2(weights, 0, (2, 3), (3, 1)),
+        'window': RebuiltTensorV2(window, 1, (2,), (1,)),
+        'window_again': RebuiltTensorV2(window, 0, (4,), (1,)),
+        'tail': RebuiltTensorV2(weights.view(6, 3), 0, (3,), (1,)),
+    }, 'legacy_views.pt')
+
+
+if __name__ == '__main__':
+    main()
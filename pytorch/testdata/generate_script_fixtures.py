@@ -0,0 +1,205 @@
+#!/usr/bin/env python3
+
+# Copyright 2023 NLP Odyssey Authors. All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+# Writes a TorchScript archive, with the layout of "torch.jit.save" in
+# PyTorch 1.x and 2.x, for the module which would be written by:
+#
+#     class Net(torch.nn.Module):
+#         config: Dict[str, int]
+#
+#         def __init__(self):
+#             super().__init__()
+#             self.fc = torch.nn.Linear(3, 2)
+#             self.register_buffer('scale', torch.tensor([2.0]))
+#             self.config = {'depth': 1}
+#
+#         def forward(self, x):
+#             return self.fc(x) * self.scale + torch.tensor([1.0, 2.0])
+#
+#     torch.jit.save(torch.jit.script(Net()), 'script_module.pt')
+#
+# This script does not depend on torch, so that the fixture is reproducible
+# without installing it: the pickles are written by the Python pickle module,
+# with stand-ins for the torch classes, producing the same opcodes as the
+# TorchScript pickler (GLOBAL, EMPTY_TUPLE and NEWOBJ for the objects, then
+# BUILD with a dict of attributes; _rebuild_tensor_v2 with storage persistent
+# IDs for the tensors).
+
+import collections
+import pickle
+import struct
+import sys
+import types
+import zipfile
+
+PICKLE_PROTOCOL = 2
+ARCHIVE = 'script_module'
+
+
+def _module(name):
+    m = types.ModuleType(name)
+    sys.modules[name] = m
+    return m
+
+
+torch = _module('torch')
+torch._utils = _module('torch._utils')
+torch.jit = _module('torch.jit')
+torch.jit._pickle = _module('torch.jit._pickle')
+torch_ns = _module('__torch__')
+linear_ns = _module('__torch__.torch.nn.modules.linear')
+
+
+def _function(module, name):
+    def f(*args):
+        raise NotImplementedError
+    f.__module__ = module.__name__
+    f.__name__ = f.__qualname__ = name
+    setattr(module, name, f)
+    return f
+
+
+_rebuild_tensor_v2 = _function(torch._utils, '_rebuild_tensor_v2')
+restore_type_tag = _function(torch.jit._pickle, 'restore_type_tag')
+
+
+class FloatStorage:
+    def __init__(self, data):
+        self.data = data
+
+
+FloatStorage.__module__ = 'torch'
+torch.FloatStorage = FloatStorage
+
+
+class Tensor:
+    def __init__(self, data, size):
+        self.storage = FloatStorage(data)
+        self.size = size
+        self.stride = tuple(
+            _prod(size[i + 1:]) for i in range(len(size)))
+
+    def __reduce__(self):
+        return _rebuild_tensor_v2, (self.storage, 0, self.size, self.stride,
+                                    False, collections.OrderedDict())
+
+
+def _prod(values):
+    result = 1
+    for v in values:
+        result *= v
+    return result
+
+
+class TypeTagged:
+    def __init__(self, value, type_str):
+        self.value = value
+        self.type_str = type_str
+
+    def __reduce__(self):
+        return restore_type_tag, (self.value, self.type_str)
+
+
+class Net:
+    pass
+
+
+Net.__module__ = '__torch__'
+torch_ns.Net = Net
+
+
+class Linear:
+    pass
+
+
+Linear.__module__ = '__torch__.torch.nn.modules.linear'
+linear_ns.Linear = Linear
+
+
+def _write(zf, name, data):
+    # a fixed date makes the archive reproducible
+    zf.writestr(zipfile.ZipInfo(name, date_time=(1980, 1, 1, 0, 0, 0)), data)
+
+
+def write_archive(zf, name, obj):
+    storages = []
+
+    def persistent_id(obj):
+        if isinstance(obj, FloatStorage):
+            key = str(len(storages))
+            storages.append(obj)
+            return ('storage', FloatStorage, key, 'cpu', len(obj.data))
+        return None
+
+    with zf.open(f'{ARCHIVE}/{name}.pkl', 'w') as f:
+        pickler = pickle.Pickler(f, protocol=PICKLE_PROTOCOL)
+        pickler.persistent_id = persistent_id
+        pickler.dump(obj)
+    for key, storage in enumerate(storages):
+        data = struct.pack(f'<{len(storage.data)}f', *storage.data)
+        _write(zf, f'{ARCHIVE}/{name}/{key}', data)
+
+
+NET_CODE = '''\
+class Net(Module):
+  __parameters__ = []
+  __buffers__ = ["scale", ]
+  scale : Tensor
+  training : bool
+  _is_full_backward_hook : Optional[bool]
+  config : Dict[str, int]
+  fc : __torch__.torch.nn.modules.linear.Linear
+  def forward(self: __torch__.Net,
+    x: Tensor) -> Tensor:
+    fc = self.fc
+    _0 = torch.mul((fc).forward(x, ), self.scale)
+    return torch.add(_0, CONSTANTS.c0)
+'''
+
+LINEAR_CODE = '''\
+class Linear(Module):
+  __parameters__ = ["weight", "bias", ]
+  __buffers__ = []
+  weight : Tensor
+  bias : Tensor
+  training : bool
+  _is_full_backward_hook : Optional[bool]
+  in_features : Final[int] = 3
+  out_features : Final[int] = 2
+  def forward(self: __torch__.torch.nn.modules.linear.Linear,
+    input: Tensor) -> Tensor:
+    weight = self.weight
+    bias = self.bias
+    return torch.linear(input, weight, bias)
+'''
+
+
+def main():
+    fc = Linear()
+    fc.training = True
+    fc._is_full_backward_hook = None
+    fc.weight = Tensor([0.5, -1.0, 1.5, -2.0, 2.5, -3.0], (2, 3))
+    fc.bias = Tensor([0.25, -0.25], (2,))
+
+    net = Net()
+    net.training = True
+    net._is_full_backward_hook = None
+    net.config = TypeTagged({'depth': 1}, 'Dict[str, int]')
+    net.fc = fc
+    net.scale = Tensor([2.0], (1,))
+
+    with zipfile.ZipFile('script_module.pt', 'w') as zf:
+        write_archive(zf, 'data', net)
+        _write(zf, f'{ARCHIVE}/code/__torch__.py', NET_CODE)
+        _write(zf, f'{ARCHIVE}/code/__torch__.py.debug_pkl', b'\x80\x02).')
+        _write(zf, f'{ARCHIVE}/code/__torch__/torch/nn/modules/linear.py',
+               LINEAR_CODE)
+        write_archive(zf, 'constants', (Tensor([1.0, 2.0], (2,)),))
+        _write(zf, f'{ARCHIVE}/version', '3\n')
+
+
+if __name__ == '__main__':
+    main()
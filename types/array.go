@@ -32,11 +32,11 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("invalid array type argument %T", args[1])
 	}
 
-	mi, ok := args[2].(int)
-	if !ok {
-		return nil, fmt.Errorf("invalid array mformat code type %T", args[2])
+	mi, err := NewTupleFromSlice(args).GetInt(2)
+	if err != nil {
+		return nil, fmt.Errorf("invalid array mformat code: %w", err)
 	}
-	if mi >= len(arrayDescriptors) {
+	if mi < 0 || mi >= len(arrayDescriptors) {
 		return nil, fmt.Errorf("invalid array mformat value %d", mi)
 	}
 	descr := arrayDescriptors[mi]
@@ -46,6 +46,13 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("invalid array payload type %T", args[3])
 	}
 
+	if (typ == "f" || typ == "d") && descr.Size != 4 && descr.Size != 8 {
+		return nil, fmt.Errorf("invalid array mformat value %d for typecode '%s'", mi, typ)
+	}
+	if typ != "u" && len(raw)%descr.Size != 0 {
+		return nil, fmt.Errorf("invalid array payload length %d for item size %d", len(raw), descr.Size)
+	}
+
 	switch typ {
 	case "b":
 		vs := make([]int8, len(raw))
@@ -102,7 +109,7 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		sz := descr.Size
 		vs := make([]int16, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = int16(descr.Order.Uint16(raw[i:]))
+			vs[i/sz] = int16(descr.int(raw[i:]))
 		}
 		return vs, nil
 
@@ -110,7 +117,7 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		sz := descr.Size
 		vs := make([]uint16, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = descr.Order.Uint16(raw[i:])
+			vs[i/sz] = uint16(descr.uint(raw[i:]))
 		}
 		return vs, nil
 
@@ -118,7 +125,7 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		sz := descr.Size
 		vs := make([]int32, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = int32(descr.Order.Uint32(raw[i:]))
+			vs[i/sz] = int32(descr.int(raw[i:]))
 		}
 		return vs, nil
 
@@ -126,39 +133,23 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		sz := descr.Size
 		vs := make([]uint32, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = descr.Order.Uint32(raw[i:])
+			vs[i/sz] = uint32(descr.uint(raw[i:]))
 		}
 		return vs, nil
 
-	case "l":
+	case "l", "q":
 		sz := descr.Size
 		vs := make([]int64, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = int64(descr.Order.Uint64(raw[i:]))
+			vs[i/sz] = descr.int(raw[i:])
 		}
 		return vs, nil
 
-	case "L":
+	case "L", "Q":
 		sz := descr.Size
 		vs := make([]uint64, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = descr.Order.Uint64(raw[i:])
-		}
-		return vs, nil
-
-	case "q":
-		sz := descr.Size
-		vs := make([]int64, len(raw)/sz)
-		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = int64(descr.Order.Uint64(raw[i:]))
-		}
-		return vs, nil
-
-	case "Q":
-		sz := descr.Size
-		vs := make([]uint64, len(raw)/sz)
-		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = descr.Order.Uint64(raw[i:])
+			vs[i/sz] = descr.uint(raw[i:])
 		}
 		return vs, nil
 
@@ -166,7 +157,7 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		sz := descr.Size
 		vs := make([]float32, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = math.Float32frombits(descr.Order.Uint32(raw[i:]))
+			vs[i/sz] = float32(descr.float(raw[i:]))
 		}
 		return vs, nil
 
@@ -174,15 +165,13 @@ func (Array) Call(args ...interface{}) (interface{}, error) {
 		sz := descr.Size
 		vs := make([]float64, len(raw)/sz)
 		for i := 0; i < len(raw); i += sz {
-			vs[i/sz] = math.Float64frombits(descr.Order.Uint64(raw[i:]))
+			vs[i/sz] = descr.float(raw[i:])
 		}
 		return vs, nil
 
 	default:
 		return nil, fmt.Errorf("invalid array typecode '%s'", typ)
 	}
-
-	panic("impossible")
 }
 
 type arrayDescriptor struct {
@@ -191,6 +180,41 @@ type arrayDescriptor struct {
 	Order  binary.ByteOrder
 }
 
+// uint decodes an unsigned integer of the descriptor's size from the
+// beginning of b.
+func (d arrayDescriptor) uint(b []byte) uint64 {
+	switch d.Size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(d.Order.Uint16(b))
+	case 4:
+		return uint64(d.Order.Uint32(b))
+	default:
+		return d.Order.Uint64(b)
+	}
+}
+
+// int decodes a signed integer of the descriptor's size from the beginning
+// of b, sign-extending it only if the descriptor is signed.
+func (d arrayDescriptor) int(b []byte) int64 {
+	u := d.uint(b)
+	if !d.Signed || d.Size == 8 {
+		return int64(u)
+	}
+	shift := 64 - 8*uint(d.Size)
+	return int64(u<<shift) >> shift
+}
+
+// float decodes a floating point number of the descriptor's size from the
+// beginning of b.
+func (d arrayDescriptor) float(b []byte) float64 {
+	if d.Size == 4 {
+		return float64(math.Float32frombits(d.Order.Uint32(b)))
+	}
+	return math.Float64frombits(d.Order.Uint64(b))
+}
+
 var (
 	arrayDescriptors = []arrayDescriptor{
 		0:  {Size: 1, Signed: false, Order: binary.LittleEndian}, // 0: UNSIGNED_INT8
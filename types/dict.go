@@ -6,7 +6,6 @@ package types
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 )
 
@@ -18,9 +17,15 @@ type DictSetter interface {
 
 // Dict represents a Python "dict" (builtin type).
 //
-// It is implemented as a slice, instead of a map, because in Go not
-// all types can be map's keys (e.g. slices).
-type Dict []DictEntry
+// Like Python dicts, it preserves the insertion order of the keys, which
+// are compared according to Python semantics (see Hash and Equal): for
+// example, 1, 1.0 and true are the same key.
+//
+// The zero value is an empty Dict ready to use.
+type Dict struct {
+	entries []DictEntry
+	index   hashIndex
+}
 
 type DictEntry struct {
 	Key   interface{}
@@ -31,13 +36,24 @@ var _ DictSetter = &Dict{}
 
 // NewDict makes and returns a new empty Dict.
 func NewDict() *Dict {
-	d := make(Dict, 0, 4)
-	return &d
+	return &Dict{entries: make([]DictEntry, 0, 4)}
 }
 
-// Set sets into the Dict the given key/value pair.
+// Set sets into the Dict the given key/value pair. If an equal key already
+// exists, its value is replaced, keeping the original key and position.
+//
+// It panics if the key is not hashable (see Hash).
 func (d *Dict) Set(key, value interface{}) {
-	*d = append(*d, DictEntry{
+	h, err := pyHash(key, 0)
+	if err != nil {
+		panic(err)
+	}
+	if i := d.find(uint64(h), key); i >= 0 {
+		d.entries[i].Value = value
+		return
+	}
+	d.index.insert(uint64(h))
+	d.entries = append(d.entries, DictEntry{
 		Key:   key,
 		Value: value,
 	})
@@ -46,14 +62,20 @@ func (d *Dict) Set(key, value interface{}) {
 // Get returns the value associated with the given key (if any), and whether
 // the key is present or not.
 func (d *Dict) Get(key interface{}) (interface{}, bool) {
-	for _, entry := range *d {
-		if reflect.DeepEqual(entry.Key, key) {
-			return entry.Value, true
-		}
+	h, err := pyHash(key, 0)
+	if err != nil {
+		return nil, false
+	}
+	if i := d.find(uint64(h), key); i >= 0 {
+		return d.entries[i].Value, true
 	}
 	return nil, false
 }
 
+func (d *Dict) find(h uint64, key interface{}) int {
+	return d.index.find(h, key, func(i int) interface{} { return d.entries[i].Key }, 0)
+}
+
 // MustGet returns the value associated with the given key, if if it exists,
 // otherwise it panics.
 func (d *Dict) MustGet(key interface{}) interface{} {
@@ -67,19 +89,45 @@ func (d *Dict) MustGet(key interface{}) interface{} {
 // Len returns the length of the Dict, that is, the amount of key/value pairs
 // contained by the Dict.
 func (d *Dict) Len() int {
-	return len(*d)
+	return len(d.entries)
 }
 
 // Keys returns the keys of the dict
 func (d *Dict) Keys() []interface{} {
-	out := make([]interface{}, len(*d))
-	for i, entry := range *d {
+	out := make([]interface{}, len(d.entries))
+	for i, entry := range d.entries {
 		out[i] = entry.Key
 	}
 
 	return out
 }
 
+// Entries returns the key/value pairs of the Dict, in insertion order.
+//
+// The returned slice is shared with the Dict, and must not be modified.
+func (d *Dict) Entries() []DictEntry {
+	return d.entries
+}
+
+// equal compares d with another Dict, like Python "==": they are equal if
+// they have the same keys, associated with equal values.
+func (d *Dict) equal(other *Dict, depth int) bool {
+	if d.Len() != other.Len() {
+		return false
+	}
+	for _, e := range d.entries {
+		h, err := pyHash(e.Key, depth+1)
+		if err != nil {
+			return false
+		}
+		i := other.index.find(uint64(h), e.Key, func(i int) interface{} { return other.entries[i].Key }, depth+1)
+		if i < 0 || !pyEqual(e.Value, other.entries[i].Value, depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
 func (*Dict) Call(args ...interface{}) (interface{}, error) {
 	if len(args) == 0 {
 		return NewDict(), nil
@@ -96,7 +144,7 @@ func (d *Dict) String() string {
 	}
 	o := new(strings.Builder)
 	o.WriteString("{")
-	for i, e := range *d {
+	for i, e := range d.entries {
 		if i > 0 {
 			o.WriteString(", ")
 		}
@@ -7,6 +7,7 @@ package types
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"testing"
 )
 
@@ -46,3 +47,40 @@ func TestDictStringer(t *testing.T) {
 		t.Fatalf("got= %q\nwant=%q", got, want)
 	}
 }
+
+func TestDictSetReplacesEqualKeys(t *testing.T) {
+	d := NewDict()
+	d.Set(1, "a")
+	d.Set("x", "b")
+	d.Set(1.0, "c")
+	d.Set(true, "d")
+	d.Set(&Tuple{1, "y"}, "e")
+	d.Set(&Tuple{1.0, "y"}, "f")
+
+	if d.Len() != 3 {
+		t.Fatalf("expected 3 entries, got %v", d)
+	}
+	entries := d.Entries()
+	if entries[0].Key != 1 || entries[0].Value != "d" {
+		t.Errorf("expected 1: d as first entry, got %#v", entries[0])
+	}
+	if v, ok := d.Get(big.NewInt(1)); !ok || v != "d" {
+		t.Errorf("expected d, got %#v", v)
+	}
+	if v, ok := d.Get(&Tuple{true, "y"}); !ok || v != "f" {
+		t.Errorf("expected f, got %#v", v)
+	}
+	if _, ok := d.Get(NewList()); ok {
+		t.Error("expected an unhashable key not to be found")
+	}
+}
+
+func TestDictBytesKeys(t *testing.T) {
+	var d Dict
+	d.Set([]byte("a"), 1)
+	d.Set([]byte("a"), 2)
+	d.Set("a", 3)
+	if v, ok := d.Get([]byte("a")); d.Len() != 2 || !ok || v != 2 {
+		t.Errorf("unexpected dict %v", &d)
+	}
+}
@@ -6,30 +6,59 @@ package types
 
 // FrozenSet represents a Python "frozenset" (builtin type).
 //
-// It is implemented in Go as a map with empty struct values; the actual set
-// of generic "interface{}" items is thus represented by all the keys.
-type FrozenSet map[interface{}]frozenSetEmptyStruct
-
-type frozenSetEmptyStruct struct{}
+// Items are compared according to Python semantics (see Hash and Equal):
+// for example, 1, 1.0 and true are the same item. Iteration follows the
+// insertion order. Unlike a Set, a FrozenSet is hashable.
+type FrozenSet struct {
+	items []interface{}
+	index hashIndex
+	// hashAcc accumulates the hashes of the items (see frozenSetHash).
+	hashAcc uint64
+}
 
 // NewFrozenSetFromSlice makes and returns a new FrozenSet initialized
-// with the elements of the given slice.
+// with the elements of the given slice, ignoring duplicates.
+//
+// It panics if any element is not hashable (see Hash).
 func NewFrozenSetFromSlice(slice []interface{}) *FrozenSet {
-	f := make(FrozenSet, len(slice))
+	f := &FrozenSet{items: make([]interface{}, 0, len(slice))}
 	for _, item := range slice {
-		f[item] = frozenSetEmptyStruct{}
+		var h uint64
+		var added bool
+		f.items, h, added = f.index.add(item, f.items)
+		if added {
+			f.hashAcc ^= shuffleBits(h)
+		}
 	}
-	return &f
+	return f
 }
 
 // Len returns the length of the FrozenSet.
 func (f *FrozenSet) Len() int {
-	return len(*f)
+	return len(f.items)
 }
 
 // Has returns whether the given value is present in the FrozenSet (true)
 // or not (false).
 func (f *FrozenSet) Has(v interface{}) bool {
-	_, ok := (*f)[v]
-	return ok
+	return f.index.lookup(v, f.items, 0) >= 0
+}
+
+// Items returns the elements of the FrozenSet, in insertion order.
+//
+// The returned slice is shared with the FrozenSet, and must not be
+// modified.
+func (f *FrozenSet) Items() []interface{} {
+	return f.items
+}
+
+// Call returns a new FrozenSet with the items of the optional argument,
+// which must be a List, Tuple, Set or FrozenSet. It is equivalent to the
+// Python "frozenset" constructor.
+func (*FrozenSet) Call(args ...interface{}) (interface{}, error) {
+	items, err := setCallItems("FrozenSet", args)
+	if err != nil {
+		return nil, err
+	}
+	return NewFrozenSetFromSlice(items), nil
 }
@@ -0,0 +1,21 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import "testing"
+
+// FuzzArrayCall checks that Array.Call never panics, whatever the
+// combination of typecode, machine format and payload.
+func FuzzArrayCall(f *testing.F) {
+	for _, typ := range []string{"b", "B", "u", "h", "H", "i", "I", "l", "L", "q", "Q", "f", "d"} {
+		for mi := -1; mi <= len(arrayDescriptors); mi++ {
+			f.Add(typ, mi, []byte{1, 2, 3})
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, typ string, mformat int, raw []byte) {
+		_, _ = Array{}.Call(nil, typ, mformat, raw)
+	})
+}
@@ -11,11 +11,20 @@ type GenericClass struct {
 
 var _ PyNewable = &GenericClass{}
 
+// GenericObject represents an instance of a class which is not otherwise
+// implemented.
 type GenericObject struct {
 	Class           *GenericClass
 	ConstructorArgs []interface{}
+	// State is the value the object was built with by the pickle BUILD
+	// opcode, if any: usually a Dict of attributes, as returned by the
+	// default Python "__getstate__", or a two-item Tuple of attribute and
+	// slot Dicts.
+	State interface{}
 }
 
+var _ PyStateSettable = &GenericObject{}
+
 func NewGenericClass(module, name string) *GenericClass {
 	return &GenericClass{Module: module, Name: name}
 }
@@ -26,3 +35,9 @@ func (g *GenericClass) PyNew(args ...interface{}) (interface{}, error) {
 		ConstructorArgs: args,
 	}, nil
 }
+
+// PySetState stores the given state into the object.
+func (g *GenericObject) PySetState(state interface{}) error {
+	g.State = state
+	return nil
+}
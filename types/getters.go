@@ -0,0 +1,381 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// ErrUnexpectedType is reported by the typed getters of Tuple, List, Dict
+// and OrderedDict (such as GetInt) when the value has a different type.
+var ErrUnexpectedType = errors.New("unexpected type")
+
+// getterItem is an item looked up by a typed getter, with the context
+// needed to describe errors.
+type getterItem struct {
+	// method is the name of the getter, e.g. "Tuple.GetInt".
+	method string
+	key    interface{}
+	value  interface{}
+	err    error
+}
+
+func (t *Tuple) item(method string, i int) getterItem {
+	return sequenceItem("Tuple."+method, *t, i)
+}
+
+func (l *List) item(method string, i int) getterItem {
+	return sequenceItem("List."+method, *l, i)
+}
+
+func sequenceItem(method string, items []interface{}, i int) getterItem {
+	it := getterItem{method: method, key: i}
+	if i < 0 || i >= len(items) {
+		it.err = fmt.Errorf("%w (length %d)", ErrIndexOutOfRange, len(items))
+		return it
+	}
+	it.value = items[i]
+	return it
+}
+
+func (d *Dict) item(method string, key interface{}) getterItem {
+	it := getterItem{method: "Dict." + method, key: key}
+	it.value, it.err = mappingValue(d.Get(key))
+	return it
+}
+
+func (o *OrderedDict) item(method string, key interface{}) getterItem {
+	it := getterItem{method: "OrderedDict." + method, key: key}
+	it.value, it.err = mappingValue(o.Get(key))
+	return it
+}
+
+func mappingValue(value interface{}, ok bool) (interface{}, error) {
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// error describes a failure of the getter, wrapping err.
+func (it getterItem) error(err error) error {
+	return fmt.Errorf("%s(%s): %w", it.method, Repr(it.key), err)
+}
+
+// typeError describes a value which doesn't have the expected type.
+func (it getterItem) typeError(expected string) error {
+	return it.error(fmt.Errorf("%w: expected %s, got %T", ErrUnexpectedType, expected, it.value))
+}
+
+func (it getterItem) int() (int, error) {
+	n, err := it.int64()
+	if err != nil {
+		return 0, err
+	}
+	if n < math.MinInt || n > math.MaxInt {
+		return 0, it.error(fmt.Errorf("value %d overflows int", n))
+	}
+	return int(n), nil
+}
+
+func (it getterItem) int64() (int64, error) {
+	if it.err != nil {
+		return 0, it.error(it.err)
+	}
+	switch v := it.value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case *big.Int:
+		if !v.IsInt64() {
+			return 0, it.error(fmt.Errorf("value %s overflows int64", v))
+		}
+		return v.Int64(), nil
+	}
+	return 0, it.typeError("int")
+}
+
+func (it getterItem) float() (float64, error) {
+	if it.err != nil {
+		return 0, it.error(it.err)
+	}
+	switch v := it.value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, nil
+	}
+	return 0, it.typeError("float")
+}
+
+func (it getterItem) string() (string, error) {
+	if it.err != nil {
+		return "", it.error(it.err)
+	}
+	if v, ok := it.value.(string); ok {
+		return v, nil
+	}
+	return "", it.typeError("string")
+}
+
+func (it getterItem) bytes() ([]byte, error) {
+	if it.err != nil {
+		return nil, it.error(it.err)
+	}
+	switch v := it.value.(type) {
+	case []byte:
+		return v, nil
+	case *ByteArray:
+		return *v, nil
+	case *PickleBuffer:
+		return v.Data, nil
+	}
+	return nil, it.typeError("bytes")
+}
+
+func (it getterItem) bool() (bool, error) {
+	if it.err != nil {
+		return false, it.error(it.err)
+	}
+	if v, ok := it.value.(bool); ok {
+		return v, nil
+	}
+	return false, it.typeError("bool")
+}
+
+func (it getterItem) list() (*List, error) {
+	if it.err != nil {
+		return nil, it.error(it.err)
+	}
+	if v, ok := it.value.(*List); ok {
+		return v, nil
+	}
+	return nil, it.typeError("*List")
+}
+
+func (it getterItem) dict() (*Dict, error) {
+	if it.err != nil {
+		return nil, it.error(it.err)
+	}
+	if v, ok := it.value.(*Dict); ok {
+		return v, nil
+	}
+	return nil, it.typeError("*Dict")
+}
+
+func (it getterItem) tuple() (*Tuple, error) {
+	if it.err != nil {
+		return nil, it.error(it.err)
+	}
+	if v, ok := it.value.(*Tuple); ok {
+		return v, nil
+	}
+	return nil, it.typeError("*Tuple")
+}
+
+// GetInt returns the item at index i, which must be an int, an int64 or a
+// *big.Int within the range of int.
+func (t *Tuple) GetInt(i int) (int, error) {
+	return t.item("GetInt", i).int()
+}
+
+// GetInt64 returns the item at index i, which must be an int, an int64 or a
+// *big.Int within the range of int64.
+func (t *Tuple) GetInt64(i int) (int64, error) {
+	return t.item("GetInt64", i).int64()
+}
+
+// GetFloat returns the item at index i, which must be a float64, or an
+// integer converted to float64.
+func (t *Tuple) GetFloat(i int) (float64, error) {
+	return t.item("GetFloat", i).float()
+}
+
+// GetString returns the item at index i, which must be a string.
+func (t *Tuple) GetString(i int) (string, error) {
+	return t.item("GetString", i).string()
+}
+
+// GetBytes returns the item at index i, which must be a []byte, a
+// *ByteArray or a *PickleBuffer.
+func (t *Tuple) GetBytes(i int) ([]byte, error) {
+	return t.item("GetBytes", i).bytes()
+}
+
+// GetBool returns the item at index i, which must be a bool.
+func (t *Tuple) GetBool(i int) (bool, error) {
+	return t.item("GetBool", i).bool()
+}
+
+// GetList returns the item at index i, which must be a *List.
+func (t *Tuple) GetList(i int) (*List, error) {
+	return t.item("GetList", i).list()
+}
+
+// GetDict returns the item at index i, which must be a *Dict.
+func (t *Tuple) GetDict(i int) (*Dict, error) {
+	return t.item("GetDict", i).dict()
+}
+
+// GetTuple returns the item at index i, which must be a *Tuple.
+func (t *Tuple) GetTuple(i int) (*Tuple, error) {
+	return t.item("GetTuple", i).tuple()
+}
+
+// GetInt returns the item at index i, which must be an int, an int64 or a
+// *big.Int within the range of int.
+func (l *List) GetInt(i int) (int, error) {
+	return l.item("GetInt", i).int()
+}
+
+// GetInt64 returns the item at index i, which must be an int, an int64 or a
+// *big.Int within the range of int64.
+func (l *List) GetInt64(i int) (int64, error) {
+	return l.item("GetInt64", i).int64()
+}
+
+// GetFloat returns the item at index i, which must be a float64, or an
+// integer converted to float64.
+func (l *List) GetFloat(i int) (float64, error) {
+	return l.item("GetFloat", i).float()
+}
+
+// GetString returns the item at index i, which must be a string.
+func (l *List) GetString(i int) (string, error) {
+	return l.item("GetString", i).string()
+}
+
+// GetBytes returns the item at index i, which must be a []byte, a
+// *ByteArray or a *PickleBuffer.
+func (l *List) GetBytes(i int) ([]byte, error) {
+	return l.item("GetBytes", i).bytes()
+}
+
+// GetBool returns the item at index i, which must be a bool.
+func (l *List) GetBool(i int) (bool, error) {
+	return l.item("GetBool", i).bool()
+}
+
+// GetList returns the item at index i, which must be a *List.
+func (l *List) GetList(i int) (*List, error) {
+	return l.item("GetList", i).list()
+}
+
+// GetDict returns the item at index i, which must be a *Dict.
+func (l *List) GetDict(i int) (*Dict, error) {
+	return l.item("GetDict", i).dict()
+}
+
+// GetTuple returns the item at index i, which must be a *Tuple.
+func (l *List) GetTuple(i int) (*Tuple, error) {
+	return l.item("GetTuple", i).tuple()
+}
+
+// GetInt returns the value associated with key, which must be an
+// int, an int64 or a *big.Int within the range of int.
+func (d *Dict) GetInt(key interface{}) (int, error) {
+	return d.item("GetInt", key).int()
+}
+
+// GetInt64 returns the value associated with key, which must be an
+// int, an int64 or a *big.Int within the range of int64.
+func (d *Dict) GetInt64(key interface{}) (int64, error) {
+	return d.item("GetInt64", key).int64()
+}
+
+// GetFloat returns the value associated with key, which must be a float64,
+// or an integer converted to float64.
+func (d *Dict) GetFloat(key interface{}) (float64, error) {
+	return d.item("GetFloat", key).float()
+}
+
+// GetString returns the value associated with key, which must be a string.
+func (d *Dict) GetString(key interface{}) (string, error) {
+	return d.item("GetString", key).string()
+}
+
+// GetBytes returns the value associated with key, which must be a []byte,
+// a *ByteArray or a *PickleBuffer.
+func (d *Dict) GetBytes(key interface{}) ([]byte, error) {
+	return d.item("GetBytes", key).bytes()
+}
+
+// GetBool returns the value associated with key, which must be a bool.
+func (d *Dict) GetBool(key interface{}) (bool, error) {
+	return d.item("GetBool", key).bool()
+}
+
+// GetList returns the value associated with key, which must be a *List.
+func (d *Dict) GetList(key interface{}) (*List, error) {
+	return d.item("GetList", key).list()
+}
+
+// GetDict returns the value associated with key, which must be a *Dict.
+func (d *Dict) GetDict(key interface{}) (*Dict, error) {
+	return d.item("GetDict", key).dict()
+}
+
+// GetTuple returns the value associated with key, which must be a *Tuple.
+func (d *Dict) GetTuple(key interface{}) (*Tuple, error) {
+	return d.item("GetTuple", key).tuple()
+}
+
+// GetInt returns the value associated with key, which must be an
+// int, an int64 or a *big.Int within the range of int.
+func (o *OrderedDict) GetInt(key interface{}) (int, error) {
+	return o.item("GetInt", key).int()
+}
+
+// GetInt64 returns the value associated with key, which must be an
+// int, an int64 or a *big.Int within the range of int64.
+func (o *OrderedDict) GetInt64(key interface{}) (int64, error) {
+	return o.item("GetInt64", key).int64()
+}
+
+// GetFloat returns the value associated with key, which must be a float64,
+// or an integer converted to float64.
+func (o *OrderedDict) GetFloat(key interface{}) (float64, error) {
+	return o.item("GetFloat", key).float()
+}
+
+// GetString returns the value associated with key, which must be a string.
+func (o *OrderedDict) GetString(key interface{}) (string, error) {
+	return o.item("GetString", key).string()
+}
+
+// GetBytes returns the value associated with key, which must be a []byte,
+// a *ByteArray or a *PickleBuffer.
+func (o *OrderedDict) GetBytes(key interface{}) ([]byte, error) {
+	return o.item("GetBytes", key).bytes()
+}
+
+// GetBool returns the value associated with key, which must be a bool.
+func (o *OrderedDict) GetBool(key interface{}) (bool, error) {
+	return o.item("GetBool", key).bool()
+}
+
+// GetList returns the value associated with key, which must be a *List.
+func (o *OrderedDict) GetList(key interface{}) (*List, error) {
+	return o.item("GetList", key).list()
+}
+
+// GetDict returns the value associated with key, which must be a *Dict.
+func (o *OrderedDict) GetDict(key interface{}) (*Dict, error) {
+	return o.item("GetDict", key).dict()
+}
+
+// GetTuple returns the value associated with key, which must be a *Tuple.
+func (o *OrderedDict) GetTuple(key interface{}) (*Tuple, error) {
+	return o.item("GetTuple", key).tuple()
+}
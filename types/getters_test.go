@@ -0,0 +1,128 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestTupleGetters(t *testing.T) {
+	bigInt := big.NewInt(42)
+	hugeInt, _ := new(big.Int).SetString("100000000000000000000", 10)
+	tuple := NewTupleFromSlice([]interface{}{
+		1, bigInt, hugeInt, 2.5, "s", []byte("b"), NewByteArrayFromSlice([]byte("ba")),
+		true, NewList(), NewDict(), NewTupleFromSlice(nil),
+	})
+
+	if v, err := tuple.GetInt(0); err != nil || v != 1 {
+		t.Errorf("GetInt(0) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetInt(1); err != nil || v != 42 {
+		t.Errorf("GetInt(1) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetInt64(1); err != nil || v != 42 {
+		t.Errorf("GetInt64(1) = %v, %v", v, err)
+	}
+	if _, err := tuple.GetInt64(2); err == nil {
+		t.Error("GetInt64(2): expected overflow error")
+	}
+	if v, err := tuple.GetFloat(3); err != nil || v != 2.5 {
+		t.Errorf("GetFloat(3) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetFloat(0); err != nil || v != 1 {
+		t.Errorf("GetFloat(0) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetFloat(2); err != nil || v != 1e20 {
+		t.Errorf("GetFloat(2) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetString(4); err != nil || v != "s" {
+		t.Errorf("GetString(4) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetBytes(5); err != nil || string(v) != "b" {
+		t.Errorf("GetBytes(5) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetBytes(6); err != nil || string(v) != "ba" {
+		t.Errorf("GetBytes(6) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetBool(7); err != nil || !v {
+		t.Errorf("GetBool(7) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetList(8); err != nil || v != tuple.Get(8) {
+		t.Errorf("GetList(8) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetDict(9); err != nil || v != tuple.Get(9) {
+		t.Errorf("GetDict(9) = %v, %v", v, err)
+	}
+	if v, err := tuple.GetTuple(10); err != nil || v != tuple.Get(10) {
+		t.Errorf("GetTuple(10) = %v, %v", v, err)
+	}
+
+	_, err := tuple.GetInt(4)
+	if !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("expected ErrUnexpectedType, got %v", err)
+	}
+	expected := "Tuple.GetInt(4): unexpected type: expected int, got string"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	_, err = tuple.GetString(11)
+	if !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+}
+
+func TestListGetters(t *testing.T) {
+	list := NewListFromSlice([]interface{}{big.NewInt(-3), "x", NewPickleBuffer([]byte("pb"), true)})
+	if v, err := list.GetInt(0); err != nil || v != -3 {
+		t.Errorf("GetInt(0) = %v, %v", v, err)
+	}
+	if v, err := list.GetString(1); err != nil || v != "x" {
+		t.Errorf("GetString(1) = %v, %v", v, err)
+	}
+	if v, err := list.GetBytes(2); err != nil || string(v) != "pb" {
+		t.Errorf("GetBytes(2) = %v, %v", v, err)
+	}
+	if _, err := list.GetBool(-1); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+	if _, err := list.GetTuple(1); !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("expected ErrUnexpectedType, got %v", err)
+	}
+}
+
+func TestDictGetters(t *testing.T) {
+	d := NewDict()
+	d.Set("n", 7)
+	d.Set(1, "one")
+	if v, err := d.GetInt("n"); err != nil || v != 7 {
+		t.Errorf("GetInt(n) = %v, %v", v, err)
+	}
+	if v, err := d.GetString(1.0); err != nil || v != "one" {
+		t.Errorf("GetString(1.0) = %v, %v", v, err)
+	}
+	_, err := d.GetFloat("missing")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	expected := "Dict.GetFloat('missing'): key not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	od := NewOrderedDict()
+	od.Set("t", NewTupleFromSlice([]interface{}{1}))
+	if v, err := od.GetTuple("t"); err != nil || v.Len() != 1 {
+		t.Errorf("GetTuple(t) = %v, %v", v, err)
+	}
+	if _, err := od.GetDict("t"); !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("expected ErrUnexpectedType, got %v", err)
+	}
+	if _, err := od.GetInt64("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,553 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/big"
+	"math/bits"
+	"reflect"
+)
+
+// ErrUnhashable is reported when a value which is not hashable in Python
+// (such as a list, a dict or a set) is used as a dict key or a set item.
+var ErrUnhashable = errors.New("unhashable type")
+
+// Hash returns a hash of v which is consistent with Python equality, as
+// implemented by Equal: equal values have the same hash. For example,
+// 1, 1.0, true and big.NewInt(1) have the same hash.
+//
+// Hashes of numbers are the same as the ones computed by CPython. Hashes of
+// strings and bytes are randomized for each process, as in CPython, but
+// they don't match CPython's ones.
+//
+// Python unhashable types (List, Dict, Set, ByteArray, OrderedDict, and Go
+// slices and maps other than []byte) make Hash return an error wrapping
+// ErrUnhashable. Any other Go comparable value (such as a pointer to a
+// GenericObject) is hashed by its identity.
+func Hash(v interface{}) (uint64, error) {
+	h, err := pyHash(v, 0)
+	return uint64(h), err
+}
+
+// Equal reports whether a and b are equal according to Python "=="
+// semantics, for the types handled by Hash: numbers of different types are
+// compared by value (1 == 1.0 == true), Tuples, Lists, Dicts, Sets and
+// FrozenSets are compared by content, []byte and ByteArray values are
+// compared to each other, and NaN is not equal to itself.
+//
+// Any other values are equal if they are equal according to Go "==".
+func Equal(a, b interface{}) bool {
+	return pyEqual(a, b, 0)
+}
+
+// hashModulus is the prime modulus of CPython's numeric hashes (2**61-1).
+const hashModulus = 1<<61 - 1
+
+// maxHashDepth is the maximum nesting depth of the containers handled by
+// Hash and Equal. In Python, hashing or comparing deeper (or circular)
+// structures raises a RecursionError.
+const maxHashDepth = 512
+
+var errHashDepth = errors.New("maximum nesting depth exceeded while hashing")
+
+var hashSeed = maphash.MakeSeed()
+
+// Hashes of the types which are not numbers: they are only required to be
+// distinct and not too likely to collide.
+const (
+	noneHash     = 0x5f3759df
+	ellipsisHash = 0x1b873593
+)
+
+var bigHashModulus = big.NewInt(hashModulus)
+
+func pyHash(v interface{}, depth int) (int64, error) {
+	if depth > maxHashDepth {
+		return 0, errHashDepth
+	}
+	switch v := v.(type) {
+	case nil:
+		return noneHash, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case int:
+		return hashInt64(int64(v)), nil
+	case int64:
+		return hashInt64(v), nil
+	case int32:
+		return hashInt64(int64(v)), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return hashBigInt(new(big.Int).SetUint64(v)), nil
+		}
+		return hashInt64(int64(v)), nil
+	case *big.Int:
+		if v.IsInt64() {
+			return hashInt64(v.Int64()), nil
+		}
+		return hashBigInt(v), nil
+	case float64:
+		return hashFloat(v), nil
+	case float32:
+		return hashFloat(float64(v)), nil
+	case string:
+		var mh maphash.Hash
+		mh.SetSeed(hashSeed)
+		_, _ = mh.WriteString(v)
+		return int64(mh.Sum64()), nil
+	case []byte:
+		var mh maphash.Hash
+		mh.SetSeed(hashSeed)
+		_, _ = mh.Write(v)
+		return int64(mh.Sum64()), nil
+	case *Tuple:
+		return hashTuple(*v, depth)
+	case Tuple:
+		return hashTuple(v, depth)
+	case *FrozenSet:
+		return frozenSetHash(v.hashAcc, len(v.items)), nil
+	case *List, List, *Dict, *Set, *ByteArray, ByteArray, *OrderedDict:
+		return 0, fmt.Errorf("%w: %T", ErrUnhashable, v)
+	}
+	return hashReflect(reflect.ValueOf(v))
+}
+
+// hashReflect hashes any other Go type: integer and floating point kinds
+// by value, like Python numbers, and comparable values by identity.
+func hashReflect(rv reflect.Value) (int64, error) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return hashInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return hashBigInt(new(big.Int).SetUint64(u)), nil
+		}
+		return hashInt64(int64(u)), nil
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(rv.Float()), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		return pyHash(rv.String(), 0)
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return int64(mixHash(uint64(rv.Pointer()))), nil
+	}
+	if !isComparable(rv) {
+		return 0, fmt.Errorf("%w: %s", ErrUnhashable, rv.Type())
+	}
+	// Other comparable values are rare as dict keys: they all share the
+	// same hash, and are told apart by Equal.
+	return ellipsisHash, nil
+}
+
+// isComparable reports whether v can be compared with Go "==" (or used as
+// a key of a Go map) without causing a run-time panic.
+func isComparable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	case reflect.Interface:
+		return v.IsNil() || isComparable(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isComparable(v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isComparable(v.Field(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mixHash scrambles the bits of identity hashes (that is, of addresses).
+func mixHash(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+// hashInt64 implements CPython's hash of int objects.
+func hashInt64(v int64) int64 {
+	var h int64
+	if v >= 0 {
+		h = int64(uint64(v) % hashModulus)
+	} else {
+		// -v overflows for math.MinInt64, but its unsigned value is right
+		h = -int64((uint64(-v)) % hashModulus)
+	}
+	if h == -1 {
+		h = -2
+	}
+	return h
+}
+
+func hashBigInt(v *big.Int) int64 {
+	m := new(big.Int).Abs(v)
+	m.Mod(m, bigHashModulus)
+	h := m.Int64()
+	if v.Sign() < 0 {
+		h = -h
+	}
+	if h == -1 {
+		h = -2
+	}
+	return h
+}
+
+// hashFloat implements CPython's hash of float objects. In particular,
+// the hash of a float with an integral value is the same as the hash of
+// that integer.
+func hashFloat(v float64) int64 {
+	switch {
+	case math.IsNaN(v):
+		// CPython hashes NaN by identity: since NaN is never equal to
+		// anything, any value is fine.
+		return 0
+	case math.IsInf(v, 1):
+		return 314159
+	case math.IsInf(v, -1):
+		return -314159
+	}
+	m, e := math.Frexp(v)
+	sign := int64(1)
+	if m < 0 {
+		sign = -1
+		m = -m
+	}
+	// process 28 bits at a time
+	var x uint64
+	for m != 0 {
+		x = ((x << 28) & hashModulus) | x>>(61-28)
+		m *= 268435456.0 // 2**28
+		e -= 28
+		y := uint64(m)
+		m -= float64(y)
+		x += y
+		if x >= hashModulus {
+			x -= hashModulus
+		}
+	}
+	// adjust for the exponent
+	if e >= 0 {
+		e %= 61
+	} else {
+		e = 61 - 1 - ((-1 - e) % 61)
+	}
+	x = ((x << uint(e)) & hashModulus) | x>>uint(61-e)
+	h := int64(x) * sign
+	if h == -1 {
+		h = -2
+	}
+	return h
+}
+
+// Constants of CPython's tuple hash, derived from xxHash.
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// hashTuple implements CPython's hash of tuple objects.
+func hashTuple(t Tuple, depth int) (int64, error) {
+	acc := xxPrime5
+	for _, item := range t {
+		lane, err := pyHash(item, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		acc += uint64(lane) * xxPrime2
+		acc = bits.RotateLeft64(acc, 31)
+		acc *= xxPrime1
+	}
+	acc += uint64(len(t)) ^ (xxPrime5 ^ 3527539)
+	if acc == math.MaxUint64 {
+		return 1546275796, nil
+	}
+	return int64(acc), nil
+}
+
+// frozenSetHash implements CPython's hash of frozenset objects, which
+// doesn't depend on the order of the items. acc is the XOR of the shuffled
+// hashes of the n items (see shuffleBits).
+func frozenSetHash(acc uint64, n int) int64 {
+	h := acc
+	// make the final result spread-out in a different pattern than the
+	// hash of the items
+	h ^= (uint64(n) + 1) * 1927868237
+	h ^= (h >> 11) ^ (h >> 25)
+	h = h*69069 + 907133923
+	if h == math.MaxUint64 {
+		h = 590923713
+	}
+	return int64(h)
+}
+
+func shuffleBits(h uint64) uint64 {
+	return ((h ^ 89869747) ^ (h << 16)) * 3644798167
+}
+
+// number is the normalized representation of the numeric types handled by
+// Equal: either an int64, a *big.Int or a float64.
+type number struct {
+	kind numberKind
+	i    int64
+	b    *big.Int
+	f    float64
+}
+
+type numberKind uint8
+
+const (
+	notNumber numberKind = iota
+	intNumber
+	bigNumber
+	floatNumber
+)
+
+func toNumber(v interface{}) number {
+	switch v := v.(type) {
+	case int:
+		return number{kind: intNumber, i: int64(v)}
+	case bool:
+		if v {
+			return number{kind: intNumber, i: 1}
+		}
+		return number{kind: intNumber}
+	case float64:
+		return number{kind: floatNumber, f: v}
+	case *big.Int:
+		if v.IsInt64() {
+			return number{kind: intNumber, i: v.Int64()}
+		}
+		return number{kind: bigNumber, b: v}
+	case string, []byte, nil:
+		return number{}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: intNumber, i: rv.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return number{kind: bigNumber, b: new(big.Int).SetUint64(u)}
+		}
+		return number{kind: intNumber, i: int64(u)}
+	case reflect.Float32, reflect.Float64:
+		return number{kind: floatNumber, f: rv.Float()}
+	case reflect.Bool:
+		if rv.Bool() {
+			return number{kind: intNumber, i: 1}
+		}
+		return number{kind: intNumber}
+	}
+	return number{}
+}
+
+func (n number) bigInt() *big.Int {
+	if n.kind == bigNumber {
+		return n.b
+	}
+	return big.NewInt(n.i)
+}
+
+func numbersEqual(a, b number) bool {
+	if a.kind == floatNumber && b.kind == floatNumber {
+		return a.f == b.f
+	}
+	if a.kind == floatNumber || b.kind == floatNumber {
+		if b.kind == floatNumber {
+			a, b = b, a
+		}
+		// compare a float with an integer exactly
+		if math.IsNaN(a.f) || math.IsInf(a.f, 0) || a.f != math.Trunc(a.f) {
+			return false
+		}
+		if b.kind == intNumber {
+			if a.f >= -(1<<63) && a.f < 1<<63 {
+				return int64(a.f) == b.i
+			}
+			return false
+		}
+		bf := new(big.Float).SetInt(b.b)
+		return bf.Cmp(big.NewFloat(a.f)) == 0
+	}
+	if a.kind == intNumber && b.kind == intNumber {
+		return a.i == b.i
+	}
+	return a.bigInt().Cmp(b.bigInt()) == 0
+}
+
+func pyEqual(a, b interface{}, depth int) bool {
+	if depth > maxHashDepth {
+		return false
+	}
+	if na := toNumber(a); na.kind != notNumber {
+		nb := toNumber(b)
+		return nb.kind != notNumber && numbersEqual(na, nb)
+	}
+	switch a := a.(type) {
+	case string:
+		bs, ok := b.(string)
+		return ok && a == bs
+	case []byte:
+		return bytesEqual(a, b)
+	case *ByteArray:
+		return bytesEqual(*a, b)
+	case *Tuple:
+		switch b := b.(type) {
+		case *Tuple:
+			return sequencesEqual(*a, *b, depth)
+		case Tuple:
+			return sequencesEqual(*a, b, depth)
+		}
+		return false
+	case Tuple:
+		return pyEqual(&a, b, depth)
+	case *List:
+		bl, ok := b.(*List)
+		return ok && sequencesEqual(*a, *bl, depth)
+	case *Dict:
+		bd, ok := b.(*Dict)
+		return ok && a.equal(bd, depth)
+	case *Set:
+		return setEqual(a.items, a.index, b, depth)
+	case *FrozenSet:
+		return setEqual(a.items, a.index, b, depth)
+	}
+	if _, ok := b.(string); ok {
+		return false
+	}
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !ra.IsValid() || !rb.IsValid() {
+		return ra.IsValid() == rb.IsValid()
+	}
+	if ra.Type() != rb.Type() || !isComparable(ra) || !isComparable(rb) {
+		return false
+	}
+	return a == b
+}
+
+func bytesEqual(a []byte, b interface{}) bool {
+	switch b := b.(type) {
+	case []byte:
+		return string(a) == string(b)
+	case *ByteArray:
+		return string(a) == string(*b)
+	}
+	return false
+}
+
+func sequencesEqual(a, b []interface{}, depth int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !pyEqual(a[i], b[i], depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// setEqual compares the items of a Set or FrozenSet with b, which is
+// equal if it is a Set or a FrozenSet with the same items.
+func setEqual(items []interface{}, index hashIndex, b interface{}, depth int) bool {
+	var bItems []interface{}
+	var bIndex hashIndex
+	switch b := b.(type) {
+	case *Set:
+		bItems, bIndex = b.items, b.index
+	case *FrozenSet:
+		bItems, bIndex = b.items, b.index
+	default:
+		return false
+	}
+	if len(items) != len(bItems) {
+		return false
+	}
+	for _, item := range items {
+		if bIndex.lookup(item, bItems, depth+1) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashIndex is the hash table of Dict, Set and FrozenSet. It maps the hash
+// of each key to its position in the container's own insertion-ordered
+// storage, with colliding keys chained together.
+type hashIndex struct {
+	// heads maps a hash to 1 + the position of the last inserted key with
+	// that hash.
+	heads map[uint64]int
+	// next holds, for each position, 1 + the position of the previous key
+	// with the same hash, or 0.
+	next []int
+}
+
+// find returns the position of the key equal to key, which has hash h, or
+// -1. keyAt returns the key at a given position.
+func (x *hashIndex) find(h uint64, key interface{}, keyAt func(int) interface{}, depth int) int {
+	for p := x.heads[h]; p != 0; p = x.next[p-1] {
+		if pyEqual(keyAt(p-1), key, depth) {
+			return p - 1
+		}
+	}
+	return -1
+}
+
+// insert adds the key with hash h, at position len(x.next).
+func (x *hashIndex) insert(h uint64) {
+	if x.heads == nil {
+		x.heads = make(map[uint64]int)
+	}
+	x.next = append(x.next, x.heads[h])
+	x.heads[h] = len(x.next)
+}
+
+// lookup returns the position of v in items, indexed by x, or -1.
+func (x *hashIndex) lookup(v interface{}, items []interface{}, depth int) int {
+	h, err := pyHash(v, depth)
+	if err != nil {
+		return -1
+	}
+	return x.find(uint64(h), v, func(i int) interface{} { return items[i] }, depth)
+}
+
+// add appends v to items, unless an equal item is already present,
+// returning the new items, the hash of v, and whether it was added.
+// It panics if v is not hashable.
+func (x *hashIndex) add(v interface{}, items []interface{}) ([]interface{}, uint64, bool) {
+	h, err := pyHash(v, 0)
+	if err != nil {
+		panic(err)
+	}
+	if x.find(uint64(h), v, func(i int) interface{} { return items[i] }, 0) >= 0 {
+		return items, uint64(h), false
+	}
+	x.insert(uint64(h))
+	return append(items, v), uint64(h), true
+}
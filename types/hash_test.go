@@ -0,0 +1,160 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func bigIntFromString(s string) *big.Int {
+	b, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid big.Int: " + s)
+	}
+	return b
+}
+
+func TestHashMatchesCPython(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    interface{}
+		want int64
+	}{
+		{"1.5", 1.5, 1152921504606846977},
+		{"-1", -1, -2},
+		{"-2", -2, -2},
+		{"2**100", bigIntFromString("1267650600228229401496703205376"), 549755813888},
+		{"-2**100", bigIntFromString("-1267650600228229401496703205376"), -549755813888},
+		{"2**61-1", int64(1<<61 - 1), 0},
+		{"2**61", int64(1 << 61), 1},
+		{"1e300", 1e300, 1224995262755759164},
+		{"0.1", 0.1, 230584300921369408},
+		{"-0.5", -0.5, -1152921504606846976},
+		{"inf", math.Inf(1), 314159},
+		{"-inf", math.Inf(-1), -314159},
+		{"-2**63", int64(math.MinInt64), -4},
+		{"2**64-1", uint64(math.MaxUint64), 7},
+		{"True", true, 1},
+		{"(1, 2)", &Tuple{1, 2}, -3550055125485641917},
+		{"()", &Tuple{}, 5740354900026072187},
+		{"(1, (2, 3.0))", &Tuple{1, &Tuple{2, 3.0}}, 7267574591690527098},
+		{"(-1,)", &Tuple{-1}, 8078679518589016365},
+		{"frozenset()", NewFrozenSetFromSlice(nil), 133146708735736},
+		{"frozenset({1, 2, 3})", NewFrozenSetFromSlice([]interface{}{3, 1, 2}), -272375401224217160},
+		{"frozenset({(1, 2), 0.5})", NewFrozenSetFromSlice([]interface{}{0.5, &Tuple{1, 2}}), 4346396547646725922},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Hash(tc.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(got) != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, int64(got))
+			}
+		})
+	}
+}
+
+func TestHashEqualNumbers(t *testing.T) {
+	values := []interface{}{1, 1.0, true, big.NewInt(1), int8(1), uint64(1), float32(1)}
+	h0, _ := Hash(values[0])
+	for _, v := range values {
+		h, err := Hash(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h != h0 {
+			t.Errorf("%#v: expected hash %d, got %d", v, h0, h)
+		}
+		for _, w := range values {
+			if !Equal(v, w) {
+				t.Errorf("expected %#v == %#v", v, w)
+			}
+		}
+	}
+
+	big70 := new(big.Int).Lsh(big.NewInt(1), 70)
+	if !Equal(big70, math.Ldexp(1, 70)) || !Equal(math.Ldexp(1, 70), big70) {
+		t.Error("expected 2**70 == 2.0**70")
+	}
+	for _, pair := range [][2]interface{}{
+		{1, 1.5},
+		{1, "1"},
+		{0, nil},
+		{math.NaN(), math.NaN()},
+		{big70, math.Inf(1)},
+		{int64(math.MaxInt64), math.Ldexp(1, 63)},
+	} {
+		if Equal(pair[0], pair[1]) {
+			t.Errorf("expected %#v != %#v", pair[0], pair[1])
+		}
+	}
+}
+
+func TestEqualContainers(t *testing.T) {
+	for _, pair := range [][2]interface{}{
+		{&Tuple{1, "a", []byte("b")}, &Tuple{1.0, "a", []byte("b")}},
+		{[]byte("abc"), NewByteArrayFromSlice([]byte("abc"))},
+		{&List{1, &Tuple{2}}, &List{true, &Tuple{2.0}}},
+		{NewFrozenSetFromSlice([]interface{}{1, 2}), NewSetFromSlice([]interface{}{2.0, 1})},
+		{nil, nil},
+	} {
+		if !Equal(pair[0], pair[1]) || !Equal(pair[1], pair[0]) {
+			t.Errorf("expected %#v == %#v", pair[0], pair[1])
+		}
+	}
+
+	d1, d2 := NewDict(), NewDict()
+	d1.Set("a", 1)
+	d1.Set("b", &Tuple{2})
+	d2.Set("b", &Tuple{2.0})
+	d2.Set("a", true)
+	if !Equal(d1, d2) {
+		t.Error("expected equal dicts")
+	}
+	d2.Set("a", 2)
+	if Equal(d1, d2) {
+		t.Error("expected different dicts")
+	}
+
+	if Equal(&Tuple{1}, &List{1}) {
+		t.Error("expected a tuple to be different from a list")
+	}
+}
+
+func TestHashUnhashable(t *testing.T) {
+	for _, v := range []interface{}{
+		NewList(),
+		NewDict(),
+		NewSet(),
+		NewByteArray(),
+		NewOrderedDict(),
+		&Tuple{1, NewList()},
+		[]int{1},
+		map[string]int{},
+	} {
+		if _, err := Hash(v); !errors.Is(err, ErrUnhashable) {
+			t.Errorf("%#v: expected ErrUnhashable, got %v", v, err)
+		}
+	}
+}
+
+func TestHashIdentity(t *testing.T) {
+	a := &GenericClass{Module: "m", Name: "n"}
+	b := &GenericClass{Module: "m", Name: "n"}
+	if Equal(a, b) || !Equal(a, a) {
+		t.Error("expected pointers to be compared by identity")
+	}
+	ha, err := Hash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ha2, _ := Hash(a); ha != ha2 {
+		t.Error("expected a stable hash")
+	}
+}
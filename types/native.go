@@ -0,0 +1,481 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// ErrCycle is returned by ToNative, and by the MarshalJSON methods, when a
+// container contains itself and NativeOptions.Cycles is CycleError.
+var ErrCycle = errors.New("reference cycle")
+
+// ErrNonStringKey is returned by ToNative, and by the MarshalJSON methods,
+// for a dictionary key which is not a string, when NativeOptions.Keys is
+// RejectNonStringKeys.
+var ErrNonStringKey = errors.New("non-string dictionary key")
+
+// ErrDuplicateKey is returned by ToNative, and by the MarshalJSON methods,
+// when different keys of a dictionary are converted to the same string, as
+// with the keys 1 and "1" when NativeOptions.Keys is StringifyKeys.
+var ErrDuplicateKey = errors.New("duplicate dictionary key")
+
+// ErrNonFiniteFloat is returned by ToNative, and by the MarshalJSON methods,
+// for a NaN or infinite float when NativeOptions.NonFiniteFloats is
+// NonFiniteError.
+var ErrNonFiniteFloat = errors.New("non-finite float")
+
+// KeyMode selects how ToNative converts dictionary keys which are not
+// strings.
+type KeyMode int
+
+const (
+	// StringifyKeys converts keys like Python "json.dumps": integers and
+	// floats to their decimal representation, booleans to "true" and
+	// "false", and None to "null". Keys of any other type are converted
+	// with Repr.
+	StringifyKeys KeyMode = iota
+	// SkipNonStringKeys drops the entries whose key is not a string.
+	SkipNonStringKeys
+	// RejectNonStringKeys makes the conversion fail with ErrNonStringKey.
+	RejectNonStringKeys
+)
+
+// BigIntMode selects how ToNative converts *big.Int values.
+type BigIntMode int
+
+const (
+	// BigIntAsIs keeps *big.Int values, which "encoding/json" marshals as
+	// numbers.
+	BigIntAsIs BigIntMode = iota
+	// BigIntAsString converts *big.Int values to their decimal string
+	// representation, which is safe for JSON consumers limited to float64
+	// numbers.
+	BigIntAsString
+	// BigIntAsFloat converts *big.Int values to the nearest float64.
+	BigIntAsFloat
+)
+
+// BytesMode selects how ToNative converts bytes and bytearray values.
+type BytesMode int
+
+const (
+	// BytesBase64 converts bytes to a standard base64 string, the same
+	// encoding used by "encoding/json" for []byte.
+	BytesBase64 BytesMode = iota
+	// BytesHex converts bytes to a lowercase hexadecimal string.
+	BytesHex
+	// BytesAsIs keeps bytes as []byte.
+	BytesAsIs
+)
+
+// NonFiniteMode selects how ToNative converts NaN and infinite floats,
+// which have no JSON representation.
+type NonFiniteMode int
+
+const (
+	// NonFiniteAsString converts NaN and infinite floats to the strings
+	// "NaN", "Infinity" and "-Infinity", the names used by Python
+	// "json.dumps".
+	NonFiniteAsString NonFiniteMode = iota
+	// NonFiniteNull converts NaN and infinite floats to nil.
+	NonFiniteNull
+	// NonFiniteError makes the conversion fail with ErrNonFiniteFloat.
+	NonFiniteError
+)
+
+// CycleMode selects how ToNative handles containers which contain
+// themselves.
+type CycleMode int
+
+const (
+	// CycleError makes the conversion fail with ErrCycle.
+	CycleError CycleMode = iota
+	// CycleNil replaces the reference to the enclosing container with nil.
+	CycleNil
+)
+
+// NativeOptions configures ToNative. The zero value selects the default
+// for each option.
+type NativeOptions struct {
+	// Keys selects how non-string dictionary keys are converted.
+	Keys KeyMode
+	// BigInts selects how *big.Int values are converted.
+	BigInts BigIntMode
+	// Bytes selects how bytes and bytearray values are converted.
+	Bytes BytesMode
+	// Cycles selects how self-referencing containers are handled.
+	Cycles CycleMode
+	// NonFiniteFloats selects how NaN and infinite floats are converted.
+	NonFiniteFloats NonFiniteMode
+}
+
+// ToNative recursively converts v to plain Go values, which can be used
+// with "encoding/json" and similar packages:
+//
+//   - Dict and OrderedDict become map[string]interface{};
+//   - List, Tuple, Set and FrozenSet become []interface{};
+//   - bytes, ByteArray and PickleBuffer become strings or []byte, according
+//     to opts.Bytes;
+//   - *big.Int values are converted according to opts.BigInts;
+//   - NaN and infinite floats are converted according to
+//     opts.NonFiniteFloats;
+//   - a GenericObject becomes a map[string]interface{} with the class name
+//     under "__class__" (as "module.Name"), the constructor arguments
+//     under "__args__" and the State, if any, under "__state__".
+//
+// Values of any other type, including the other scalars, are returned
+// unchanged. Dictionary keys are converted according to opts.Keys; the
+// conversion fails with ErrDuplicateKey if two keys of the same dictionary
+// become the same string.
+// Values shared by multiple containers are converted once for each
+// occurrence.
+func ToNative(v interface{}, opts NativeOptions) (interface{}, error) {
+	c := newNativeConverter(opts)
+	return c.convert(v)
+}
+
+// nativeConverter implements ToNative and the MarshalJSON methods, keeping
+// track of the containers being converted, to detect cycles.
+type nativeConverter struct {
+	opts     NativeOptions
+	visiting map[interface{}]bool
+}
+
+func newNativeConverter(opts NativeOptions) *nativeConverter {
+	return &nativeConverter{
+		opts:     opts,
+		visiting: make(map[interface{}]bool),
+	}
+}
+
+// enter marks the container v as being converted. It returns false if v
+// contains itself and should be replaced by nil, or ErrCycle.
+func (c *nativeConverter) enter(v interface{}) (bool, error) {
+	if !c.visiting[v] {
+		c.visiting[v] = true
+		return true, nil
+	}
+	if c.opts.Cycles == CycleNil {
+		return false, nil
+	}
+	return false, fmt.Errorf("%w in %T", ErrCycle, v)
+}
+
+func (c *nativeConverter) leave(v interface{}) {
+	delete(c.visiting, v)
+}
+
+func (c *nativeConverter) convert(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case float64:
+		return c.float(v)
+	case *big.Int:
+		return c.bigInt(v), nil
+	case []byte:
+		return c.bytes(v), nil
+	case *ByteArray:
+		return c.bytes(*v), nil
+	case *PickleBuffer:
+		return c.bytes(v.Data), nil
+	case *List:
+		return c.sequence(v, *v)
+	case *Tuple:
+		return c.sequence(v, *v)
+	case *Set:
+		return c.sequence(v, v.Items())
+	case *FrozenSet:
+		return c.sequence(v, v.Items())
+	case *Dict:
+		return c.mapping(v, v.Entries())
+	case *OrderedDict:
+		return c.mapping(v, orderedDictEntries(v))
+	case *GenericObject:
+		if ok, err := c.enter(v); !ok {
+			return nil, err
+		}
+		defer c.leave(v)
+		args, err := c.items(v.ConstructorArgs)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{
+			"__class__": v.Class.Module + "." + v.Class.Name,
+			"__args__":  args,
+		}
+		if v.State != nil {
+			state, err := c.convert(v.State)
+			if err != nil {
+				return nil, err
+			}
+			m["__state__"] = state
+		}
+		return m, nil
+	default:
+		return v, nil
+	}
+}
+
+func (c *nativeConverter) float(v float64) (interface{}, error) {
+	if !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v, nil
+	}
+	switch c.opts.NonFiniteFloats {
+	case NonFiniteNull:
+		return nil, nil
+	case NonFiniteError:
+		return nil, fmt.Errorf("%w: %s", ErrNonFiniteFloat, Repr(v))
+	}
+	switch {
+	case math.IsNaN(v):
+		return "NaN", nil
+	case v > 0:
+		return "Infinity", nil
+	default:
+		return "-Infinity", nil
+	}
+}
+
+func (c *nativeConverter) bigInt(v *big.Int) interface{} {
+	switch c.opts.BigInts {
+	case BigIntAsString:
+		return v.String()
+	case BigIntAsFloat:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+func (c *nativeConverter) bytes(b []byte) interface{} {
+	switch c.opts.Bytes {
+	case BytesHex:
+		return hex.EncodeToString(b)
+	case BytesAsIs:
+		return b
+	default:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+func (c *nativeConverter) sequence(container interface{}, items []interface{}) (interface{}, error) {
+	if ok, err := c.enter(container); !ok {
+		return nil, err
+	}
+	defer c.leave(container)
+	return c.items(items)
+}
+
+func (c *nativeConverter) items(items []interface{}) ([]interface{}, error) {
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		v, err := c.convert(item)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+func (c *nativeConverter) mapping(container interface{}, entries []DictEntry) (interface{}, error) {
+	if ok, err := c.enter(container); !ok {
+		return nil, err
+	}
+	defer c.leave(container)
+	result := make(map[string]interface{}, len(entries))
+	for _, e := range entries {
+		k, ok, err := c.key(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if _, dup := result[k]; dup {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateKey, k)
+		}
+		v, err := c.convert(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// key converts a dictionary key to a string, returning false if the entry
+// must be skipped.
+func (c *nativeConverter) key(k interface{}) (string, bool, error) {
+	if s, ok := k.(string); ok {
+		return s, true, nil
+	}
+	switch c.opts.Keys {
+	case SkipNonStringKeys:
+		return "", false, nil
+	case RejectNonStringKeys:
+		return "", false, fmt.Errorf("%w: %s", ErrNonStringKey, Repr(k))
+	}
+	switch k := k.(type) {
+	case nil:
+		return "null", true, nil
+	case bool:
+		return strconv.FormatBool(k), true, nil
+	default:
+		return Repr(k), true, nil
+	}
+}
+
+// marshalJSON encodes v as JSON with the default NativeOptions. Unlike
+// json.Marshal(ToNative(v)), the entries of dictionaries are written in
+// insertion order.
+func marshalJSON(v interface{}) ([]byte, error) {
+	c := newNativeConverter(NativeOptions{})
+	buf := new(bytes.Buffer)
+	if err := c.writeJSON(buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *nativeConverter) writeJSON(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case *List:
+		return c.writeJSONArray(buf, v, *v)
+	case *Tuple:
+		return c.writeJSONArray(buf, v, *v)
+	case *Set:
+		return c.writeJSONArray(buf, v, v.Items())
+	case *FrozenSet:
+		return c.writeJSONArray(buf, v, v.Items())
+	case *Dict:
+		return c.writeJSONObject(buf, v, v.Entries())
+	case *OrderedDict:
+		return c.writeJSONObject(buf, v, orderedDictEntries(v))
+	case *GenericObject:
+		entries := []DictEntry{
+			{Key: "__class__", Value: v.Class.Module + "." + v.Class.Name},
+			{Key: "__args__", Value: NewListFromSlice(v.ConstructorArgs)},
+		}
+		if v.State != nil {
+			entries = append(entries, DictEntry{Key: "__state__", Value: v.State})
+		}
+		return c.writeJSONObject(buf, v, entries)
+	}
+	native, err := c.convert(v)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(native)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+func (c *nativeConverter) writeJSONArray(buf *bytes.Buffer, container interface{}, items []interface{}) error {
+	if ok, err := c.enter(container); !ok {
+		if err == nil {
+			buf.WriteString("null")
+		}
+		return err
+	}
+	defer c.leave(container)
+	buf.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := c.writeJSON(buf, item); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func (c *nativeConverter) writeJSONObject(buf *bytes.Buffer, container interface{}, entries []DictEntry) error {
+	if ok, err := c.enter(container); !ok {
+		if err == nil {
+			buf.WriteString("null")
+		}
+		return err
+	}
+	defer c.leave(container)
+	buf.WriteByte('{')
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		k, ok, err := c.key(e.Key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if seen[k] {
+			return fmt.Errorf("%w %q", ErrDuplicateKey, k)
+		}
+		if len(seen) > 0 {
+			buf.WriteByte(',')
+		}
+		seen[k] = true
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		if err := c.writeJSON(buf, e.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// MarshalJSON encodes the List as a JSON array, converting its items as
+// ToNative does with the default options.
+func (l *List) MarshalJSON() ([]byte, error) { return marshalJSON(l) }
+
+// MarshalJSON encodes the Tuple as a JSON array, converting its items as
+// ToNative does with the default options.
+func (t *Tuple) MarshalJSON() ([]byte, error) { return marshalJSON(t) }
+
+// MarshalJSON encodes the Set as a JSON array, converting its items as
+// ToNative does with the default options.
+func (s *Set) MarshalJSON() ([]byte, error) { return marshalJSON(s) }
+
+// MarshalJSON encodes the FrozenSet as a JSON array, converting its items as
+// ToNative does with the default options.
+func (f *FrozenSet) MarshalJSON() ([]byte, error) { return marshalJSON(f) }
+
+// MarshalJSON encodes the Dict as a JSON object, in insertion order,
+// converting its keys and values as ToNative does with the default options.
+func (d *Dict) MarshalJSON() ([]byte, error) { return marshalJSON(d) }
+
+// MarshalJSON encodes the OrderedDict as a JSON object, in insertion order,
+// converting its keys and values as ToNative does with the default options.
+func (o *OrderedDict) MarshalJSON() ([]byte, error) { return marshalJSON(o) }
+
+// MarshalJSON encodes the GenericObject as a JSON object, like ToNative does
+// with the default options.
+func (g *GenericObject) MarshalJSON() ([]byte, error) { return marshalJSON(g) }
+
+// MarshalJSON encodes the ByteArray as a base64 string, like a []byte.
+func (b *ByteArray) MarshalJSON() ([]byte, error) { return marshalJSON(b) }
+
+// MarshalJSON encodes the PickleBuffer data as a base64 string, like a
+// []byte.
+func (b *PickleBuffer) MarshalJSON() ([]byte, error) { return marshalJSON(b) }
@@ -0,0 +1,205 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestToNative(t *testing.T) {
+	inner := NewOrderedDict()
+	inner.Set("b", NewTupleFromSlice([]interface{}{1, []byte("hi")}))
+	d := NewDict()
+	d.Set("a", NewListFromSlice([]interface{}{1.5, nil, true}))
+	d.Set(1, "one")
+	d.Set(2.5, NewSetFromSlice([]interface{}{"x"}))
+	d.Set(nil, NewByteArrayFromSlice([]byte{0xff}))
+	d.Set(NewTupleFromSlice([]interface{}{1, 2}), inner)
+	d.Set("obj", &GenericObject{
+		Class:           NewGenericClass("foo", "Bar"),
+		ConstructorArgs: []interface{}{NewFrozenSetFromSlice([]interface{}{3})},
+	})
+
+	actual, err := ToNative(d, NativeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"a":    []interface{}{1.5, nil, true},
+		"1":    "one",
+		"2.5":  []interface{}{"x"},
+		"null": "/w==",
+		"(1, 2)": map[string]interface{}{
+			"b": []interface{}{1, "aGk="},
+		},
+		"obj": map[string]interface{}{
+			"__class__": "foo.Bar",
+			"__args__":  []interface{}{[]interface{}{3}},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, actual %#v", expected, actual)
+	}
+}
+
+func TestToNativeOptions(t *testing.T) {
+	bigInt, _ := new(big.Int).SetString("100000000000000000000", 10)
+	d := NewDict()
+	d.Set("n", bigInt)
+	d.Set("b", []byte("hi"))
+	d.Set(1, "skipped")
+
+	actual, err := ToNative(d, NativeOptions{
+		Keys:    SkipNonStringKeys,
+		BigInts: BigIntAsString,
+		Bytes:   BytesHex,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"n": "100000000000000000000", "b": "6869"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, actual %#v", expected, actual)
+	}
+
+	actual, err = ToNative(d, NativeOptions{
+		Keys:    SkipNonStringKeys,
+		BigInts: BigIntAsFloat,
+		Bytes:   BytesAsIs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]interface{}{"n": 1e20, "b": []byte("hi")}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, actual %#v", expected, actual)
+	}
+
+	_, err = ToNative(d, NativeOptions{Keys: RejectNonStringKeys})
+	if !errors.Is(err, ErrNonStringKey) {
+		t.Errorf("expected ErrNonStringKey, actual %v", err)
+	}
+}
+
+func TestToNativeNonFiniteFloats(t *testing.T) {
+	l := NewListFromSlice([]interface{}{math.NaN(), math.Inf(1), math.Inf(-1), 1.5})
+
+	for _, tc := range []struct {
+		mode     NonFiniteMode
+		expected []interface{}
+	}{
+		{NonFiniteAsString, []interface{}{"NaN", "Infinity", "-Infinity", 1.5}},
+		{NonFiniteNull, []interface{}{nil, nil, nil, 1.5}},
+	} {
+		actual, err := ToNative(l, NativeOptions{NonFiniteFloats: tc.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%d: expected %#v, actual %#v", tc.mode, tc.expected, actual)
+		}
+	}
+
+	_, err := ToNative(l, NativeOptions{NonFiniteFloats: NonFiniteError})
+	if !errors.Is(err, ErrNonFiniteFloat) {
+		t.Errorf("expected ErrNonFiniteFloat, actual %v", err)
+	}
+}
+
+func TestToNativeDuplicateKeys(t *testing.T) {
+	d := NewDict()
+	d.Set(1, "int")
+	d.Set("1", "str")
+
+	if _, err := ToNative(d, NativeOptions{}); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, actual %v", err)
+	}
+	if _, err := json.Marshal(d); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, actual %v", err)
+	}
+
+	actual, err := ToNative(d, NativeOptions{Keys: SkipNonStringKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"1": "str"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, actual %#v", expected, actual)
+	}
+}
+
+func TestToNativeCycles(t *testing.T) {
+	l := NewListFromSlice([]interface{}{1})
+	l.Append(l)
+
+	_, err := ToNative(l, NativeOptions{})
+	if !errors.Is(err, ErrCycle) {
+		t.Errorf("expected ErrCycle, actual %v", err)
+	}
+
+	actual, err := ToNative(l, NativeOptions{Cycles: CycleNil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{1, nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, actual %#v", expected, actual)
+	}
+
+	// shared values are not cycles
+	shared := NewListFromSlice([]interface{}{1})
+	outer := NewTupleFromSlice([]interface{}{shared, shared})
+	actual, err = ToNative(outer, NativeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []interface{}{[]interface{}{1}, []interface{}{1}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, actual %#v", expected, actual)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	od := NewOrderedDict()
+	od.Set("z", 1)
+	od.Set("a", NewSetFromSlice([]interface{}{2}))
+	d := NewDict()
+	d.Set("y", od)
+	d.Set(3, NewTupleFromSlice([]interface{}{[]byte("hi"), NewByteArrayFromSlice([]byte("x"))}))
+	d.Set("obj", &GenericObject{
+		Class:           NewGenericClass("foo", "Bar"),
+		ConstructorArgs: []interface{}{NewFrozenSetFromSlice([]interface{}{}), 1.5},
+	})
+
+	actual, err := json.Marshal(map[string]interface{}{"root": d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"root":{"y":{"z":1,"a":[2]},"3":["aGk=","eA=="],` +
+		`"obj":{"__class__":"foo.Bar","__args__":[[],1.5]}}}`
+	if string(actual) != expected {
+		t.Errorf("expected %s, actual %s", expected, actual)
+	}
+
+	f := NewListFromSlice([]interface{}{math.Inf(1), math.NaN()})
+	actual, err = json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `["Infinity","NaN"]`; string(actual) != expected {
+		t.Errorf("expected %s, actual %s", expected, actual)
+	}
+
+	l := NewList()
+	l.Append(l)
+	if _, err := json.Marshal(l); !errors.Is(err, ErrCycle) {
+		t.Errorf("expected ErrCycle, actual %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+// PickleBuffer represents a Python "pickle.PickleBuffer": a buffer which
+// can be transferred out-of-band with pickle protocol 5, without being
+// copied into the pickle data.
+type PickleBuffer struct {
+	// Data is the content of the buffer.
+	Data []byte
+	// ReadOnly reports whether the buffer must not be modified, as for a
+	// buffer of Python "bytes", rather than "bytearray".
+	ReadOnly bool
+}
+
+// NewPickleBuffer makes and returns a new PickleBuffer wrapping data. The
+// data is _not_ copied.
+func NewPickleBuffer(data []byte, readOnly bool) *PickleBuffer {
+	return &PickleBuffer{Data: data, ReadOnly: readOnly}
+}
+
+// Len returns the length of the PickleBuffer.
+func (b *PickleBuffer) Len() int {
+	return len(b.Data)
+}
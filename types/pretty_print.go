@@ -0,0 +1,422 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrettyPrintOptions configures PrettyPrint. The zero value of each field
+// selects the same default as Python "pprint".
+type PrettyPrintOptions struct {
+	// Indent is the amount of indentation added for each nesting level.
+	// The default is 1.
+	Indent int
+	// Width is the desired maximum number of characters per line. Values
+	// which don't fit are split across multiple lines, when possible.
+	// The default is 80.
+	Width int
+	// Depth is the maximum nesting level of the containers being printed:
+	// the content of deeper containers is replaced by "...". The default
+	// is 0, which means no limit.
+	Depth int
+}
+
+// PrettyPrint writes a representation of v to w, followed by a newline,
+// like Python "pprint.pprint(v)".
+//
+// Values are rendered as by Repr, but containers (and long strings and
+// bytes) are split across multiple lines, to fit the configured width. Dict
+// and Set entries are printed in insertion order, like "pprint" with
+// "sort_dicts=False". A GenericObject is printed as "module.Name(args...)".
+//
+// Containers which contain themselves are rendered like Python, e.g.
+// "[1, <Recursion on list with id=...>]".
+func PrettyPrint(w io.Writer, v interface{}, opts PrettyPrintOptions) error {
+	p := &prettyPrinter{
+		indentPerLevel: opts.Indent,
+		width:          opts.Width,
+		depth:          opts.Depth,
+		context:        make(map[interface{}]bool),
+	}
+	if p.indentPerLevel <= 0 {
+		p.indentPerLevel = 1
+	}
+	if p.width <= 0 {
+		p.width = 80
+	}
+	p.format(v, 0, 0, 0)
+	p.o.WriteString("\n")
+	_, err := io.WriteString(w, p.o.String())
+	return err
+}
+
+// prettyPrinter is a port of Python "pprint.PrettyPrinter".
+type prettyPrinter struct {
+	indentPerLevel int
+	width          int
+	depth          int
+	// context holds the containers being printed, to detect recursion.
+	context map[interface{}]bool
+	o       strings.Builder
+}
+
+func (p *prettyPrinter) write(s string) {
+	p.o.WriteString(s)
+}
+
+// isContainer reports whether v is printed by identity, and might thus
+// contain itself.
+func isContainer(v interface{}) bool {
+	switch v.(type) {
+	case *List, *Tuple, *Dict, *OrderedDict, *Set, *FrozenSet, *GenericObject:
+		return true
+	}
+	return false
+}
+
+func recursionRepr(v interface{}) string {
+	var name string
+	switch v := v.(type) {
+	case *List:
+		name = "list"
+	case *Tuple:
+		name = "tuple"
+	case *Dict:
+		name = "dict"
+	case *OrderedDict:
+		name = "OrderedDict"
+	case *Set:
+		name = "set"
+	case *FrozenSet:
+		name = "frozenset"
+	case *GenericObject:
+		name = v.Class.Name
+	}
+	return fmt.Sprintf("<Recursion on %s with id=%d>", name, pointerID(v))
+}
+
+// pointerID returns the address of a container, standing for Python "id()".
+func pointerID(v interface{}) uintptr {
+	return reflect.ValueOf(v).Pointer()
+}
+
+func (p *prettyPrinter) format(v interface{}, indent, allowance, level int) {
+	if isContainer(v) && p.context[v] {
+		p.write(recursionRepr(v))
+		return
+	}
+	rep := p.safeRepr(v, level)
+	maxWidth := p.width - indent - allowance
+	if utf8.RuneCountInString(rep) > maxWidth {
+		if p.dispatch(v, indent, allowance, level+1) {
+			return
+		}
+	}
+	p.write(rep)
+}
+
+// dispatch prints the containers and the long strings which don't fit into
+// the available width, returning false for other values.
+func (p *prettyPrinter) dispatch(v interface{}, indent, allowance, level int) bool {
+	if isContainer(v) {
+		p.context[v] = true
+		defer delete(p.context, v)
+	}
+	switch v := v.(type) {
+	case *List:
+		p.write("[")
+		p.formatItems(*v, indent, allowance+1, level)
+		p.write("]")
+	case *Tuple:
+		p.write("(")
+		end := ")"
+		if v.Len() == 1 {
+			end = ",)"
+		}
+		p.formatItems(*v, indent, allowance+len(end), level)
+		p.write(end)
+	case *Set:
+		if v.Len() == 0 {
+			return false
+		}
+		p.write("{")
+		p.formatItems(v.Items(), indent, allowance+1, level)
+		p.write("}")
+	case *FrozenSet:
+		if v.Len() == 0 {
+			return false
+		}
+		p.write("frozenset({")
+		p.formatItems(v.Items(), indent+len("frozenset("), allowance+2, level)
+		p.write("})")
+	case *Dict:
+		p.write("{")
+		if p.indentPerLevel > 1 {
+			p.write(strings.Repeat(" ", p.indentPerLevel-1))
+		}
+		if v.Len() > 0 {
+			p.formatDictEntries(v.Entries(), indent, allowance+1, level)
+		}
+		p.write("}")
+	case *OrderedDict:
+		if v.Len() == 0 {
+			return false
+		}
+		p.write("OrderedDict(")
+		entries := orderedDictEntries(v)
+		items := make(List, len(entries))
+		for i, e := range entries {
+			items[i] = &Tuple{e.Key, e.Value}
+		}
+		p.format(&items, indent+len("OrderedDict("), allowance+1, level)
+		p.write(")")
+	case *GenericObject:
+		name := v.Class.Module + "." + v.Class.Name
+		p.write(name + "(")
+		p.formatItems(v.ConstructorArgs, indent+len(name), allowance+1, level)
+		p.write(")")
+	case string:
+		p.formatString(v, indent, allowance, level)
+	case []byte:
+		p.formatBytes(v, indent, allowance, level)
+	case *ByteArray:
+		p.write("bytearray(")
+		p.formatBytes(*v, indent+len("bytearray("), allowance+1, level+1)
+		p.write(")")
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *prettyPrinter) formatItems(items []interface{}, indent, allowance, level int) {
+	indent += p.indentPerLevel
+	if p.indentPerLevel > 1 {
+		p.write(strings.Repeat(" ", p.indentPerLevel-1))
+	}
+	delimNL := ",\n" + strings.Repeat(" ", indent)
+	for i, item := range items {
+		if i > 0 {
+			p.write(delimNL)
+		}
+		if i == len(items)-1 {
+			p.format(item, indent, allowance, level)
+		} else {
+			p.format(item, indent, 1, level)
+		}
+	}
+}
+
+func (p *prettyPrinter) formatDictEntries(entries []DictEntry, indent, allowance, level int) {
+	indent += p.indentPerLevel
+	delimNL := ",\n" + strings.Repeat(" ", indent)
+	for i, e := range entries {
+		if i > 0 {
+			p.write(delimNL)
+		}
+		rep := p.safeRepr(e.Key, level)
+		p.write(rep)
+		p.write(": ")
+		valueIndent := indent + utf8.RuneCountInString(rep) + 2
+		if i == len(entries)-1 {
+			p.format(e.Value, valueIndent, allowance, level)
+		} else {
+			p.format(e.Value, valueIndent, 1, level)
+		}
+	}
+}
+
+// wordRegexp splits a line into alternating non-space and space runs.
+var wordRegexp = regexp.MustCompile(`\S*\s*`)
+
+// formatString splits a long string into multiple adjacent string literals,
+// at line breaks and spaces.
+func (p *prettyPrinter) formatString(s string, indent, allowance, level int) {
+	if len(s) == 0 {
+		p.write(reprString(s))
+		return
+	}
+	lines := splitLinesKeepEnds(s)
+	if level == 1 {
+		indent++
+		allowance++
+	}
+	maxWidth := p.width - indent
+	maxWidth1 := maxWidth
+	var chunks []string
+	for i, line := range lines {
+		rep := reprString(line)
+		if i == len(lines)-1 {
+			maxWidth1 -= allowance
+		}
+		if utf8.RuneCountInString(rep) <= maxWidth1 {
+			chunks = append(chunks, rep)
+			continue
+		}
+		parts := wordRegexp.FindAllString(line, -1)
+		maxWidth2 := maxWidth
+		current := ""
+		for j, part := range parts {
+			candidate := current + part
+			if j == len(parts)-1 && i == len(lines)-1 {
+				maxWidth2 -= allowance
+			}
+			if utf8.RuneCountInString(reprString(candidate)) > maxWidth2 {
+				if current != "" {
+					chunks = append(chunks, reprString(current))
+				}
+				current = part
+			} else {
+				current = candidate
+			}
+		}
+		if current != "" {
+			chunks = append(chunks, reprString(current))
+		}
+	}
+	if len(chunks) == 1 {
+		p.write(chunks[0])
+		return
+	}
+	p.writeChunks(chunks, indent, level == 1)
+}
+
+// formatBytes splits a long bytes value into multiple adjacent literals of
+// whole 4-byte groups.
+func (p *prettyPrinter) formatBytes(b []byte, indent, allowance, level int) {
+	if len(b) <= 4 {
+		p.write(reprBytes(b))
+		return
+	}
+	parens := level == 1
+	if parens {
+		indent++
+		allowance++
+	}
+	width := p.width - indent
+	var chunks []string
+	var current []byte
+	last := len(b) / 4 * 4
+	for i := 0; i < len(b); i += 4 {
+		end := i + 4
+		if end > len(b) {
+			end = len(b)
+		}
+		part := b[i:end]
+		candidate := append(current[:len(current):len(current)], part...)
+		if i == last {
+			width -= allowance
+		}
+		if len(reprBytes(candidate)) > width {
+			if len(current) > 0 {
+				chunks = append(chunks, reprBytes(current))
+			}
+			current = part
+		} else {
+			current = candidate
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, reprBytes(current))
+	}
+	p.writeChunks(chunks, indent, parens)
+}
+
+func (p *prettyPrinter) writeChunks(chunks []string, indent int, parens bool) {
+	if parens {
+		p.write("(")
+	}
+	for i, chunk := range chunks {
+		if i > 0 {
+			p.write("\n" + strings.Repeat(" ", indent))
+		}
+		p.write(chunk)
+	}
+	if parens {
+		p.write(")")
+	}
+}
+
+// splitLinesKeepEnds is like Python "str.splitlines(True)", for "\n",
+// "\r\n" and "\r" line breaks.
+func splitLinesKeepEnds(s string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			lines = append(lines, s[start:i+1])
+			start = i + 1
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			lines = append(lines, s[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+	return lines
+}
+
+// safeRepr is like Repr, but replaces the content of the containers nested
+// deeper than the configured depth with "...", and renders recursion like
+// Python "pprint".
+func (p *prettyPrinter) safeRepr(v interface{}, level int) string {
+	var open, close string
+	var items []interface{}
+	var entries []DictEntry
+	switch v := v.(type) {
+	case *List:
+		open, close, items = "[", "]", *v
+	case *Tuple:
+		open, close, items = "(", ")", *v
+		if v.Len() == 1 {
+			close = ",)"
+		}
+	case *Dict:
+		open, close, entries = "{", "}", v.Entries()
+	default:
+		return Repr(v)
+	}
+	if len(items) == 0 && len(entries) == 0 {
+		return open + strings.TrimPrefix(close, ",")
+	}
+	if p.depth > 0 && level >= p.depth {
+		return open + "..." + close
+	}
+	if p.context[v] {
+		return recursionRepr(v)
+	}
+	p.context[v] = true
+	defer delete(p.context, v)
+
+	o := new(strings.Builder)
+	o.WriteString(open)
+	for i, item := range items {
+		if i > 0 {
+			o.WriteString(", ")
+		}
+		o.WriteString(p.safeRepr(item, level+1))
+	}
+	for i, e := range entries {
+		if i > 0 {
+			o.WriteString(", ")
+		}
+		o.WriteString(p.safeRepr(e.Key, level+1))
+		o.WriteString(": ")
+		o.WriteString(p.safeRepr(e.Value, level+1))
+	}
+	o.WriteString(close)
+	return o.String()
+}
@@ -0,0 +1,383 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors wrapped by a *QueryError.
+var (
+	// ErrInvalidQuery is reported for a syntax error in the query.
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrKeyNotFound is reported when a dictionary has no such key, or
+	// an object has no such attribute.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrIndexOutOfRange is reported for an index which is out of the
+	// range of a sequence.
+	ErrIndexOutOfRange = errors.New("index out of range")
+	// ErrNotSubscriptable is reported when a segment is applied to a value
+	// which does not support it, such as an index applied to a string.
+	ErrNotSubscriptable = errors.New("value is not subscriptable")
+)
+
+// QueryError is the error returned by Query and Select. It names the
+// segment of the query which failed.
+type QueryError struct {
+	// Query is the whole query.
+	Query string
+	// Segment is the failing segment, as written in the query (e.g.
+	// "['encoder.weight']"). It is empty for errors not related to a
+	// specific segment.
+	Segment string
+	// Offset is the position of Segment in Query, in bytes.
+	Offset int
+	// Err is the underlying error.
+	Err error
+}
+
+var _ error = &QueryError{}
+
+func (e *QueryError) Error() string {
+	if e.Segment == "" {
+		return fmt.Sprintf("query %q: %v", e.Query, e.Err)
+	}
+	return fmt.Sprintf("query %q: segment %s (offset %d): %v",
+		e.Query, e.Segment, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Query returns the single value identified by query within root, using
+// the same syntax as Select, without wildcards. For example:
+//
+//	types.Query(checkpoint, "state_dict['encoder.weight']")
+func Query(root interface{}, query string) (interface{}, error) {
+	segments, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range segments {
+		if s.kind == wildcardSegment {
+			return nil, s.error(query, fmt.Errorf("%w: wildcards are only allowed by Select", ErrInvalidQuery))
+		}
+	}
+	values, err := selectSegments(root, query, segments)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// Select returns all the values matching query within root.
+//
+// The query is a sequence of segments, optionally preceded by "$", which
+// stands for root:
+//
+//   - ".name" or "['name']" (or "[\"name\"]") selects the value of a
+//     string key of a Dict or OrderedDict, or an attribute of an object:
+//     an entry of OrderedDict.PyDict, or of the dictionary State of a
+//     GenericObject; the special attributes "__args__" and "__state__" of a
+//     GenericObject select its constructor arguments (as a Tuple) and its
+//     State. The first segment can be written as a bare "name";
+//   - "[n]" selects the item at index n of a List or Tuple (counting from
+//     the end if negative, like Python), or of a Set or FrozenSet in
+//     insertion order, or the value of the integer key n of a Dict or
+//     OrderedDict;
+//   - "[*]" or ".*" selects all the items of a sequence or set, all the
+//     values of a dictionary (not including OrderedDict.PyDict), or all the
+//     attributes of the State of a GenericObject.
+//
+// For example, "$.layers[*].bias" selects the "bias" of each item of the
+// "layers" list of root. The strings returned by Path.String are valid
+// queries, as long as the dictionary keys along the path are strings or
+// integers within the range of int: keys of other types, such as tuples,
+// floats or bytes, can't be written in a query.
+//
+// A segment which cannot be applied makes Select fail with a *QueryError,
+// unless it follows a wildcard: the values which don't match are then
+// silently skipped, like in JSONPath.
+func Select(root interface{}, query string) ([]interface{}, error) {
+	segments, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return selectSegments(root, query, segments)
+}
+
+type segmentKind int
+
+const (
+	keySegment segmentKind = iota
+	indexSegment
+	wildcardSegment
+)
+
+// querySegment is a parsed segment of a query.
+type querySegment struct {
+	kind  segmentKind
+	key   string
+	index int
+	// text is the segment as written in the query, at offset.
+	text   string
+	offset int
+}
+
+func (s querySegment) error(query string, err error) *QueryError {
+	return &QueryError{Query: query, Segment: s.text, Offset: s.offset, Err: err}
+}
+
+func selectSegments(root interface{}, query string, segments []querySegment) ([]interface{}, error) {
+	values := []interface{}{root}
+	wildcard := false
+	for _, s := range segments {
+		var next []interface{}
+		for _, v := range values {
+			if s.kind == wildcardSegment {
+				items, ok := queryAll(v)
+				if !ok && !wildcard {
+					return nil, s.error(query, fmt.Errorf("%w: %T", ErrNotSubscriptable, v))
+				}
+				next = append(next, items...)
+				continue
+			}
+			result, err := queryOne(v, s)
+			if err != nil {
+				if wildcard {
+					continue
+				}
+				return nil, s.error(query, err)
+			}
+			next = append(next, result)
+		}
+		if s.kind == wildcardSegment {
+			wildcard = true
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// queryOne applies a key or index segment to v.
+func queryOne(v interface{}, s querySegment) (interface{}, error) {
+	if s.kind == keySegment {
+		return queryKey(v, s.key)
+	}
+	var items []interface{}
+	switch v := v.(type) {
+	case *List:
+		items = *v
+	case *Tuple:
+		items = *v
+	case *Set:
+		items = v.Items()
+	case *FrozenSet:
+		items = v.Items()
+	case *Dict:
+		if value, ok := v.Get(s.index); ok {
+			return value, nil
+		}
+		return nil, fmt.Errorf("%w: %d", ErrKeyNotFound, s.index)
+	case *OrderedDict:
+		if value, ok := v.Get(s.index); ok {
+			return value, nil
+		}
+		return nil, fmt.Errorf("%w: %d", ErrKeyNotFound, s.index)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrNotSubscriptable, v)
+	}
+	i := s.index
+	if i < 0 {
+		i += len(items)
+	}
+	if i < 0 || i >= len(items) {
+		return nil, fmt.Errorf("%w: %d (length %d)", ErrIndexOutOfRange, s.index, len(items))
+	}
+	return items[i], nil
+}
+
+// queryKey looks up a string key or an attribute of v.
+func queryKey(v interface{}, key string) (interface{}, error) {
+	switch v := v.(type) {
+	case *Dict:
+		if value, ok := v.Get(key); ok {
+			return value, nil
+		}
+	case *OrderedDict:
+		if value, ok := v.Get(key); ok {
+			return value, nil
+		}
+		if value, ok := v.PyDict[key]; ok {
+			return value, nil
+		}
+	case *GenericObject:
+		switch key {
+		case "__args__":
+			return NewTupleFromSlice(v.ConstructorArgs), nil
+		case "__state__":
+			return v.State, nil
+		}
+		attrs, slots, _ := stateAttributes(v.State)
+		for _, entries := range [][]DictEntry{attrs, slots} {
+			for _, e := range entries {
+				if e.Key == key {
+					return e.Value, nil
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrNotSubscriptable, v)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, reprString(key))
+}
+
+// queryAll returns the items of a sequence or set, the values of a
+// dictionary, or the attributes of an object.
+func queryAll(v interface{}) ([]interface{}, bool) {
+	var entries []DictEntry
+	switch v := v.(type) {
+	case *List:
+		return *v, true
+	case *Tuple:
+		return *v, true
+	case *Set:
+		return v.Items(), true
+	case *FrozenSet:
+		return v.Items(), true
+	case *Dict:
+		entries = v.Entries()
+	case *OrderedDict:
+		entries = orderedDictEntries(v)
+	case *GenericObject:
+		attrs, slots, _ := stateAttributes(v.State)
+		entries = append(attrs[:len(attrs):len(attrs)], slots...)
+	default:
+		return nil, false
+	}
+	values := make([]interface{}, len(entries))
+	for i, e := range entries {
+		values[i] = e.Value
+	}
+	return values, true
+}
+
+// parseQuery splits a query into its segments.
+func parseQuery(query string) ([]querySegment, error) {
+	var segments []querySegment
+	i := 0
+	if strings.HasPrefix(query, "$") {
+		i++
+	} else if n := identifierLen(query); n > 0 {
+		segments = append(segments, querySegment{
+			kind: keySegment, key: query[:n], text: query[:n],
+		})
+		i = n
+	}
+	for i < len(query) {
+		start := i
+		syntaxError := func(msg string) error {
+			return &QueryError{
+				Query:   query,
+				Segment: query[start:],
+				Offset:  start,
+				Err:     fmt.Errorf("%w: %s", ErrInvalidQuery, msg),
+			}
+		}
+		s := querySegment{offset: start}
+		switch query[i] {
+		case '.':
+			i++
+			if strings.HasPrefix(query[i:], "*") {
+				s.kind = wildcardSegment
+				i++
+				break
+			}
+			n := identifierLen(query[i:])
+			if n == 0 {
+				return nil, syntaxError("expected a name after '.'")
+			}
+			s.kind, s.key = keySegment, query[i:i+n]
+			i += n
+		case '[':
+			i++
+			switch {
+			case strings.HasPrefix(query[i:], "*"):
+				s.kind = wildcardSegment
+				i++
+			case strings.HasPrefix(query[i:], "'") || strings.HasPrefix(query[i:], `"`):
+				key, n, ok := parseQuotedKey(query[i:])
+				if !ok {
+					return nil, syntaxError("invalid or unterminated string")
+				}
+				s.kind, s.key = keySegment, key
+				i += n
+			default:
+				n := strings.IndexByte(query[i:], ']')
+				if n < 0 {
+					return nil, syntaxError("missing ']'")
+				}
+				index, err := strconv.Atoi(query[i : i+n])
+				if err != nil {
+					return nil, syntaxError("expected an integer, a string or '*' in brackets")
+				}
+				s.kind, s.index = indexSegment, index
+				i += n
+			}
+			if !strings.HasPrefix(query[i:], "]") {
+				return nil, syntaxError("missing ']'")
+			}
+			i++
+		default:
+			return nil, syntaxError("expected '.' or '['")
+		}
+		s.text = query[start:i]
+		segments = append(segments, s)
+	}
+	return segments, nil
+}
+
+// identifierLen returns the length of the name (made of ASCII letters,
+// digits and underscores, not starting with a digit) at the beginning of s.
+func identifierLen(s string) int {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(n > 0 && c >= '0' && c <= '9') {
+			n++
+			continue
+		}
+		break
+	}
+	return n
+}
+
+// parseQuotedKey parses a string quoted with single or double quotes at the
+// beginning of s, with Python escape sequences, such as the ones written by
+// Repr. It returns the unquoted string and the length of the quoted one.
+func parseQuotedKey(s string) (string, int, bool) {
+	quote := s[0]
+	var key strings.Builder
+	rest := s[1:]
+	for len(rest) > 0 {
+		if rest[0] == quote {
+			return key.String(), len(s) - len(rest) + 1, true
+		}
+		value, _, tail, err := strconv.UnquoteChar(rest, quote)
+		if err != nil {
+			return "", 0, false
+		}
+		// "\xNN" is a code point, as in Python, rather than a byte
+		key.WriteRune(value)
+		rest = tail
+	}
+	return "", 0, false
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func queryTestRoot() *Dict {
+	stateDict := NewOrderedDict()
+	stateDict.Set("encoder.weight", 1)
+	stateDict.Set("encoder.bias", 2)
+	stateDict.PyDict["_metadata"] = "meta"
+
+	layer := func(bias interface{}) *GenericObject {
+		state := NewDict()
+		if bias != nil {
+			state.Set("bias", bias)
+		}
+		state.Set("weight", 0)
+		return &GenericObject{
+			Class:           NewGenericClass("torch.nn", "Linear"),
+			ConstructorArgs: []interface{}{"arg"},
+			State:           state,
+		}
+	}
+
+	d := NewDict()
+	d.Set(7, "seven")
+	d.Set("it's", "quoted")
+
+	root := NewDict()
+	root.Set("state_dict", stateDict)
+	root.Set("layers", NewListFromSlice([]interface{}{layer(10), layer(nil), layer(30)}))
+	root.Set("pair", NewTupleFromSlice([]interface{}{"a", "b"}))
+	root.Set("d", d)
+	return root
+}
+
+func TestQuery(t *testing.T) {
+	root := queryTestRoot()
+	testCases := []struct {
+		query    string
+		expected interface{}
+	}{
+		{"state_dict['encoder.weight']", 1},
+		{`$.state_dict["encoder.bias"]`, 2},
+		{"$['state_dict']._metadata", "meta"},
+		{"layers[0].bias", 10},
+		{"layers[-1].bias", 30},
+		{"layers[0].__args__[0]", "arg"},
+		{"pair[1]", "b"},
+		{"d[7]", "seven"},
+		{`d['it\'s']`, "quoted"},
+		{`$['d']["it's"]`, "quoted"},
+	}
+	for _, tc := range testCases {
+		actual, err := Query(root, tc.query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.query, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected %#v, actual %#v", tc.query, tc.expected, actual)
+		}
+	}
+
+	if actual, err := Query(root, "$"); err != nil || actual != root {
+		t.Errorf("$: expected root, actual %#v, %v", actual, err)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	root := queryTestRoot()
+	testCases := []struct {
+		query   string
+		segment string
+		offset  int
+		err     error
+	}{
+		{"state_dict['decoder.weight']", "['decoder.weight']", 10, ErrKeyNotFound},
+		{"layers[3]", "[3]", 6, ErrIndexOutOfRange},
+		{"layers[1].bias", ".bias", 9, ErrKeyNotFound},
+		{"pair[0].x", ".x", 7, ErrNotSubscriptable},
+		{"pair[0][*]", "[*]", 7, ErrInvalidQuery},
+		{"layers[x]", "[x]", 6, ErrInvalidQuery},
+		{"layers[0", "[0", 6, ErrInvalidQuery},
+		{"layers.", ".", 6, ErrInvalidQuery},
+		{"d['x]", "['x]", 1, ErrInvalidQuery},
+		{"$layers", "layers", 1, ErrInvalidQuery},
+	}
+	for _, tc := range testCases {
+		_, err := Query(root, tc.query)
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Errorf("%s: expected *QueryError, actual %v", tc.query, err)
+			continue
+		}
+		if qe.Segment != tc.segment || qe.Offset != tc.offset || !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected segment %q at %d (%v), actual %q at %d (%v)",
+				tc.query, tc.segment, tc.offset, tc.err, qe.Segment, qe.Offset, qe.Err)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	root := queryTestRoot()
+	testCases := []struct {
+		query    string
+		expected []interface{}
+	}{
+		{"$.layers[*].bias", []interface{}{10, 30}},
+		{"$.layers.*.weight", []interface{}{0, 0, 0}},
+		{"state_dict[*]", []interface{}{1, 2}},
+		{"layers[1].*", []interface{}{0}},
+		{"$.pair[*][0]", nil},
+		{"pair[0]", []interface{}{"a"}},
+	}
+	for _, tc := range testCases {
+		actual, err := Select(root, tc.query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %#v, actual %#v", tc.query, tc.expected, actual)
+		}
+	}
+
+	if _, err := Select(root, "pair[0][*]"); !errors.Is(err, ErrNotSubscriptable) {
+		t.Errorf("expected ErrNotSubscriptable, actual %v", err)
+	}
+}
+
+func TestQueryWalkPaths(t *testing.T) {
+	root := queryTestRoot()
+	err := Walk(root, func(path Path, v interface{}) error {
+		actual, err := Query(root, path.String())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+			return nil
+		}
+		if !reflect.DeepEqual(actual, v) {
+			t.Errorf("%s: expected %#v, actual %#v", path, v, actual)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryWalkPathsAttributes(t *testing.T) {
+	state := NewDict()
+	state.Set("a-b", 1)
+	state.Set("1x", 2)
+	state.Set("it's", 3)
+	state.Set("ok", 4)
+	root := NewListFromSlice([]interface{}{&GenericObject{
+		Class: NewGenericClass("foo", "Bar"),
+		State: state,
+	}})
+
+	var paths []string
+	err := Walk(root, func(path Path, v interface{}) error {
+		paths = append(paths, path.String())
+		actual, err := Query(root, path.String())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+			return nil
+		}
+		if !reflect.DeepEqual(actual, v) {
+			t.Errorf("%s: expected %#v, actual %#v", path, v, actual)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"$", "$[0]", "$[0]['a-b']", "$[0]['1x']", `$[0]["it's"]`, "$[0].ok"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %q, actual %q", expected, paths)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Repr returns a string representation of v which is identical to the one
+// returned by Python "repr()" (as of CPython 3.12) for builtin values:
+// for example, strings are quoted and escaped, bytes are prefixed by "b",
+// and an empty Set is rendered as "set()".
+//
+// A GenericObject is rendered as "module.Name(args...)", with the arguments
+// it was constructed with, and a GenericClass as "<class 'module.Name'>".
+// Go values of other types are rendered with "%v", except for slices and
+// arrays, which are rendered like lists.
+//
+// Self-referencing values are rendered like in Python, e.g. "[1, [...]]".
+func Repr(v interface{}) string {
+	r := reprState{visiting: make(map[interface{}]bool)}
+	o := new(strings.Builder)
+	r.repr(o, v)
+	return o.String()
+}
+
+// reprState keeps track of the containers being rendered, to detect
+// recursion.
+type reprState struct {
+	visiting map[interface{}]bool
+}
+
+// enter marks the container v as being rendered, returning false if it
+// already is, that is, if v contains itself.
+func (r *reprState) enter(v interface{}) bool {
+	if r.visiting[v] {
+		return false
+	}
+	r.visiting[v] = true
+	return true
+}
+
+func (r *reprState) leave(v interface{}) {
+	delete(r.visiting, v)
+}
+
+func (r *reprState) repr(o *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		o.WriteString("None")
+	case bool:
+		if v {
+			o.WriteString("True")
+		} else {
+			o.WriteString("False")
+		}
+	case int:
+		o.WriteString(strconv.Itoa(v))
+	case *big.Int:
+		o.WriteString(v.String())
+	case float64:
+		o.WriteString(reprFloat(v))
+	case float32:
+		o.WriteString(reprFloat(float64(v)))
+	case string:
+		o.WriteString(reprString(v))
+	case []byte:
+		o.WriteString(reprBytes(v))
+	case *ByteArray:
+		o.WriteString("bytearray(")
+		o.WriteString(reprBytes(*v))
+		o.WriteString(")")
+	case *Tuple:
+		if !r.enter(v) {
+			o.WriteString("(...)")
+			return
+		}
+		defer r.leave(v)
+		r.reprSequence(o, "(", *v, ")")
+	case *List:
+		if !r.enter(v) {
+			o.WriteString("[...]")
+			return
+		}
+		defer r.leave(v)
+		r.reprSequence(o, "[", *v, "]")
+	case *Dict:
+		if !r.enter(v) {
+			o.WriteString("{...}")
+			return
+		}
+		defer r.leave(v)
+		r.reprDictEntries(o, v.Entries())
+	case *OrderedDict:
+		if v.Len() == 0 {
+			o.WriteString("OrderedDict()")
+			return
+		}
+		if !r.enter(v) {
+			o.WriteString("...")
+			return
+		}
+		defer r.leave(v)
+		o.WriteString("OrderedDict(")
+		r.reprDictEntries(o, orderedDictEntries(v))
+		o.WriteString(")")
+	case *Set:
+		if v.Len() == 0 {
+			o.WriteString("set()")
+			return
+		}
+		if !r.enter(v) {
+			o.WriteString("set(...)")
+			return
+		}
+		defer r.leave(v)
+		r.reprSequence(o, "{", v.Items(), "}")
+	case *FrozenSet:
+		if v.Len() == 0 {
+			o.WriteString("frozenset()")
+			return
+		}
+		if !r.enter(v) {
+			o.WriteString("frozenset(...)")
+			return
+		}
+		defer r.leave(v)
+		r.reprSequence(o, "frozenset({", v.Items(), "})")
+	case *GenericClass:
+		fmt.Fprintf(o, "<class '%s.%s'>", v.Module, v.Name)
+	case *ObjectClass:
+		o.WriteString("<class 'object'>")
+	case *OrderedDictClass:
+		o.WriteString("<class 'collections.OrderedDict'>")
+	case *GenericObject:
+		name := v.Class.Module + "." + v.Class.Name
+		if !r.enter(v) {
+			o.WriteString(name)
+			o.WriteString("(...)")
+			return
+		}
+		defer r.leave(v)
+		r.reprSequence(o, name+"(", v.ConstructorArgs, ")")
+	default:
+		r.reprOther(o, v)
+	}
+}
+
+// reprSequence renders items separated by commas, between open and close.
+// One-item tuples get a trailing comma.
+func (r *reprState) reprSequence(o *strings.Builder, open string, items []interface{}, close string) {
+	o.WriteString(open)
+	for i, item := range items {
+		if i > 0 {
+			o.WriteString(", ")
+		}
+		r.repr(o, item)
+	}
+	if open == "(" && len(items) == 1 {
+		o.WriteString(",")
+	}
+	o.WriteString(close)
+}
+
+func (r *reprState) reprDictEntries(o *strings.Builder, entries []DictEntry) {
+	o.WriteString("{")
+	for i, e := range entries {
+		if i > 0 {
+			o.WriteString(", ")
+		}
+		r.repr(o, e.Key)
+		o.WriteString(": ")
+		r.repr(o, e.Value)
+	}
+	o.WriteString("}")
+}
+
+// reprOther renders Go values of any other type.
+func (r *reprState) reprOther(o *strings.Builder, v interface{}) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		o.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		o.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Slice, reflect.Array:
+		if _, ok := v.(fmt.Stringer); ok {
+			fmt.Fprintf(o, "%v", v)
+			return
+		}
+		o.WriteString("[")
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				o.WriteString(", ")
+			}
+			r.repr(o, rv.Index(i).Interface())
+		}
+		o.WriteString("]")
+	default:
+		fmt.Fprintf(o, "%v", v)
+	}
+}
+
+func orderedDictEntries(od *OrderedDict) []DictEntry {
+	entries := make([]DictEntry, 0, od.Len())
+	for e := od.List.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*OrderedDictEntry)
+		entries = append(entries, DictEntry{Key: entry.Key, Value: entry.Value})
+	}
+	return entries
+}
+
+// reprFloat renders a float like Python: with the shortest representation
+// which round-trips, in scientific notation only for very small or large
+// exponents, and always with a decimal point or an exponent.
+func reprFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	mantissa := s[:i]
+	exp, _ := strconv.Atoi(s[i+1:])
+	// position of the decimal point, relative to the first digit
+	if decpt := exp + 1; decpt > -4 && decpt <= 16 {
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.ContainsRune(s, '.') {
+			s += ".0"
+		}
+		return s
+	}
+	sign := '+'
+	if exp < 0 {
+		sign = '-'
+		exp = -exp
+	}
+	return fmt.Sprintf("%se%c%02d", mantissa, sign, exp)
+}
+
+// reprQuote returns the quote character used by Python to represent a str
+// or bytes value: a single quote, unless s contains single quotes and no
+// double quotes.
+func reprQuote(s string) byte {
+	if strings.IndexByte(s, '\'') >= 0 && strings.IndexByte(s, '"') < 0 {
+		return '"'
+	}
+	return '\''
+}
+
+// reprString renders a string like Python. Invalid UTF-8 bytes are rendered
+// as lone surrogates, like Python strings decoded with "surrogateescape".
+func reprString(s string) string {
+	quote := reprQuote(s)
+	o := new(strings.Builder)
+	o.Grow(len(s) + 2)
+	o.WriteByte(quote)
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			fmt.Fprintf(o, `\udc%02x`, s[i])
+			i++
+			continue
+		}
+		i += size
+		switch {
+		case c == rune(quote) || c == '\\':
+			o.WriteByte('\\')
+			o.WriteRune(c)
+		case c == '\t':
+			o.WriteString(`\t`)
+		case c == '\n':
+			o.WriteString(`\n`)
+		case c == '\r':
+			o.WriteString(`\r`)
+		case c < ' ' || c == 0x7f:
+			fmt.Fprintf(o, `\x%02x`, c)
+		case c < utf8.RuneSelf || unicode.IsPrint(c):
+			o.WriteRune(c)
+		case c <= 0xff:
+			fmt.Fprintf(o, `\x%02x`, c)
+		case c <= 0xffff:
+			fmt.Fprintf(o, `\u%04x`, c)
+		default:
+			fmt.Fprintf(o, `\U%08x`, c)
+		}
+	}
+	o.WriteByte(quote)
+	return o.String()
+}
+
+// reprBytes renders a bytes value like Python.
+func reprBytes(b []byte) string {
+	quote := reprQuote(string(b))
+	o := new(strings.Builder)
+	o.Grow(len(b) + 3)
+	o.WriteByte('b')
+	o.WriteByte(quote)
+	for _, c := range b {
+		switch {
+		case c == quote || c == '\\':
+			o.WriteByte('\\')
+			o.WriteByte(c)
+		case c == '\t':
+			o.WriteString(`\t`)
+		case c == '\n':
+			o.WriteString(`\n`)
+		case c == '\r':
+			o.WriteString(`\r`)
+		case c < ' ' || c >= 0x7f:
+			fmt.Fprintf(o, `\x%02x`, c)
+		default:
+			o.WriteByte(c)
+		}
+	}
+	o.WriteByte(quote)
+	return o.String()
+}
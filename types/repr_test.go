@@ -0,0 +1,249 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestRepr(t *testing.T) {
+	od := NewOrderedDict()
+	od.Set("a", 1)
+
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	testCases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "None"},
+		{true, "True"},
+		{false, "False"},
+		{42, "42"},
+		{bigInt, "123456789012345678901234567890"},
+		{1e16, "1e+16"},
+		{1e15, "1000000000000000.0"},
+		{1e-5, "1e-05"},
+		{1e-4, "0.0001"},
+		{math.Copysign(0, -1), "-0.0"},
+		{1.5e300, "1.5e+300"},
+		{math.NaN(), "nan"},
+		{math.Inf(-1), "-inf"},
+		{"a'b", `"a'b"`},
+		{`a'"b`, `'a\'"b'`},
+		{"\x00\x7f\u00a0\u200b😀é\n\t\\", `'\x00\x7f\xa0\u200b😀é\n\t\\'`},
+		{"\xff", `'\udcff'`},
+		{[]byte("\x00a'\""), `b'\x00a\'"'`},
+		{NewByteArrayFromSlice([]byte("x")), "bytearray(b'x')"},
+		{NewTupleFromSlice([]interface{}{}), "()"},
+		{NewTupleFromSlice([]interface{}{1}), "(1,)"},
+		{NewTupleFromSlice([]interface{}{1, "a"}), "(1, 'a')"},
+		{NewList(), "[]"},
+		{NewListFromSlice([]interface{}{1, nil, 2.5}), "[1, None, 2.5]"},
+		{NewDict(), "{}"},
+		{NewOrderedDict(), "OrderedDict()"},
+		{od, "OrderedDict({'a': 1})"},
+		{NewSet(), "set()"},
+		{NewSetFromSlice([]interface{}{1, 2}), "{1, 2}"},
+		{NewFrozenSetFromSlice([]interface{}{}), "frozenset()"},
+		{NewFrozenSetFromSlice([]interface{}{1, 2}), "frozenset({1, 2})"},
+		{NewGenericClass("foo", "Bar"), "<class 'foo.Bar'>"},
+		{
+			&GenericObject{
+				Class:           NewGenericClass("foo", "Bar"),
+				ConstructorArgs: []interface{}{1, "x"},
+			},
+			"foo.Bar(1, 'x')",
+		},
+		{[]int{1, 2}, "[1, 2]"},
+	}
+	for _, tc := range testCases {
+		actual := Repr(tc.value)
+		if actual != tc.expected {
+			t.Errorf("Repr(%#v): expected %s, actual %s", tc.value, tc.expected, actual)
+		}
+	}
+}
+
+func TestReprDict(t *testing.T) {
+	d := NewDict()
+	d.Set("a", 1)
+	d.Set(NewTupleFromSlice([]interface{}{1, 2}), NewList())
+	expected := "{'a': 1, (1, 2): []}"
+	if actual := Repr(d); actual != expected {
+		t.Errorf("expected %s, actual %s", expected, actual)
+	}
+}
+
+func TestReprRecursion(t *testing.T) {
+	l := NewListFromSlice([]interface{}{1})
+	l.Append(l)
+	if actual := Repr(l); actual != "[1, [...]]" {
+		t.Errorf("expected [1, [...]], actual %s", actual)
+	}
+
+	d := NewDict()
+	d.Set("self", d)
+	if actual := Repr(d); actual != "{'self': {...}}" {
+		t.Errorf("expected {'self': {...}}, actual %s", actual)
+	}
+
+	obj := &GenericObject{Class: NewGenericClass("foo", "Bar")}
+	obj.ConstructorArgs = []interface{}{obj}
+	if actual := Repr(obj); actual != "foo.Bar(foo.Bar(...))" {
+		t.Errorf("expected foo.Bar(foo.Bar(...)), actual %s", actual)
+	}
+
+	// the same value appearing twice is not a recursion
+	inner := NewListFromSlice([]interface{}{1})
+	outer := NewListFromSlice([]interface{}{inner, inner})
+	if actual := Repr(outer); actual != "[[1], [1]]" {
+		t.Errorf("expected [[1], [1]], actual %s", actual)
+	}
+}
+
+func TestPrettyPrint(t *testing.T) {
+	nested := NewDict()
+	nested.Set("a", NewTupleFromSlice([]interface{}{1}))
+	nested.Set("b", NewListFromSlice([]interface{}{
+		[]byte(strings.Repeat("\x00\x01", 20)),
+		"xxxxxxxxxx",
+	}))
+	values := NewList()
+	for i := 0; i < 30; i++ {
+		values.Append(i)
+	}
+	data := NewDict()
+	data.Set("name", "gopickle")
+	data.Set("values", values)
+	data.Set("nested", nested)
+	data.Set("s", NewSetFromSlice([]interface{}{1, 2}))
+
+	indented := NewDict()
+	indented.Set("a", NewListFromSlice([]interface{}{1, 2}))
+	indented.Set("bb", NewTupleFromSlice([]interface{}{3}))
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		opts     PrettyPrintOptions
+		expected string
+	}{
+		{
+			name:     "short",
+			value:    NewListFromSlice([]interface{}{1, "a"}),
+			expected: "[1, 'a']\n",
+		},
+		{
+			name:  "nested",
+			value: data,
+			opts:  PrettyPrintOptions{Width: 40},
+			expected: "{'name': 'gopickle',\n" +
+				" 'values': [" + joinLines(values, 12) + "],\n" +
+				" 'nested': {'a': (1,),\n" +
+				"            'b': [" + strings.Repeat(`b'\x00\x01\x00\x01'`+"\n"+strings.Repeat(" ", 18), 9) +
+				`b'\x00\x01\x00\x01',` + "\n" +
+				"                  'xxxxxxxxxx']},\n" +
+				" 's': {1, 2}}\n",
+		},
+		{
+			name:     "depth",
+			value:    data,
+			opts:     PrettyPrintOptions{Depth: 1},
+			expected: "{'name': 'gopickle', 'values': [...], 'nested': {...}, 's': {1, 2}}\n",
+		},
+		{
+			name: "string",
+			value: NewListFromSlice([]interface{}{
+				"lorem ipsum dolor sit amet, consectetur adipiscing elit\nsed do eiusmod",
+			}),
+			opts: PrettyPrintOptions{Width: 30},
+			expected: "['lorem ipsum dolor sit '\n" +
+				" 'amet, consectetur '\n" +
+				" 'adipiscing elit\\n'\n" +
+				" 'sed do eiusmod']\n",
+		},
+		{
+			name:  "top-level string",
+			value: "lorem ipsum dolor sit amet, consectetur adipiscing elit",
+			opts:  PrettyPrintOptions{Width: 30},
+			expected: "('lorem ipsum dolor sit '\n" +
+				" 'amet, consectetur '\n" +
+				" 'adipiscing elit')\n",
+		},
+		{
+			name:     "bytes",
+			value:    []byte("0123456789abcdefghij"),
+			opts:     PrettyPrintOptions{Width: 20},
+			expected: "(b'0123456789abcdef'\n b'ghij')\n",
+		},
+		{
+			name:     "frozenset",
+			value:    NewFrozenSetFromSlice([]interface{}{1, 2, 3}),
+			opts:     PrettyPrintOptions{Width: 10},
+			expected: "frozenset({1,\n           2,\n           3})\n",
+		},
+		{
+			name:     "tuple",
+			value:    NewTupleFromSlice([]interface{}{1, 2, 3}),
+			opts:     PrettyPrintOptions{Width: 5},
+			expected: "(1,\n 2,\n 3)\n",
+		},
+		{
+			name:  "indent",
+			value: indented,
+			opts:  PrettyPrintOptions{Width: 10, Indent: 4},
+			expected: "{   'a': [   1,\n" +
+				"             2],\n" +
+				"    'bb': (   3,)}\n",
+		},
+		{
+			name: "generic object",
+			value: &GenericObject{
+				Class:           NewGenericClass("foo", "Bar"),
+				ConstructorArgs: []interface{}{1, NewListFromSlice([]interface{}{2, 3})},
+			},
+			opts:     PrettyPrintOptions{Width: 10},
+			expected: "foo.Bar(1,\n        [2,\n         3])\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := PrettyPrint(&sb, tc.value, tc.opts); err != nil {
+				t.Fatal(err)
+			}
+			if actual := sb.String(); actual != tc.expected {
+				t.Errorf("expected\n%s\nactual\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrettyPrintRecursion(t *testing.T) {
+	l := NewListFromSlice([]interface{}{1})
+	l.Append(l)
+	var sb strings.Builder
+	if err := PrettyPrint(&sb, l, PrettyPrintOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	actual := sb.String()
+	if !strings.HasPrefix(actual, "[1, <Recursion on list with id=") || !strings.HasSuffix(actual, ">]\n") {
+		t.Errorf("unexpected output %q", actual)
+	}
+}
+
+// joinLines renders the items of l one per line, as PrettyPrint does for a
+// list which doesn't fit, with the given indentation.
+func joinLines(l *List, indent int) string {
+	parts := make([]string, l.Len())
+	for i, v := range *l {
+		parts[i] = Repr(v)
+	}
+	return strings.Join(parts, ",\n"+strings.Repeat(" ", indent))
+}
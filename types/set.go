@@ -4,6 +4,8 @@
 
 package types
 
+import "fmt"
+
 // SetAdder is implemented by any value that exhibits a set-like behaviour,
 // allowing arbitrary values to be added.
 type SetAdder interface {
@@ -12,43 +14,98 @@ type SetAdder interface {
 
 // Set represents a Python "set" (builtin type).
 //
-// It is implemented in Go as a map with empty struct values; the actual set
-// of generic "interface{}" items is thus represented by all the keys.
-type Set map[interface{}]setEmptyStruct
+// Items are compared according to Python semantics (see Hash and Equal):
+// for example, 1, 1.0 and true are the same item. Iteration follows the
+// insertion order.
+//
+// The zero value is an empty Set ready to use.
+type Set struct {
+	items []interface{}
+	index hashIndex
+}
 
 var _ SetAdder = &Set{}
 
-type setEmptyStruct struct{}
-
 // NewSet makes and returns a new empty Set.
 func NewSet() *Set {
-	s := make(Set, 4)
-	return &s
+	return &Set{items: make([]interface{}, 0, 4)}
 }
 
 // NewSetFromSlice makes and returns a new Set initialized with the elements
-// of the given slice.
+// of the given slice, ignoring duplicates.
+//
+// It panics if any element is not hashable (see Hash).
 func NewSetFromSlice(slice []interface{}) *Set {
-	s := make(Set, len(slice))
+	s := &Set{items: make([]interface{}, 0, len(slice))}
 	for _, item := range slice {
-		s[item] = setEmptyStruct{}
+		s.Add(item)
 	}
-	return &s
+	return s
 }
 
 // Len returns the length of the Set.
 func (s *Set) Len() int {
-	return len(*s)
+	return len(s.items)
 }
 
-// Add adds one element to the Set.
+// Add adds one element to the Set, unless an equal element is already
+// present.
+//
+// It panics if the element is not hashable (see Hash).
 func (s *Set) Add(v interface{}) {
-	(*s)[v] = setEmptyStruct{}
+	s.items, _, _ = s.index.add(v, s.items)
 }
 
 // Has returns whether the given value is present in the Set (true)
 // or not (false).
 func (s *Set) Has(v interface{}) bool {
-	_, ok := (*s)[v]
-	return ok
+	return s.index.lookup(v, s.items, 0) >= 0
+}
+
+// Items returns the elements of the Set, in insertion order.
+//
+// The returned slice is shared with the Set, and must not be modified.
+func (s *Set) Items() []interface{} {
+	return s.items
+}
+
+// Call returns a new Set with the items of the optional argument, which
+// must be a List, Tuple, Set or FrozenSet. It is equivalent to the Python
+// "set" constructor.
+func (*Set) Call(args ...interface{}) (interface{}, error) {
+	items, err := setCallItems("Set", args)
+	if err != nil {
+		return nil, err
+	}
+	return NewSetFromSlice(items), nil
+}
+
+// setCallItems returns the items of the optional argument of the "set"
+// and "frozenset" constructors, checking that they are hashable.
+func setCallItems(class string, args []interface{}) ([]interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%s: invalid arguments: %#v", class, args)
+	}
+	var items []interface{}
+	switch v := args[0].(type) {
+	case *List:
+		items = *v
+	case *Tuple:
+		items = *v
+	case *Set:
+		items = v.Items()
+	case *FrozenSet:
+		items = v.Items()
+	default:
+		return nil, fmt.Errorf("%s: invalid arguments: %#v", class, args)
+	}
+	for _, item := range items {
+		if _, err := Hash(item); err != nil {
+			return nil, fmt.Errorf("%s: %w", class, err)
+		}
+	}
+	return items, nil
 }
@@ -0,0 +1,88 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import "testing"
+
+func TestSetDeduplicatesLikePython(t *testing.T) {
+	// {1, 1.0, True, (1, 2), (1.0, 2), b'a', 'a'} == {1, (1, 2), b'a', 'a'}
+	s := NewSetFromSlice([]interface{}{
+		1, 1.0, true, &Tuple{1, 2}, &Tuple{1.0, 2}, []byte("a"), "a",
+	})
+	if s.Len() != 4 {
+		t.Fatalf("expected 4 items, got %d: %#v", s.Len(), s.Items())
+	}
+	if s.Items()[0] != 1 {
+		t.Errorf("expected the first inserted item to be kept, got %#v", s.Items()[0])
+	}
+	for _, v := range []interface{}{true, 1.0, &Tuple{true, 2.0}, []byte("a"), "a"} {
+		if !s.Has(v) {
+			t.Errorf("expected %#v to be in the set", v)
+		}
+	}
+	for _, v := range []interface{}{2, "b", NewList()} {
+		if s.Has(v) {
+			t.Errorf("expected %#v not to be in the set", v)
+		}
+	}
+}
+
+func TestSetZeroValue(t *testing.T) {
+	var s Set
+	s.Add(NewFrozenSetFromSlice([]interface{}{1, 2}))
+	s.Add(NewFrozenSetFromSlice([]interface{}{2, 1}))
+	if s.Len() != 1 || !s.Has(NewFrozenSetFromSlice([]interface{}{1, 2.0})) {
+		t.Errorf("expected a set with one frozenset, got %#v", s.Items())
+	}
+}
+
+func TestSetAddUnhashablePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewSet().Add(NewList())
+}
+
+func TestFrozenSet(t *testing.T) {
+	f := NewFrozenSetFromSlice([]interface{}{"a", 2, 2.0, "a", false})
+	if f.Len() != 3 {
+		t.Fatalf("expected 3 items, got %#v", f.Items())
+	}
+	if !f.Has(0) || !f.Has(2) || !f.Has("a") || f.Has(1) {
+		t.Errorf("unexpected membership for %#v", f.Items())
+	}
+}
+
+func TestSetCall(t *testing.T) {
+	s, err := (&Set{}).Call(NewListFromSlice([]interface{}{1, 1.0, "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set, ok := s.(*Set); !ok || set.Len() != 2 || !set.Has("a") {
+		t.Errorf("unexpected set %#v", s)
+	}
+
+	f, err := (&FrozenSet{}).Call(NewTupleFromSlice([]interface{}{2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs, ok := f.(*FrozenSet); !ok || fs.Len() != 1 || !fs.Has(2) {
+		t.Errorf("unexpected frozenset %#v", f)
+	}
+
+	empty, err := (&Set{}).Call()
+	if set, ok := empty.(*Set); !ok || set.Len() != 0 || err != nil {
+		t.Errorf("unexpected empty set %#v, %v", empty, err)
+	}
+
+	if _, err := (&Set{}).Call(NewListFromSlice([]interface{}{NewList()})); err == nil {
+		t.Error("expected an error for an unhashable item")
+	}
+	if _, err := (&FrozenSet{}).Call(1); err == nil {
+		t.Error("expected an error for a non-iterable argument")
+	}
+}
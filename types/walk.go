@@ -0,0 +1,285 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SkipDir can be returned by a WalkFunc to skip the content of the
+// container it was called for. When returned for a value which is not a
+// container, the remaining items of the enclosing container are skipped.
+// It is never returned as an error by Walk.
+var SkipDir = errors.New("skip this container")
+
+// SkipAll can be returned by a WalkFunc to stop the walk. It is never
+// returned as an error by Walk.
+var SkipAll = errors.New("skip everything")
+
+// StepKind identifies the kind of a PathStep.
+type StepKind int
+
+const (
+	// IndexStep selects the item of a List, Tuple, Set or FrozenSet at
+	// PathStep.Index. Set items are indexed in insertion order.
+	IndexStep StepKind = iota
+	// KeyStep selects the value associated with PathStep.Key in a Dict or
+	// OrderedDict.
+	KeyStep
+	// AttrStep selects the attribute named PathStep.Key of an object: an
+	// entry of OrderedDict.PyDict, or of the state of a GenericObject.
+	AttrStep
+	// ArgStep selects the constructor argument of a GenericObject at
+	// PathStep.Index.
+	ArgStep
+	// StateStep selects the State of a GenericObject which is not made of
+	// attribute dictionaries.
+	StateStep
+)
+
+// PathStep is a single step of a Path.
+type PathStep struct {
+	Kind StepKind
+	// Index is the position of the item, for IndexStep and ArgStep.
+	Index int
+	// Key is the dictionary key, for KeyStep, or the attribute name (a
+	// string), for AttrStep.
+	Key interface{}
+}
+
+// String renders the step with Python syntax: "[0]" for IndexStep,
+// "['key']" for KeyStep, ".name" for AttrStep (or "['name']" if the name is
+// not an identifier), ".__args__[0]" for ArgStep and ".__state__" for
+// StateStep.
+func (s PathStep) String() string {
+	switch s.Kind {
+	case IndexStep:
+		return fmt.Sprintf("[%d]", s.Index)
+	case KeyStep:
+		return "[" + Repr(s.Key) + "]"
+	case AttrStep:
+		if name, ok := s.Key.(string); ok && name != "" && identifierLen(name) == len(name) {
+			return "." + name
+		}
+		return "[" + Repr(s.Key) + "]"
+	case ArgStep:
+		return fmt.Sprintf(".__args__[%d]", s.Index)
+	case StateStep:
+		return ".__state__"
+	default:
+		return fmt.Sprintf("<invalid step kind %d>", s.Kind)
+	}
+}
+
+// Path is the location of a value within an object graph, as the sequence
+// of steps leading to it from the root.
+type Path []PathStep
+
+// String renders the path starting with "$", which stands for the root,
+// followed by each step, e.g. "$['layers'][0].weight".
+func (p Path) String() string {
+	o := new(strings.Builder)
+	o.WriteString("$")
+	for _, s := range p {
+		o.WriteString(s.String())
+	}
+	return o.String()
+}
+
+// WalkFunc is the type of the function called by Walk for each value.
+//
+// The path must not be retained after the function returns, since its
+// underlying array is reused: Path values which must be kept can be
+// copied with "append(Path(nil), path...)".
+type WalkFunc func(path Path, v interface{}) error
+
+// Walk visits the object graph rooted at root, depth-first, calling fn for
+// each value, starting with root itself, whose path is empty.
+//
+// Walk descends into the values of Dict, OrderedDict (including the
+// attributes in PyDict, in name order), List, Tuple, Set and FrozenSet, and
+// into the constructor arguments and the State of a GenericObject. The
+// entries of a dictionary State (or of the two dictionaries of a
+// (dict, slots) Tuple State) are visited as attributes. Dictionary keys are
+// not visited.
+//
+// Values which are pointers are visited only once, the first time they are
+// found, so that values shared by multiple containers and reference cycles
+// are reported once.
+//
+// If fn returns SkipDir or SkipAll, Walk behaves as described for them;
+// any other non-nil error stops the walk and is returned by Walk.
+func Walk(root interface{}, fn WalkFunc) error {
+	w := &walker{
+		fn:      fn,
+		visited: make(map[interface{}]bool),
+	}
+	err := w.walk(root)
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	fn      WalkFunc
+	path    Path
+	visited map[interface{}]bool
+}
+
+// walk visits v and its content.
+func (w *walker) walk(v interface{}) error {
+	if isPointer(v) {
+		if w.visited[v] {
+			return nil
+		}
+		w.visited[v] = true
+	}
+	err := w.fn(w.path, v)
+	if err != nil {
+		if err == SkipDir && isContainer(v) {
+			return nil
+		}
+		return err
+	}
+	return w.walkContent(v)
+}
+
+func isPointer(v interface{}) bool {
+	switch v.(type) {
+	case nil, bool, int, float64, string, []byte:
+		return false
+	case *List, *Tuple, *Dict, *OrderedDict, *Set, *FrozenSet, *GenericObject:
+		return true
+	}
+	return reflect.ValueOf(v).Kind() == reflect.Ptr
+}
+
+// step visits v, adding the given step to the current path. It returns
+// SkipDir to signal that the remaining items of the enclosing container
+// must be skipped.
+func (w *walker) step(s PathStep, v interface{}) error {
+	w.path = append(w.path, s)
+	err := w.walk(v)
+	w.path = w.path[:len(w.path)-1]
+	return err
+}
+
+// walkContent visits the items of v, if it is a container.
+func (w *walker) walkContent(v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case *List:
+		err = w.walkItems(IndexStep, *v)
+	case *Tuple:
+		err = w.walkItems(IndexStep, *v)
+	case *Set:
+		err = w.walkItems(IndexStep, v.Items())
+	case *FrozenSet:
+		err = w.walkItems(IndexStep, v.Items())
+	case *Dict:
+		err = w.walkEntries(KeyStep, v.Entries())
+	case *OrderedDict:
+		err = w.walkEntries(KeyStep, orderedDictEntries(v))
+		if err == nil {
+			err = w.walkEntries(AttrStep, pyDictEntries(v.PyDict))
+		}
+	case *GenericObject:
+		err = w.walkItems(ArgStep, v.ConstructorArgs)
+		if err == nil {
+			err = w.walkState(v.State)
+		}
+	}
+	if err == SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (w *walker) walkItems(kind StepKind, items []interface{}) error {
+	for i, item := range items {
+		if err := w.step(PathStep{Kind: kind, Index: i}, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) walkEntries(kind StepKind, entries []DictEntry) error {
+	for _, e := range entries {
+		if err := w.step(PathStep{Kind: kind, Key: e.Key}, e.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) walkState(state interface{}) error {
+	if state == nil {
+		return nil
+	}
+	if attrs, slots, ok := stateAttributes(state); ok {
+		if err := w.walkEntries(AttrStep, attrs); err != nil {
+			return err
+		}
+		return w.walkEntries(AttrStep, slots)
+	}
+	return w.step(PathStep{Kind: StateStep}, state)
+}
+
+// stateAttributes returns the attributes of a GenericObject State made of a
+// Dict, or of a (Dict or None, Dict or None) Tuple. Non-string keys are not
+// considered attributes.
+func stateAttributes(state interface{}) (attrs, slots []DictEntry, ok bool) {
+	dictEntries := func(v interface{}) ([]DictEntry, bool) {
+		switch v := v.(type) {
+		case nil:
+			return nil, true
+		case *Dict:
+			for _, e := range v.Entries() {
+				if _, isString := e.Key.(string); !isString {
+					return nil, false
+				}
+			}
+			return v.Entries(), true
+		}
+		return nil, false
+	}
+	if t, isTuple := state.(*Tuple); isTuple {
+		if t.Len() != 2 {
+			return nil, nil, false
+		}
+		attrs, ok1 := dictEntries(t.Get(0))
+		slots, ok2 := dictEntries(t.Get(1))
+		return attrs, slots, ok1 && ok2
+	}
+	if state == nil {
+		return nil, nil, false
+	}
+	attrs, ok = dictEntries(state)
+	return attrs, nil, ok
+}
+
+// pyDictEntries returns the entries of an OrderedDict.PyDict, sorted by
+// name.
+func pyDictEntries(m map[string]interface{}) []DictEntry {
+	if len(m) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]DictEntry, len(names))
+	for i, name := range names {
+		entries[i] = DictEntry{Key: name, Value: m[name]}
+	}
+	return entries
+}
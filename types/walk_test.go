@@ -0,0 +1,149 @@
+// Copyright 2023 NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func walkPaths(t *testing.T, root interface{}, fn WalkFunc) []string {
+	t.Helper()
+	var paths []string
+	err := Walk(root, func(path Path, v interface{}) error {
+		paths = append(paths, path.String())
+		if fn != nil {
+			return fn(path, v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	shared := NewListFromSlice([]interface{}{1})
+
+	od := NewOrderedDict()
+	od.Set("w", 2)
+	od.PyDict["_metadata"] = "meta"
+
+	state := NewDict()
+	state.Set("x", shared)
+	obj := &GenericObject{
+		Class:           NewGenericClass("foo", "Bar"),
+		ConstructorArgs: []interface{}{"arg"},
+		State:           state,
+	}
+
+	root := NewDict()
+	root.Set("list", NewListFromSlice([]interface{}{shared, NewTupleFromSlice([]interface{}{3})}))
+	root.Set("od", od)
+	root.Set(1, NewSetFromSlice([]interface{}{4}))
+	root.Set("obj", obj)
+	root.Set("fs", NewFrozenSetFromSlice([]interface{}{5}))
+
+	actual := walkPaths(t, root, nil)
+	expected := []string{
+		"$",
+		"$['list']",
+		"$['list'][0]",
+		"$['list'][0][0]",
+		"$['list'][1]",
+		"$['list'][1][0]",
+		"$['od']",
+		"$['od']['w']",
+		"$['od']._metadata",
+		"$[1]",
+		"$[1][0]",
+		"$['obj']",
+		"$['obj'].__args__[0]",
+		"$['fs']",
+		"$['fs'][0]",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+}
+
+func TestWalkState(t *testing.T) {
+	slots := NewDict()
+	slots.Set("s", 1)
+	withSlots := &GenericObject{
+		Class: NewGenericClass("foo", "Bar"),
+		State: NewTupleFromSlice([]interface{}{nil, slots}),
+	}
+	withList := &GenericObject{
+		Class: NewGenericClass("foo", "Baz"),
+		State: NewListFromSlice([]interface{}{2}),
+	}
+	root := NewListFromSlice([]interface{}{withSlots, withList})
+
+	actual := walkPaths(t, root, nil)
+	expected := []string{"$", "$[0]", "$[0].s", "$[1]", "$[1].__state__", "$[1].__state__[0]"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+}
+
+func TestWalkCycle(t *testing.T) {
+	l := NewListFromSlice([]interface{}{1})
+	l.Append(l)
+	actual := walkPaths(t, l, nil)
+	expected := []string{"$", "$[0]"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+}
+
+func TestWalkSkip(t *testing.T) {
+	root := NewListFromSlice([]interface{}{
+		NewListFromSlice([]interface{}{1, 2}),
+		NewTupleFromSlice([]interface{}{"stop", 3}),
+		4,
+	})
+
+	actual := walkPaths(t, root, func(path Path, v interface{}) error {
+		if _, ok := v.(*List); ok && len(path) == 1 {
+			return SkipDir
+		}
+		if v == "stop" {
+			return SkipDir
+		}
+		return nil
+	})
+	expected := []string{"$", "$[0]", "$[1]", "$[1][0]", "$[2]"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+
+	actual = walkPaths(t, root, func(path Path, v interface{}) error {
+		if v == 2 {
+			return SkipAll
+		}
+		return nil
+	})
+	expected = []string{"$", "$[0]", "$[0][0]", "$[0][1]"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+}
+
+func TestWalkError(t *testing.T) {
+	errStop := errors.New("stop")
+	root := NewListFromSlice([]interface{}{1, 2})
+	err := Walk(root, func(path Path, v interface{}) error {
+		if v == 1 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("expected %v, actual %v", errStop, err)
+	}
+}